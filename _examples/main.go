@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/rsgcata/go-cli-command/cli"
@@ -46,9 +47,17 @@ func (s SayHelloDynamic) Description() string {
 }
 
 func (s SayHelloDynamic) Exec(stdWriter io.Writer) error {
+	return s.ExecContext(context.Background(), stdWriter)
+}
+
+func (s SayHelloDynamic) ExecContext(ctx context.Context, stdWriter io.Writer) error {
 	for i := 0; i < s.ParsedFlags.CountTo; i++ {
 		_, _ = stdWriter.Write([]byte("Hello there " + s.ParsedFlags.Name + "\n"))
-		time.Sleep(s.ParsedFlags.CountDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.ParsedFlags.CountDelay):
+		}
 	}
 	return nil
 }
@@ -75,22 +84,17 @@ func (s SayHelloDynamic) ValidateFlags() error {
 }
 
 func main() {
-	registry := cli.NewCommandsRegistry()
-	availableCommands := []cli.Command{
+	registry, err := cli.NewCommandsRegistryFrom(
 		&SayHello{},
 		cli.NewLockableCommand(
 			&SayHelloDynamic{ParsedFlags: &SayHelloFlags{}},
 			os.TempDir(),
 		),
-	}
-
-	for _, cmd := range availableCommands {
-		err := registry.Register(cmd)
-		if err != nil {
-			panic(err)
-		}
+	)
+	if err != nil {
+		panic(err)
 	}
 
 	// os.Args[1:] is mandatory to remove the program Name from the args slice
-	cli.Bootstrap(os.Args[1:], registry, os.Stdout, os.Exit)
+	cli.BootstrapWith(os.Args[1:], registry)
 }
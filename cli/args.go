@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+)
+
+// RawArgsCommand is an optional marker interface for passthrough commands, like a command
+// wrapping another CLI tool, which need the unmodified args tail. Flag parsing is skipped
+// entirely for them: their flags are not defined and all the args given after the command
+// id are passed to SetArgs as is, including the ones looking like flags.
+type RawArgsCommand interface {
+	ArgsReceiver
+	RawArgs()
+}
+
+// takesRawArgs reports whether the command opted into receiving raw args
+func takesRawArgs(cmd Command) bool {
+	_, ok := findOptional[RawArgsCommand](cmd)
+	return ok
+}
+
+// ArgsValidator validates the positional args of a command, returning an error
+// describing the expectation, like "expects 2 arguments, got 1"
+type ArgsValidator func(args []string) error
+
+// ArgsValidatingCommand is an optional interface for commands expecting a fixed or
+// bounded number of positional args. The validator returned by ExpectedArgs is applied to
+// the args left after the flags, after SetArgs (see ArgsReceiver) and the required flags
+// check, and before ValidateFlags, which can then rely on the args count being valid.
+type ArgsValidatingCommand interface {
+	Command
+	ExpectedArgs() ArgsValidator
+}
+
+// ExactArgs returns a validator accepting exactly n positional args
+func ExactArgs(n int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("expects %s, got %d", pluralArgs(n), len(args))
+		}
+		return nil
+	}
+}
+
+// MinArgs returns a validator accepting at least n positional args
+func MinArgs(n int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) < n {
+			return fmt.Errorf("expects at least %s, got %d", pluralArgs(n), len(args))
+		}
+		return nil
+	}
+}
+
+// MaxArgs returns a validator accepting at most n positional args
+func MaxArgs(n int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) > n {
+			return fmt.Errorf("expects at most %s, got %d", pluralArgs(n), len(args))
+		}
+		return nil
+	}
+}
+
+// RangeArgs returns a validator accepting between min and max positional args, inclusive
+func RangeArgs(min int, max int) ArgsValidator {
+	return func(args []string) error {
+		if len(args) < min || len(args) > max {
+			return fmt.Errorf("expects %d to %d arguments, got %d", min, max, len(args))
+		}
+		return nil
+	}
+}
+
+// pluralArgs formats the count of arguments, like "1 argument" or "2 arguments"
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
+
+// validateArgs applies the args validator of the command, if it has one
+func validateArgs(cmd Command, args []string) error {
+	argsValidating, ok := findOptional[ArgsValidatingCommand](cmd)
+	if !ok {
+		return nil
+	}
+
+	validator := argsValidating.ExpectedArgs()
+	if validator == nil {
+		return nil
+	}
+	if err := validator(args); err != nil {
+		return fmt.Errorf("command %s %w", cmd.Id(), err)
+	}
+	return nil
+}
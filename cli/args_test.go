@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// MockArgsValidatingCommand is an ArgsValidatingCommand implementation for testing
+type MockArgsValidatingCommand struct {
+	MockArgsCommand
+	validator ArgsValidator
+}
+
+func (m *MockArgsValidatingCommand) ExpectedArgs() ArgsValidator {
+	return m.validator
+}
+
+func TestItCanValidateTheArgsCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ArgsValidator
+		args      []string
+		wantErr   string
+	}{
+		{
+			name:      "exact args",
+			validator: ExactArgs(2),
+			args:      []string{"a", "b"},
+		},
+		{
+			name:      "too few exact args",
+			validator: ExactArgs(2),
+			args:      []string{"a"},
+			wantErr:   "command copy expects 2 arguments, got 1",
+		},
+		{
+			name:      "min args",
+			validator: MinArgs(1),
+			args:      []string{"a", "b"},
+		},
+		{
+			name:      "too few min args",
+			validator: MinArgs(1),
+			args:      []string{},
+			wantErr:   "command copy expects at least 1 argument, got 0",
+		},
+		{
+			name:      "too many max args",
+			validator: MaxArgs(1),
+			args:      []string{"--test-flag", "v", "a", "b"},
+			wantErr:   "command copy expects at most 1 argument, got 2",
+		},
+		{
+			name:      "range args",
+			validator: RangeArgs(1, 3),
+			args:      []string{"a", "b", "c"},
+		},
+		{
+			name:      "out of range args",
+			validator: RangeArgs(1, 3),
+			args:      []string{"a", "b", "c", "d"},
+			wantErr:   "command copy expects 1 to 3 arguments, got 4",
+		},
+		{
+			name:      "no validator",
+			validator: nil,
+			args:      []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockArgsValidatingCommand{
+					MockArgsCommand: MockArgsCommand{MockCommandWithFlags: MockCommandWithFlags{id: "copy"}},
+					validator:       tt.validator,
+				}
+
+				err := runCommand(context.Background(), cmd, tt.args, io.Discard, io.Discard)
+				if tt.wantErr == "" && err != nil {
+					t.Errorf("runCommand() error = %v, want nil", err)
+				}
+				if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+					t.Errorf("runCommand() error = %v, want %q", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+// MockRawArgsCommand is a RawArgsCommand implementation for testing
+type MockRawArgsCommand struct {
+	MockArgsCommand
+}
+
+func (m *MockRawArgsCommand) RawArgs() {}
+
+func TestItPassesRawArgsToPassthroughCommands(t *testing.T) {
+	cmd := &MockRawArgsCommand{MockArgsCommand{MockCommandWithFlags: MockCommandWithFlags{id: "kubectl"}}}
+	registry := NewCommandsRegistry()
+	_ = registry.Register(cmd)
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"kubectl", "--foo", "bar", "-x"},
+		registry,
+		io.Discard,
+		func(code int) { exitCode = code },
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+	if strings.Join(cmd.args, " ") != "--foo bar -x" {
+		t.Errorf("SetArgs() received %v, want [--foo bar -x]", cmd.args)
+	}
+
+	var helpBuf bytes.Buffer
+	_ = NewHelpCommand([]Command{cmd}).Exec(&helpBuf)
+	if !strings.Contains(helpBuf.String(), "Args: raw") || strings.Contains(helpBuf.String(), "--test-flag") {
+		t.Errorf("Help output should indicate raw args instead of flags, got %s", helpBuf.String())
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// auditEntry is the json line appended to the audit log for each command run
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Command    string    `json:"command"`
+	Args       []string  `json:"args"`
+	ExitCode   int       `json:"exitCode"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// newAuditEntry returns the audit entry of a command run started at the given time, the
+// user being read from the USER environment variable (USERNAME on Windows)
+func newAuditEntry(start time.Time, cmdId string, args []string, result ExecResult) auditEntry {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+
+	entry := auditEntry{
+		Time:       start,
+		User:       user,
+		Command:    cmdId,
+		Args:       args,
+		ExitCode:   result.ExitCode,
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	if entry.Args == nil {
+		entry.Args = []string{}
+	}
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+	return entry
+}
+
+// appendAuditEntry appends the entry to the audit log file as a single json line, creating
+// the file if needed. The line is written with a single write to the file opened with
+// O_APPEND, so that the entries of concurrent processes are not interleaved.
+func appendAuditEntry(path string, entry auditEntry) (err error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}()
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItCanWriteAnAuditLog(t *testing.T) {
+	t.Setenv("USER", "alice")
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommandWithFlags{id: "deploy"})
+	_ = registry.Register(
+		&MockCommand{
+			id: "report",
+			execFunc: func(writer io.Writer) error {
+				return NewExitError(3, errors.New("bad input"))
+			},
+		},
+	)
+
+	before := time.Now()
+	runs := [][]string{
+		{"--dry-run", "deploy", "--test-flag", "prod"},
+		{"report"},
+		{CompleteCommandId, "dep"},
+	}
+	for _, args := range runs {
+		_, _ = Run(args, registry, io.Discard, WithErrorWriter(io.Discard), WithoutSignalHandling(), WithAuditLog(auditPath))
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("Failed to read the audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Audit log has %d lines, want 2, completions not being audited:\n%s", len(lines), data)
+	}
+
+	wantEntries := []auditEntry{
+		{User: "alice", Command: "deploy", Args: runs[0], ExitCode: StatusOk},
+		{User: "alice", Command: "report", Args: runs[1], ExitCode: 3, Error: "bad input"},
+	}
+	for i, line := range lines {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Audit log line %q is not valid json: %v", line, err)
+		}
+
+		want := wantEntries[i]
+		if entry.User != want.User || entry.Command != want.Command || !slices.Equal(entry.Args, want.Args) ||
+			entry.ExitCode != want.ExitCode || entry.Error != want.Error {
+			t.Errorf("Audit entry = %+v, want %+v", entry, want)
+		}
+		if entry.Time.Before(before.Truncate(time.Second)) || entry.Time.After(time.Now()) || entry.DurationMs < 0 {
+			t.Errorf("Audit entry time = %v, duration = %dms, want the run time", entry.Time, entry.DurationMs)
+		}
+	}
+}
+
+func TestItDoesNotFailTheCommandWhenTheAuditLogCannotBeWritten(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "missing", "audit.log")
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "deploy"})
+
+	var errBuf bytes.Buffer
+	exitCode, err := Run(
+		[]string{"deploy"},
+		registry,
+		io.Discard,
+		WithErrorWriter(&errBuf),
+		WithoutSignalHandling(),
+		WithAuditLog(auditPath),
+	)
+
+	if exitCode != StatusOk || err != nil {
+		t.Errorf("Run() = (%d, %v), want (%d, nil)", exitCode, err, StatusOk)
+	}
+	if !strings.HasPrefix(errBuf.String(), "Warning: failed to write the audit log:") {
+		t.Errorf("Run() error output = %q, want an audit log warning", errBuf.String())
+	}
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindFlags defines a flag for each field of the struct pointed to by target which has a
+// `flag:"name,usage,default"` tag, bound to the field, so that the parsed value is stored
+// into it. The usage and the default value are optional, "flag:\"name\"" defining a flag
+// without usage, defaulting to the zero value. The usage may contain commas when the
+// default value is given, the default being the text after the last comma.
+// The name may be followed by aliases separated by "|", like `flag:"name|n,The name"`,
+// defined with AliasFlag.
+// Fields without the tag are ignored. The supported field types are string, int, int64,
+// bool, float64 and time.Duration, the default value being parsed like the flag package
+// parses the flag value.
+// Call it from DefineFlags, for example with a SayHelloFlags struct:
+//
+//	type SayHelloFlags struct {
+//		Name  string        `flag:"name,The name to greet,world"`
+//		Delay time.Duration `flag:"delay,How long to wait before greeting"`
+//	}
+//
+// It returns an error, without defining any flag, if target is not a pointer to a
+// struct, or if a tagged field is unexported, has an unsupported type or an invalid
+// default value.
+func BindFlags(flagSet *flag.FlagSet, target any) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Pointer || targetValue.IsNil() ||
+		targetValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot bind flags to %T, expected a pointer to a struct", target)
+	}
+
+	structValue := targetValue.Elem()
+	structType := structValue.Type()
+
+	var bindings []func()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, hasTag := field.Tag.Lookup("flag")
+		if !hasTag {
+			continue
+		}
+
+		binding, err := flagBinding(flagSet, field, structValue.Field(i), tag)
+		if err != nil {
+			return fmt.Errorf("cannot bind flag to field %s.%s: %w", structType.Name(), field.Name, err)
+		}
+		bindings = append(bindings, binding)
+	}
+
+	for _, bind := range bindings {
+		bind()
+	}
+	return nil
+}
+
+// flagBinding returns the function defining the flag bound to the field, described by
+// the given flag tag
+func flagBinding(
+	flagSet *flag.FlagSet,
+	field reflect.StructField,
+	fieldValue reflect.Value,
+	tag string,
+) (func(), error) {
+	names, usage, defaultValue := parseFlagTag(tag)
+	name, aliases := names[0], names[1:]
+	if name == "" || slices.Contains(aliases, "") {
+		return nil, errors.New("the flag tag has an empty flag name")
+	}
+	if !field.IsExported() {
+		return nil, errors.New("the field is not exported")
+	}
+
+	var err error
+	var bind func()
+	switch pointer := fieldValue.Addr().Interface().(type) {
+	case *string:
+		bind = func() { flagSet.StringVar(pointer, name, defaultValue, usage) }
+	case *int:
+		var value int
+		if defaultValue != "" {
+			value, err = strconv.Atoi(defaultValue)
+		}
+		bind = func() { flagSet.IntVar(pointer, name, value, usage) }
+	case *int64:
+		var value int64
+		if defaultValue != "" {
+			value, err = strconv.ParseInt(defaultValue, 0, 64)
+		}
+		bind = func() { flagSet.Int64Var(pointer, name, value, usage) }
+	case *bool:
+		var value bool
+		if defaultValue != "" {
+			value, err = strconv.ParseBool(defaultValue)
+		}
+		bind = func() { flagSet.BoolVar(pointer, name, value, usage) }
+	case *float64:
+		var value float64
+		if defaultValue != "" {
+			value, err = strconv.ParseFloat(defaultValue, 64)
+		}
+		bind = func() { flagSet.Float64Var(pointer, name, value, usage) }
+	case *time.Duration:
+		var value time.Duration
+		if defaultValue != "" {
+			value, err = time.ParseDuration(defaultValue)
+		}
+		bind = func() { flagSet.DurationVar(pointer, name, value, usage) }
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", field.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid default value %q: %w", defaultValue, err)
+	}
+	return func() {
+		bind()
+		AliasFlag(flagSet, name, aliases...)
+	}, nil
+}
+
+// parseFlagTag splits a "name|alias,usage,default" flag tag into the flag name followed by
+// its aliases, the usage, holding everything between the first and the last comma, and the
+// default value
+func parseFlagTag(tag string) (names []string, usage string, defaultValue string) {
+	parts := strings.Split(tag, ",")
+	for _, name := range strings.Split(parts[0], "|") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	switch {
+	case len(parts) == 2:
+		usage = parts[1]
+	case len(parts) > 2:
+		usage = strings.Join(parts[1:len(parts)-1], ",")
+		defaultValue = parts[len(parts)-1]
+	}
+	return names, usage, defaultValue
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SayHelloFlags struct {
+	Name     string        `flag:"name,The name to greet,world"`
+	Times    int           `flag:"times,How many times to greet,2"`
+	Id       int64         `flag:"id,The greeting id"`
+	Shout    bool          `flag:"shout,Greet loudly, in capitals,true"`
+	Ratio    float64       `flag:"ratio,,0.5"`
+	Delay    time.Duration `flag:"delay,How long to wait,1s"`
+	Untagged string
+}
+
+func TestItCanBindFlagsToStructFields(t *testing.T) {
+	flagSet := flag.NewFlagSet("say-hello", flag.ContinueOnError)
+	var flags SayHelloFlags
+
+	if err := BindFlags(flagSet, &flags); err != nil {
+		t.Fatalf("BindFlags() error = %v, want nil", err)
+	}
+
+	expectedDefaults := SayHelloFlags{
+		Name: "world", Times: 2, Shout: true, Ratio: 0.5, Delay: time.Second,
+	}
+	if flags != expectedDefaults {
+		t.Errorf("BindFlags() defaults = %+v, want %+v", flags, expectedDefaults)
+	}
+	if usage := flagSet.Lookup("shout").Usage; usage != "Greet loudly, in capitals" {
+		t.Errorf("BindFlags() shout usage = %q, want %q", usage, "Greet loudly, in capitals")
+	}
+	if flagSet.Lookup("Untagged") != nil {
+		t.Errorf("BindFlags() should ignore untagged fields")
+	}
+
+	err := flagSet.Parse(
+		[]string{
+			"--name", "john", "--times", "3", "--id", "42", "--shout=false",
+			"--ratio", "1.5", "--delay", "2m",
+		},
+	)
+	if err != nil {
+		t.Fatalf("Parse() error = %v, want nil", err)
+	}
+
+	expected := SayHelloFlags{
+		Name: "john", Times: 3, Id: 42, Shout: false, Ratio: 1.5, Delay: 2 * time.Minute,
+	}
+	if flags != expected {
+		t.Errorf("BindFlags() parsed = %+v, want %+v", flags, expected)
+	}
+}
+
+func TestItRejectsInvalidFlagBindings(t *testing.T) {
+	var flags SayHelloFlags
+	var unsupported struct {
+		Names []string `flag:"names"`
+	}
+	var unexported struct {
+		name string `flag:"name"`
+	}
+	var invalidDefault struct {
+		Times int `flag:"times,How many times,many"`
+	}
+	var noName struct {
+		Name string `flag:",The name"`
+	}
+
+	tests := []struct {
+		name    string
+		target  any
+		wantErr string
+	}{
+		{name: "not a pointer", target: flags, wantErr: "expected a pointer to a struct"},
+		{name: "nil pointer", target: (*SayHelloFlags)(nil), wantErr: "expected a pointer to a struct"},
+		{name: "unsupported type", target: &unsupported, wantErr: "unsupported field type []string"},
+		{name: "unexported field", target: &unexported, wantErr: "not exported"},
+		{name: "invalid default", target: &invalidDefault, wantErr: "invalid default value \"many\""},
+		{name: "missing name", target: &noName, wantErr: "empty flag name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				flagSet.SetOutput(io.Discard)
+
+				err := BindFlags(flagSet, tt.target)
+
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("BindFlags() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				defined := 0
+				flagSet.VisitAll(func(*flag.Flag) { defined++ })
+				if defined != 0 {
+					t.Errorf("BindFlags() defined %d flags on failure, want 0", defined)
+				}
+			},
+		)
+	}
+}
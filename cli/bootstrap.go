@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -8,13 +9,34 @@ import (
 	"maps"
 	"os"
 	"reflect"
+	"regexp"
+	"runtime/debug"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 const StatusOk = 0
 const StatusErr = 1
 
+// StatusInterrupted is the default exit code used when the process is stopped by
+// SIGINT or SIGTERM (128 + SIGINT, as shells report it)
+const StatusInterrupted = 130
+
+// StatusTimeout is the exit code used when a command does not finish within its timeout
+// (as reported by the GNU timeout utility)
+const StatusTimeout = 124
+
+// StatusUsage is the exit code used when the command line flags cannot be parsed (as the
+// flag package does with flag.ExitOnError)
+const StatusUsage = 2
+
+// StatusOutputErr is the exit code used when a failure cannot be written to the error
+// writer, so that output failures can be told apart from command failures (EX_IOERR, as
+// defined by sysexits.h)
+const StatusOutputErr = 74
+
 // Command interface defines the methods that a command must implement
 type Command interface {
 	Id() string
@@ -24,6 +46,70 @@ type Command interface {
 	ValidateFlags() error
 }
 
+// ContextualCommand is an optional interface for commands that want to observe
+// cancellation. When a command implements it, ExecContext is called instead of Exec.
+type ContextualCommand interface {
+	Command
+	ExecContext(ctx context.Context, stdWriter io.Writer) error
+}
+
+// WrapperCommand is implemented by commands decorating another command, like
+// FsLockableCommand. Optional interfaces describing a command (for example
+// ConfigurableCommand) are looked up through the whole chain of wrapped commands.
+type WrapperCommand interface {
+	Command
+	Unwrap() Command
+}
+
+// findOptional returns the first command in the wrapping chain of cmd which implements
+// the optional interface T
+func findOptional[T any](cmd Command) (T, bool) {
+	for cmd != nil {
+		if optional, ok := cmd.(T); ok {
+			return optional, true
+		}
+		wrapper, isWrapper := cmd.(WrapperCommand)
+		if !isWrapper {
+			break
+		}
+		cmd = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
+
+// ArgsReceiver is an optional interface for commands which accept positional args.
+// SetArgs is called after flag parsing, before ValidateFlags, with the args left after
+// the flags: everything from the first non-flag argument on, or everything after a "--"
+// terminator (the terminator itself is dropped). A "--" given before the command id is
+// consumed while resolving the command and does not end the command flags.
+type ArgsReceiver interface {
+	Command
+	SetArgs(args []string)
+}
+
+// InitializableCommand is an optional interface for commands needing one-time setup,
+// like opening a database connection, and teardown. When running the command, Init is
+// called first, before DefineFlags, and an Init error aborts the run. Close is then always
+// called once the command is done, even if its execution failed or panicked (or timed
+// out, in which case a command ignoring its context may still be running). Note that the
+// help command calls DefineFlags to list the flags without calling Init.
+type InitializableCommand interface {
+	Command
+	Init() error
+	Close() error
+}
+
+// DeprecatableCommand is an optional interface for commands being phased out. When
+// Deprecated returns a non-empty message (e.g. "use 'new-cmd' instead"), a warning is
+// written to the error writer before the command runs, and the help output marks the
+// command as deprecated. The command still runs normally.
+type DeprecatableCommand interface {
+	Command
+	Deprecated() string
+}
+
 type LockableCommand interface {
 	Command
 	Lock() (bool, error)
@@ -37,58 +123,280 @@ func (*CommandWithoutFlags) ValidateFlags() error {
 	return nil
 }
 
-// setupFlagSet creates and configures a flag.FlagSet for the given command
-func setupFlagSet(cmd Command, outputWriter io.Writer) *flag.FlagSet {
+// setupFlagSet creates and configures a flag.FlagSet for the given command. Usage and
+// parse errors are written to errWriter.
+func setupFlagSet(cmd Command, errWriter io.Writer) *flag.FlagSet {
 	flagSet := flag.NewFlagSet(cmd.Id(), flag.ContinueOnError)
+	flagSet.SetOutput(errWriter)
 	flagSet.Usage = func() {
-		_, _ = fmt.Fprintf(outputWriter, "Usage of %s:\n", cmd.Id())
-		flagSet.PrintDefaults()
+		_, _ = fmt.Fprintf(flagSet.Output(), "Usage of %s:\n", cmd.Id())
+		if description := strings.TrimSpace(cmd.Description()); description != "" {
+			_, _ = fmt.Fprintf(flagSet.Output(), "  %s\n", description)
+		}
+		printDefaults(flagSet)
 	}
 
 	return flagSet
 }
 
-// runCommand runs the given command with the provided arguments
-func runCommand(cmd Command, args []string, outputWriter io.Writer) (cmdErr error) {
+// defineCommandFlags defines the command flags, plus the flags the framework adds for
+// commands implementing optional interfaces
+func defineCommandFlags(cmd Command, flagSet *flag.FlagSet) {
+	cmd.DefineFlags(flagSet)
+
+	if configurableCmd, ok := findOptional[ConfigurableCommand](cmd); ok {
+		flagSet.String(
+			ConfigFlagName,
+			configurableCmd.DefaultConfigPath(),
+			"Path to a JSON or TOML file with flag defaults",
+		)
+	}
+}
+
+// defineCommandFlagsRecovering defines the command flags like defineCommandFlags does, returning a
+// panic in DefineFlags as a PanicError naming the command, so that it can be told apart
+// from a panic during the execution. A flag defined twice is returned as a
+// FlagRedefinedError naming the flag, instead of the message the flag package prints.
+func defineCommandFlagsRecovering(cmd Command, flagSet *flag.FlagSet) (err error) {
+	output := flagSet.Output()
+	flagSet.SetOutput(io.Discard)
 	defer func() {
-		if err := recover(); err != nil {
-			switch v := err.(type) {
-			case string:
-				err = errors.New(v)
-			case error:
-				err = v
-			default:
-				err = errors.New(fmt.Sprint(v))
+		flagSet.SetOutput(output)
+		if recovered := recover(); recovered != nil {
+			err = panicToError(recovered)
+			if flagName, redefined := redefinedFlagName(recovered); redefined {
+				err = &FlagRedefinedError{CommandId: cmd.Id(), FlagName: flagName, Err: err}
+				return
 			}
-			cmdErr = err.(error)
+			err = fmt.Errorf("command %s failed to define flags: %w", cmd.Id(), err)
 		}
 	}()
 
-	// Setup flag set for the command
-	flagSet := setupFlagSet(cmd, outputWriter)
-	flagSet.SetOutput(outputWriter)
-	cmd.DefineFlags(flagSet)
+	defineCommandFlags(cmd, flagSet)
+	return nil
+}
+
+// redefinedFlagName returns the name of the flag from the panic value of the flag package
+// on a flag redefinition, like "greet flag redefined: name"
+func redefinedFlagName(recovered any) (string, bool) {
+	message, ok := recovered.(string)
+	if !ok {
+		return "", false
+	}
+	_, name, found := strings.Cut(message, "flag redefined: ")
+	if !found || name == "" {
+		return "", false
+	}
+	return flagDisplayName(name), true
+}
+
+// isHelpRequest reports whether the positional args, left after parsing the flags, ask for
+// the usage of the command, like the -h and --help flags do before them. They do so with a
+// trailing "help" word or with a help flag following the positional args, which the flag
+// package does not parse. Args following a "--" terminator are never help requests, nor
+// are help flags the command defines itself.
+func isHelpRequest(args []string, flagSet *flag.FlagSet) bool {
+	positionalArgs := flagSet.Args()
+	if len(positionalArgs) == 0 {
+		return false
+	}
+	if terminatorIndex := len(args) - len(positionalArgs) - 1; terminatorIndex >= 0 &&
+		args[terminatorIndex] == "--" {
+		return false
+	}
+
+	for _, arg := range positionalArgs {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "-help", "--help":
+			if flagSet.Lookup(strings.TrimLeft(arg, "-")) == nil {
+				return true
+			}
+		}
+	}
+	return positionalArgs[len(positionalArgs)-1] == "help"
+}
+
+// passArgs calls SetArgs on every command of the wrapping chain implementing ArgsReceiver
+func passArgs(cmd Command, args []string) {
+	for cmd != nil {
+		if receiver, ok := cmd.(ArgsReceiver); ok {
+			receiver.SetArgs(args)
+		}
+		wrapper, isWrapper := cmd.(WrapperCommand)
+		if !isWrapper {
+			return
+		}
+		cmd = wrapper.Unwrap()
+	}
+}
+
+// execCommand executes the command, preferring ExecContext when it is implemented
+func execCommand(ctx context.Context, cmd Command, outputWriter io.Writer) error {
+	if ctxCmd, ok := cmd.(ContextualCommand); ok {
+		return ctxCmd.ExecContext(ctx, outputWriter)
+	}
+	if inputCmd, ok := cmd.(InputCommand); ok {
+		return inputCmd.ExecIO(Input(ctx), outputWriter)
+	}
+	return cmd.Exec(outputWriter)
+}
+
+// panicToError converts a recovered panic value into a PanicError carrying the stack
+// trace of the panic. It must be called from the deferred recovering function.
+func panicToError(recovered any) error {
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("command panicked: %v", recovered)
+	}
+	return &PanicError{Err: err, Stack: trimPanicStack(debug.Stack())}
+}
+
+// runCommand runs the given command with the provided arguments. Command output is
+// written to outputWriter while flag usage and parse errors go to errWriter.
+func runCommand(
+	ctx context.Context,
+	cmd Command,
+	args []string,
+	outputWriter io.Writer,
+	errWriter io.Writer,
+) (cmdErr error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			cmdErr = panicToError(recovered)
+		}
+	}()
+	ctx = withErrWriter(ctx, errWriter)
+
+	// Run the command lifecycle: Init, DefineFlags, Parse, Validate, Exec and Close
+	if initializable, ok := findOptional[InitializableCommand](cmd); ok {
+		if cmdErr = initializable.Init(); cmdErr != nil {
+			return fmt.Errorf("failed to initialize command: %w", cmdErr)
+		}
+		defer func() {
+			if closeErr := initializable.Close(); closeErr != nil {
+				cmdErr = errors.Join(cmdErr, fmt.Errorf("failed to close command: %w", closeErr))
+			}
+		}()
+	}
+
+	if message := deprecationMessage(cmd); message != "" && !IsQuiet(ctx) {
+		_, _ = fmt.Fprintf(errWriter, "Warning: command %s is deprecated, %s\n", cmd.Id(), message)
+	}
 
-	// Parse flagSet
-	if !flagSet.Parsed() {
-		if cmdErr = flagSet.Parse(args); cmdErr != nil {
+	// Setup flag set for the command. Commands taking raw args get them unparsed.
+	flagSet := setupFlagSet(cmd, errWriter)
+	flagErrorPolicyFrom(ctx).apply(flagSet)
+	positionalArgs := args
+	if !takesRawArgs(cmd) {
+		if cmdErr = defineCommandFlagsRecovering(cmd, flagSet); cmdErr != nil {
 			return cmdErr
 		}
+
+		// Parse flagSet. The flag package already printed the usage on failure, and on a
+		// help request (-h or --help), which is not a failure.
+		if !flagSet.Parsed() {
+			if cmdErr = flagSet.Parse(args); errors.Is(cmdErr, flag.ErrHelp) {
+				return nil
+			} else if cmdErr != nil {
+				eventsFrom(ctx).validationFailed(cmdErr)
+				return &FlagParseError{Err: cmdErr}
+			}
+		}
+		if isHelpRequest(args, flagSet) {
+			flagSet.Usage()
+			return nil
+		}
+
+		// Apply the environment, then the config file, to the flags not set in the args
+		var envFlags []string
+		if prefix, enabled := envPrefixFrom(ctx); enabled {
+			if envFlags, cmdErr = applyFlagEnv(flagSet, prefix); cmdErr != nil {
+				return cmdErr
+			}
+		}
+		if _, isConfigurable := findOptional[ConfigurableCommand](cmd); isConfigurable {
+			configPath := flagSet.Lookup(ConfigFlagName).Value.String()
+			if _, cmdErr = applyFlagDefaults(flagSet, configPath, envFlags); cmdErr != nil {
+				return cmdErr
+			}
+		}
+
+		// Replace the "@path" values of the file flags with the content of the files
+		if fileFlagsCmd, ok := findOptional[FileFlagsCommand](cmd); ok {
+			if cmdErr = ExpandFileFlags(flagSet, Input(ctx), fileFlagsCmd.FileFlags()...); cmdErr != nil {
+				return cmdErr
+			}
+		}
+
+		eventsFrom(ctx).flagsParsed(flagSet)
+		positionalArgs = flagSet.Args()
+	}
+
+	// Hand the positional args, left after the flags, to the command
+	passArgs(cmd, positionalArgs)
+
+	// Check and validate the flags and args, showing the error and the usage to help the
+	// user fix the invocation, like the flag package does on parse errors
+	if cmdErr = CheckRequired(flagSet); cmdErr != nil {
+		reportInvalidFlags(flagSet, cmdErr)
+		eventsFrom(ctx).validationFailed(cmdErr)
+		return cmdErr
+	}
+
+	if cmdErr = validateArgs(cmd, positionalArgs); cmdErr != nil {
+		reportInvalidFlags(flagSet, cmdErr)
+		eventsFrom(ctx).validationFailed(cmdErr)
+		return cmdErr
 	}
 
-	cmdErr = cmd.ValidateFlags()
+	cmdErr = validateFlags(cmd, flagSet)
 	if cmdErr != nil {
+		reportInvalidFlags(flagSet, cmdErr)
+		eventsFrom(ctx).validationFailed(cmdErr)
 		return cmdErr
 	}
 
-	// Execute the command
-	if cmdErr = cmd.Exec(outputWriter); cmdErr != nil {
+	// Execute the command, within its timeout if it has one
+	if timeout := commandTimeout(ctx, cmd); timeout > 0 {
+		return execCommandWithTimeout(ctx, cmd, outputWriter, timeout)
+	}
+	if cmdErr = execCommand(ctx, cmd, outputWriter); cmdErr != nil {
 		return cmdErr
 	}
 
 	return cmdErr
 }
 
+// RunCommand runs a single command outside of Bootstrap: it parses the args into the
+// command flags, validates them and executes the command, writing its output to
+// outputWriter and the flag usage and parse errors to errWriter. Panics are recovered and
+// returned as PanicError. It is meant for embedding and testing commands, see the clitest
+// package.
+func RunCommand(
+	ctx context.Context,
+	cmd Command,
+	args []string,
+	outputWriter io.Writer,
+	errWriter io.Writer,
+) error {
+	return runCommand(ctx, cmd, args, outputWriter, errWriter)
+}
+
+// Execute runs a single known command with the given args, bypassing the registry lookup
+// and the help command, through RunCommand. The command output is written to out, while
+// the flag usage and parse errors go to the writer set with WithErrorWriter, os.Stderr by
+// default. Only the WithContext and WithErrorWriter options apply.
+func Execute(cmd Command, args []string, out io.Writer, opts ...BootstrapOption) error {
+	options := newBootstrapOptions(opts...)
+	errWriter := options.errWriter
+	if errWriter == nil {
+		errWriter = os.Stderr
+	}
+	return RunCommand(options.ctx, cmd, args, out, errWriter)
+}
+
 // parseCmdInput parses the command name and arguments from the input args
 func parseCmdInput(args []string) (cmdName string, cmdArgs []string) {
 	if len(args) == 0 {
@@ -107,98 +415,578 @@ func parseCmdInput(args []string) (cmdName string, cmdArgs []string) {
 
 // CommandsRegistry holds all registered commands
 type CommandsRegistry struct {
-	commands map[string]Command
+	commands        map[string]Command
+	caseInsensitive bool
+}
+
+// RegistryOption configures optional CommandsRegistry behaviour
+type RegistryOption func(registry *CommandsRegistry)
+
+// WithCaseInsensitiveLookup makes the registry match command ids regardless of their
+// case, so "Say-Hello" resolves to the "say-hello" command. Registering ids which only
+// differ by case is rejected in this mode.
+func WithCaseInsensitiveLookup() RegistryOption {
+	return func(registry *CommandsRegistry) {
+		registry.caseInsensitive = true
+	}
 }
 
-func NewCommandsRegistry() *CommandsRegistry {
-	return &CommandsRegistry{make(map[string]Command)}
+func NewCommandsRegistry(opts ...RegistryOption) *CommandsRegistry {
+	registry := &CommandsRegistry{commands: make(map[string]Command)}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(registry)
+		}
+	}
+	return registry
+}
+
+// lookupKey returns the key under which the command with the given id is stored
+func (registry *CommandsRegistry) lookupKey(id string) string {
+	if registry.caseInsensitive {
+		return strings.ToLower(id)
+	}
+	return id
+}
+
+// NewCommandsRegistryFrom creates a registry holding the given commands
+func NewCommandsRegistryFrom(cmds ...Command) (*CommandsRegistry, error) {
+	registry := NewCommandsRegistry()
+	if err := registry.RegisterAll(cmds...); err != nil {
+		return nil, err
+	}
+	return registry, nil
 }
 
-// Register adds a command to the registry
+// commandIdPattern matches the allowed command ids, see validateCommandId
+var commandIdPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:-]*$`)
+
+// validateCommandId checks that the id can be typed as a single command line argument:
+// it must start with a letter or a digit, followed by letters, digits, '-', '_', '.' or
+// ':'. Spaces, slashes and other characters are rejected, as are ids starting with '-',
+// which would be parsed as flags.
+func validateCommandId(id string) error {
+	if id == "" {
+		return errors.New("invalid command id, the id must not be empty")
+	}
+	if !commandIdPattern.MatchString(id) {
+		return fmt.Errorf(
+			"invalid command id '%s', ids must start with a letter or a digit and "+
+				"contain only letters, digits, '-', '_', '.' or ':'",
+			id,
+		)
+	}
+	return nil
+}
+
+// isNilCommand reports whether the command is nil, including a nil pointer (or other
+// nillable value) wrapped in the Command interface, whose methods would panic
+func isNilCommand(cmd Command) bool {
+	if cmd == nil {
+		return true
+	}
+	value := reflect.ValueOf(cmd)
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// Register adds a command to the registry. Nil commands are rejected and the command id
+// is validated, see validateCommandId for the allowed format.
 func (registry *CommandsRegistry) Register(cmd Command) error {
-	if _, exists := registry.commands[cmd.Id()]; exists {
+	if isNilCommand(cmd) {
+		return fmt.Errorf("cannot register a nil command (%T)", cmd)
+	}
+	if err := validateCommandId(cmd.Id()); err != nil {
+		return err
+	}
+
+	key := registry.lookupKey(cmd.Id())
+	if existing, exists := registry.commands[key]; exists {
+		if existing.Id() != cmd.Id() {
+			return fmt.Errorf(
+				"command '%s' collides with the already registered command '%s'",
+				cmd.Id(),
+				existing.Id(),
+			)
+		}
 		return fmt.Errorf("command '%s' is already registered", cmd.Id())
 	}
-	registry.commands[cmd.Id()] = cmd
+	registry.commands[key] = cmd
+	return nil
+}
+
+// RegisterAll registers the commands in order. Registration continues past failures,
+// the returned error aggregates all of them.
+func (registry *CommandsRegistry) RegisterAll(cmds ...Command) error {
+	var errs []error
+	for _, cmd := range cmds {
+		if err := registry.Register(cmd); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Unregister removes a command from the registry
+func (registry *CommandsRegistry) Unregister(id string) error {
+	key := registry.lookupKey(id)
+	if _, exists := registry.commands[key]; !exists {
+		return fmt.Errorf("command '%s' is not registered", id)
+	}
+	delete(registry.commands, key)
 	return nil
 }
 
-// Commands returns a copy of all registered commands
+// Commands returns a copy of all registered commands, keyed by their ID
 func (registry *CommandsRegistry) Commands() map[string]Command {
 	cmdCopy := make(map[string]Command, len(registry.commands))
-	for name, cmd := range registry.commands {
-		cmdCopy[name] = cmd
+	for _, cmd := range registry.commands {
+		cmdCopy[cmd.Id()] = cmd
 	}
 	return cmdCopy
 }
 
 // Command returns a command by its ID
 func (registry *CommandsRegistry) Command(id string) (Command, bool) {
-	cmd, ok := registry.commands[id]
+	cmd, ok := registry.commands[registry.lookupKey(id)]
 	return cmd, ok
 }
 
+// BootstrapWith bootstraps everything needed for the user CLI request: it processes the
+// user input, runs the requested command and exits the process with its exit code. The
+// output goes to os.Stdout and the error messages to os.Stderr, unless other writers are
+// set via WithOutputWriter and WithErrorWriter, and the process exits via os.Exit, unless
+// another function is set via WithProcessExit. Additional behaviour is configured through
+// the other BootstrapOption arguments, or all at once with a BootstrapOptions struct.
+func BootstrapWith(args []string, availableCommands *CommandsRegistry, opts ...BootstrapOption) {
+	processExit := newBootstrapOptions(opts...).processExit
+	if processExit == nil {
+		processExit = os.Exit
+	}
+
+	// The signal handler may force-exit concurrently with the normal exit path,
+	// make sure the process exit callback is only invoked once
+	var exitOnce sync.Once
+	exit := func(code int) {
+		exitOnce.Do(
+			func() {
+				processExit(code)
+			},
+		)
+	}
+
+	result := run(args, availableCommands, nil, exit, opts...)
+	exit(result.ExitCode)
+}
+
 // Bootstrap Will bootstrap everything needed for the user CLI request. Will process the
 // user input and run the requested command. By default, will output to os.Stdout if
-// nil is provided for the io.Writer argument.
+// nil is provided for the io.Writer argument. Error messages are written to os.Stderr,
+// unless another writer is provided via WithErrorWriter. Additional behaviour can be
+// configured through the variadic BootstrapOption arguments.
+//
+// Deprecated: use BootstrapWith, passing the output writer and the process exit function
+// as options: BootstrapWith(args, registry, WithOutputWriter(w), WithProcessExit(exit)).
 func Bootstrap(
 	args []string,
 	availableCommands *CommandsRegistry,
 	outputWriter io.Writer,
 	processExit func(code int),
+	opts ...BootstrapOption,
 ) {
+	BootstrapWith(
+		args,
+		availableCommands,
+		append([]BootstrapOption{WithOutputWriter(outputWriter), WithProcessExit(processExit)}, opts...)...,
+	)
+}
+
+// Run processes the user input and runs the requested command like BootstrapWith does, but
+// returns the exit code and the command error instead of exiting the process, so that
+// callers can handle them, for example by checking errors.Is(err, ErrCommandNotFound).
+// Failures are still reported to the error writer. Run never exits the process: on
+// SIGINT or SIGTERM, the command context is cancelled, but a command ignoring it is waited
+// for, even after the shutdown grace period or a second signal, and the signal exit code
+// is returned. Use WithoutSignalHandling to leave the signals to the caller.
+func Run(
+	args []string,
+	availableCommands *CommandsRegistry,
+	outputWriter io.Writer,
+	opts ...BootstrapOption,
+) (exitCode int, err error) {
+	result := run(args, availableCommands, outputWriter, nil, opts...)
+	return result.ExitCode, result.Err
+}
+
+// RunWithResult runs the requested command like Run does, returning the ExecResult of the
+// execution, with its duration and whether the command panicked besides the exit code and
+// the command error. Like Run, it never exits the process.
+func RunWithResult(
+	args []string,
+	availableCommands *CommandsRegistry,
+	outputWriter io.Writer,
+	opts ...BootstrapOption,
+) ExecResult {
+	return run(args, availableCommands, outputWriter, nil, opts...)
+}
+
+// run processes the user input and runs the requested command, returning the result of
+// the execution. forceExit is called when the command has to be force-exited after a
+// shutdown signal, nil never exiting the process.
+func run(
+	args []string,
+	availableCommands *CommandsRegistry,
+	outputWriter io.Writer,
+	forceExit func(code int),
+	opts ...BootstrapOption,
+) (execResult ExecResult) {
+	start := time.Now()
+	options := newBootstrapOptions(opts...)
+
+	if outputWriter == nil {
+		outputWriter = options.outputWriter
+	}
 	if outputWriter == nil {
 		outputWriter = os.Stdout
 	}
+	terminalWriter := outputWriter
 
-	if processExit == nil {
-		processExit = os.Exit
+	errWriter := options.errWriter
+	if errWriter == nil {
+		errWriter = os.Stderr
+	}
+
+	if options.outputCapture != nil {
+		outputWriter = NewTeeWriter(outputWriter, options.outputCapture)
+	}
+	if options.errorCapture != nil {
+		errWriter = NewTeeWriter(errWriter, options.errorCapture)
+	}
+
+	input := options.input
+	if input == nil {
+		input = os.Stdin
+	}
+
+	ctx := options.ctx
+	var shutdown *shutdownHandler
+
+	// Record the run in the audit log once it is over, whatever its outcome, except for
+	// shell completions. Failing to write it does not fail the command.
+	auditedId, auditedArgs := "", args
+	if options.auditLogPath != "" {
+		defer func() {
+			if auditedId == CompleteCommandId {
+				return
+			}
+			entry := newAuditEntry(start, auditedId, auditedArgs, execResult)
+			if err := appendAuditEntry(options.auditLogPath, entry); err != nil && !IsQuiet(ctx) {
+				_ = writeFailure(errWriter, fmt.Sprintf("Warning: failed to write the audit log: %s\n", err))
+			}
+		}()
+	}
+
+	// Report the run to the metrics collector once it is over, like the audit log, unless
+	// no command was resolved, because the global flags could not be parsed
+	defer func() {
+		if auditedId != "" && auditedId != CompleteCommandId {
+			options.metricsCollector.ObserveExecution(auditedId, execResult.Duration, execResult.Err)
+		}
+	}()
+
+	// When the command was interrupted by a signal, the signal exit code takes precedence
+	resolveExitCode := func(code int) int {
+		if shutdown != nil && shutdown.Interrupted() {
+			return options.signalExitCode
+		}
+		return code
+	}
+
+	if options.handleSignals {
+		var exitProcess func()
+		if forceExit != nil {
+			exitProcess = func() {
+				forceExit(options.signalExitCode)
+			}
+		}
+		ctx, shutdown = handleShutdownSignals(ctx, options.shutdownGracePeriod, exitProcess)
+		defer shutdown.Stop()
+	}
+
+	// The exit code of a command error, computed in one place for the result, the events
+	// and the logging middleware
+	commandExitCode := func(err error) int {
+		if errors.Is(err, CommandLocked) {
+			return resolveExitCode(options.skippedExitCode)
+		}
+		return resolveExitCode(exitCodeFor(err))
 	}
+	ctx = withExitCodeResolver(ctx, commandExitCode)
 
-	_ = availableCommands.Register(
-		&HelpCommand{
-			CommandWithoutFlags{},
-			slices.Collect(
-				maps.Values(
-					availableCommands.
-						Commands(),
+	// A help command registered by the caller takes precedence over the built-in one
+	helpId := (&HelpCommand{}).Id()
+	if _, hasHelp := availableCommands.Command(helpId); !hasHelp && !options.withoutDefaultHelp {
+		_ = availableCommands.Register(
+			NewHelpCommand(
+				slices.Collect(
+					maps.Values(
+						availableCommands.
+							Commands(),
+					),
 				),
 			),
-		},
+		)
+	}
+
+	// Global flags are parsed before the command id, the remaining args hold the command
+	// The flag package writes text, so with the json error format, failures are only
+	// reported by the json lines
+	silentFlagErrors := options.silentFlagErrors || options.errorFormat == ErrorFormatJson
+	globalFlagsErrWriter := errWriter
+	if silentFlagErrors {
+		globalFlagsErrWriter = io.Discard
+	}
+	globalFlagSet := newGlobalFlagSet(options.globalFlags, globalFlagsErrWriter)
+
+	// With a default command, the args following the global flags may be its args
+	globalArgs, defaultCommandArgs := args, []string(nil)
+	if options.defaultCommand != "" {
+		globalArgs, defaultCommandArgs = splitGlobalFlagArgs(globalFlagSet, args)
+	}
+	if err := globalFlagSet.Parse(globalArgs); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			exitCode := StatusUsage
+			outputErr := writeErrorReport(
+				errWriter,
+				options.errorFormat,
+				errorReport{
+					Error:    fmt.Sprintf("failed to parse global flags: %s", err),
+					ExitCode: resolveExitCode(StatusUsage),
+				},
+				fmt.Sprintf("Failed to parse global flags with error: %s\n", err),
+			)
+			if outputErr != nil {
+				exitCode = StatusOutputErr
+			}
+			parseErr := fmt.Errorf("failed to parse global flags: %w", err)
+
+			// No command was resolved, so the events report the failure without a command id
+			if options.eventWriter != nil {
+				events := newEventEmitter(options.eventWriter, "")
+				events.commandStarted()
+				events.commandEnded(resolveExitCode(exitCode), parseErr)
+			}
+			return newExecResult(resolveExitCode(exitCode), parseErr, time.Since(start))
+		}
+		args = nil
+	} else {
+		args = append(globalFlagSet.Args(), defaultCommandArgs...)
+	}
+	ctx = withGlobalFlags(ctx, globalFlagSet)
+
+	// Verbose error reporting overrides the quiet mode
+	verbose := options.verbose
+	if verboseFlag, _ := globalFlagValue[bool](globalFlagSet, VerboseFlagName); verboseFlag {
+		verbose = true
+	}
+	quietFlag, _ := globalFlagValue[bool](globalFlagSet, QuietFlagName)
+	quiet := (options.quiet || quietFlag) && !verbose
+	ctx = withQuiet(ctx, quiet)
+	if options.envPrefix != nil {
+		ctx = withEnvPrefix(ctx, *options.envPrefix)
+	}
+	ctx = withFlagErrorPolicy(
+		ctx,
+		flagErrorPolicy{handling: options.flagErrorHandling, silent: silentFlagErrors},
 	)
 
+	// Pipe the output through the pager, which writes it to the terminal
+	noPager, _ := globalFlagValue[bool](globalFlagSet, NoPagerFlagName)
+	if command, terminal, page := pagerCommand(options.pager, noPager, terminalWriter); page {
+		if pager, err := startPager(command, terminal, errWriter); err != nil {
+			if !quiet {
+				_ = writeFailure(errWriter, fmt.Sprintf("Warning: %s, writing the output directly\n", err))
+			}
+		} else {
+			outputWriter = pager
+			if options.outputCapture != nil {
+				outputWriter = NewTeeWriter(pager, options.outputCapture)
+			}
+			defer func() {
+				if err := pager.close(); err != nil && !quiet {
+					_ = writeFailure(errWriter, fmt.Sprintf("Warning: %s\n", err))
+				}
+			}()
+		}
+	}
+
+	if options.progressReporting {
+		ctx = withProgress(ctx, NewProgressReporter(outputWriter))
+	}
+
 	cmdId, cmdArgs := parseCmdInput(args)
+	auditedId = cmdId
+	if cmdId == CompleteCommandId {
+		for _, suggestion := range complete(availableCommands, cmdArgs) {
+			_, _ = fmt.Fprintln(outputWriter, suggestion)
+		}
+		return newExecResult(StatusOk, nil, time.Since(start))
+	}
+
+	if options.defaultCommand != "" && (cmdId == "" || strings.HasPrefix(cmdId, "-")) {
+		// No command id given, the args (if any) are the default command args
+		if _, hasDefault := availableCommands.Command(options.defaultCommand); hasDefault {
+			cmdId, cmdArgs = options.defaultCommand, args
+		} else {
+			if !quiet {
+				_ = writeFailure(
+					errWriter,
+					fmt.Sprintf(
+						"The default command %s does not exist, showing help instead\n",
+						options.defaultCommand,
+					),
+				)
+			}
+			cmdId, cmdArgs = "", nil
+		}
+	}
 	if cmdId == "" {
-		cmdId = (&HelpCommand{}).Id()
+		cmdId = helpId
 	}
 
 	var cmdErr error
+	var invocationResult *ExecResult
 	cmd, exists := availableCommands.Command(cmdId)
-	if !exists {
-		cmdErr = fmt.Errorf("The command %s does not exist\n", cmdId)
-	} else {
-		cmdErr = runCommand(cmd, cmdArgs, outputWriter)
+	if exists {
+		cmd, cmdId, cmdArgs, exists = resolveSubcommand(cmd, cmdArgs)
 	}
+	auditedId = cmdId
 
-	if cmdErr != nil {
-		_, outputErr := outputWriter.Write(
-			[]byte(
-				fmt.Sprintf(
-					"Failed to execute command %s with error: %s\n",
-					cmdId,
-					cmdErr.Error(),
-				),
-			),
+	// The events report the resolved id, like "db migrate" or the id of the default command
+	var events *eventEmitter
+	if options.eventWriter != nil {
+		events = newEventEmitter(options.eventWriter, cmdId)
+		ctx = withEvents(ctx, events)
+	}
+	events.commandStarted()
+
+	if !exists {
+		// The command not found hook may run, or forward, the invocation itself
+		handled := false
+		if options.commandNotFoundHook != nil {
+			handled, cmdErr = options.commandNotFoundHook(cmdId, cmdArgs)
+		}
+
+		if !handled {
+			candidateIds := slices.Collect(maps.Keys(availableCommands.Commands()))
+			if group, isGroup := cmd.(*CommandGroup); isGroup {
+				candidateIds = candidateIds[:0]
+				for _, child := range group.Commands() {
+					candidateIds = append(candidateIds, child.Id())
+				}
+			}
+			requestedId := cmdId[strings.LastIndex(cmdId, " ")+1:]
+
+			cmdErr = &CommandNotFoundError{
+				Id:          cmdId,
+				Suggestions: suggestCommandIds(requestedId, candidateIds),
+			}
+		}
+	} else if cmd, cmdErr = decorateCommand(cmd, options.decorators); cmdErr == nil {
+		middlewares := options.middlewares
+		if options.logger != nil {
+			middlewares = append([]Middleware{loggingMiddleware(options.logger)}, middlewares...)
+		}
+
+		handler := chainMiddlewares(runInvocation, middlewares)
+		invocationResult = &ExecResult{}
+		cmdErr = handler(
+			ctx,
+			Invocation{
+				Id:           cmdId,
+				Command:      cmd,
+				Args:         cmdArgs,
+				OutputWriter: outputWriter,
+				ErrWriter:    errWriter,
+				Input:        input,
+				GlobalFlags:  globalFlagSet,
+				Result:       invocationResult,
+			},
 		)
-		if outputErr != nil {
-			fmt.Printf(
-				"Error writing to the provided output writer %s\n",
-				reflect.TypeOf(outputWriter),
+	}
+
+	// A command skipped because its lock is held by another process is not a failure
+	if errors.Is(cmdErr, CommandLocked) {
+		if !quiet {
+			_ = writeErrorReport(
+				errWriter,
+				options.errorFormat,
+				errorReport{
+					Command:  cmdId,
+					Error:    cmdErr.Error(),
+					ExitCode: commandExitCode(cmdErr),
+					Skipped:  true,
+				},
+				fmt.Sprintf("Skipped command %s: %s\n", cmdId, cmdErr.Error()),
 			)
 		}
-		processExit(StatusErr)
-		return
+		events.commandEnded(commandExitCode(cmdErr), cmdErr)
+		return newExecResult(commandExitCode(cmdErr), cmdErr, time.Since(start))
 	}
 
-	processExit(StatusOk)
+	// Flag parse errors were already reported by the flag package, along with the usage,
+	// unless it is silenced
+	var parseErr *FlagParseError
+	alreadyReported := errors.As(cmdErr, &parseErr) && !silentFlagErrors
+
+	if cmdErr != nil && !isSilentExit(cmdErr) && !alreadyReported && !quiet {
+		report := errorReport{
+			Command:  cmdId,
+			Error:    strings.TrimSpace(cmdErr.Error()),
+			ExitCode: commandExitCode(cmdErr),
+		}
+		message := fmt.Sprintf("Failed to execute command %s with error: %s\n", cmdId, cmdErr.Error())
+
+		var panicErr *PanicError
+		if verbose && errors.As(cmdErr, &panicErr) {
+			report.Stack = string(panicErr.Stack)
+			message += fmt.Sprintf("%s\n", panicErr.Stack)
+		}
+
+		if writeErrorReport(errWriter, options.errorFormat, report, message) != nil {
+			events.commandEnded(resolveExitCode(StatusOutputErr), cmdErr)
+			return newExecResult(resolveExitCode(StatusOutputErr), cmdErr, time.Since(start))
+		}
+	}
+
+	events.commandEnded(commandExitCode(cmdErr), cmdErr)
+	result := newExecResult(commandExitCode(cmdErr), cmdErr, time.Since(start))
+	if invocationResult != nil && cmdErr == nil {
+		result.Value = invocationResult.Value
+	}
+	return result
+}
+
+// fallbackErrWriter receives the failure messages which could not be written to the
+// error writer
+var fallbackErrWriter io.Writer = os.Stderr
+
+// writeFailure writes the failure message to the error writer. If that fails, the writer
+// error and the original message are written to os.Stderr instead, and the writer error is
+// returned.
+func writeFailure(errWriter io.Writer, message string) error {
+	_, outputErr := errWriter.Write([]byte(message))
+	if outputErr != nil {
+		_, _ = fmt.Fprintf(
+			fallbackErrWriter,
+			"Failed to write to the error writer %T: %s\n%s",
+			errWriter,
+			outputErr,
+			message,
+		)
+	}
+	return outputErr
 }
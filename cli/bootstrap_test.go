@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -66,6 +67,18 @@ func (m *MockCommandWithFlags) ValidateFlags() error {
 	return m.validateErr
 }
 
+type ctxKey string
+
+// MockContextCommand is a ContextualCommand implementation for testing
+type MockContextCommand struct {
+	MockCommand
+	execContextFunc func(ctx context.Context, writer io.Writer) error
+}
+
+func (m *MockContextCommand) ExecContext(ctx context.Context, writer io.Writer) error {
+	return m.execContextFunc(ctx, writer)
+}
+
 func TestItCanParseCmdInput(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -152,6 +165,72 @@ func TestItCanRegisterCommandsWithoutDuplicates(t *testing.T) {
 	}
 }
 
+func TestItRejectsNilCommandsAtRegistration(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     Command
+		wantErr string
+	}{
+		{name: "nil command", cmd: nil, wantErr: "cannot register a nil command"},
+		{name: "typed nil command", cmd: (*MockCommand)(nil), wantErr: "cannot register a nil command (*cli.MockCommand)"},
+		{name: "empty id", cmd: &MockCommand{}, wantErr: "the id must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+
+				err := registry.Register(tt.cmd)
+
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Register() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if len(registry.Commands()) != 0 {
+					t.Errorf("Register() registered %d commands, want 0", len(registry.Commands()))
+				}
+			},
+		)
+	}
+}
+
+func TestItValidatesCommandIdsAtRegistration(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{name: "lowercase with dashes", id: "say-hello"},
+		{name: "namespaced", id: "db:backup"},
+		{name: "underscores and dots", id: "cache_clear.v2"},
+		{name: "uppercase", id: "Say-Hello"},
+		{name: "empty", id: "", wantErr: true},
+		{name: "space", id: "say hello", wantErr: true},
+		{name: "surrounding spaces", id: " say-hello ", wantErr: true},
+		{name: "slash", id: "db/backup", wantErr: true},
+		{name: "leading dash", id: "-say-hello", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				err := registry.Register(&MockCommand{id: tt.id})
+
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Register(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+				}
+				if err != nil && !strings.Contains(err.Error(), "invalid command id") {
+					t.Errorf("Register(%q) error = %v, want a descriptive error", tt.id, err)
+				}
+				if _, registered := registry.Command(tt.id); registered == tt.wantErr {
+					t.Errorf("Command(%q) registered = %v, want %v", tt.id, registered, !tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
 func TestItCanRegisterMultipleCommandsAndExposeACopyOfThem(t *testing.T) {
 	registry := CommandsRegistry{commands: make(map[string]Command)}
 	cmd1 := &MockCommand{id: "cmd1", description: "Command 1"}
@@ -243,7 +322,7 @@ func TestItCanRunCommand(t *testing.T) {
 		t.Run(
 			tt.name, func(t *testing.T) {
 				var buf bytes.Buffer
-				err := runCommand(tt.cmd, tt.args, &buf)
+				err := runCommand(context.Background(), tt.cmd, tt.args, &buf, &buf)
 
 				if (err != nil) != tt.wantErr {
 					t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
@@ -263,6 +342,48 @@ func TestItCanRunCommand(t *testing.T) {
 }
 
 // TestBootstrap tests the Bootstrap function
+func TestItCanExecuteACommand(t *testing.T) {
+	execErr := errors.New("command failed")
+	cmd := &MockCommandWithFlags{
+		id: "flag-cmd",
+		execFunc: func(writer io.Writer) error {
+			_, _ = writer.Write([]byte("executed"))
+			return execErr
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Execute(cmd, []string{"--test-flag", "value"}, &buf)
+
+	if !errors.Is(err, execErr) {
+		t.Errorf("Execute() error = %v, want %v", err, execErr)
+	}
+	if buf.String() != "executed" {
+		t.Errorf("Execute() output = %q, want %q", buf.String(), "executed")
+	}
+	if value := cmd.flagSet.Lookup("test-flag").Value.String(); value != "value" {
+		t.Errorf("Execute() test-flag = %q, want %q", value, "value")
+	}
+}
+
+func TestItWritesExecuteFailuresToTheErrorWriter(t *testing.T) {
+	cmd := &MockCommandWithFlags{id: "flag-cmd"}
+
+	var out, errOut bytes.Buffer
+	err := Execute(cmd, []string{"--unknown-flag"}, &out, WithErrorWriter(&errOut))
+
+	var parseErr *FlagParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("Execute() error = %v, want a FlagParseError", err)
+	}
+	if !strings.Contains(errOut.String(), "Usage of flag-cmd") {
+		t.Errorf("Execute() error output = %q, want the usage", errOut.String())
+	}
+	if out.Len() != 0 {
+		t.Errorf("Execute() output = %q, want none", out.String())
+	}
+}
+
 func TestItCanBootstrapCliApp(t *testing.T) {
 	registry := CommandsRegistry{commands: make(map[string]Command)}
 
@@ -293,18 +414,939 @@ func TestItCanBootstrapCliApp(t *testing.T) {
 
 	// Test command not found
 	buf.Reset()
+	var errBuf bytes.Buffer
 	exitCode = -1
 	Bootstrap(
 		[]string{"non-existent-cmd"},
 		&registry,
 		&buf,
 		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
 	)
 
 	if exitCode != StatusErr {
 		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusErr)
 	}
-	if !strings.Contains(buf.String(), "does not exist") {
-		t.Errorf("Bootstrap() output should contain 'does not exist', got %v", buf.String())
+	if !strings.Contains(errBuf.String(), "does not exist") {
+		t.Errorf("Bootstrap() error output should contain 'does not exist', got %v", errBuf.String())
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Bootstrap() output should be empty on failure, got %v", buf.String())
+	}
+}
+
+func TestItCanBootstrapWithOptions(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "test-cmd",
+			execFunc: func(writer io.Writer) error {
+				_, _ = fmt.Fprint(writer, "Test command executed")
+				return nil
+			},
+		},
+	)
+
+	tests := []struct {
+		name     string
+		args     []string
+		opts     func(output, errOutput io.Writer, exit func(int)) []BootstrapOption
+		wantCode int
+		wantOut  string
+		wantErr  string
+	}{
+		{
+			name: "functional options",
+			args: []string{"test-cmd"},
+			opts: func(output, errOutput io.Writer, exit func(int)) []BootstrapOption {
+				return []BootstrapOption{WithOutputWriter(output), WithErrorWriter(errOutput), WithProcessExit(exit)}
+			},
+			wantCode: StatusOk,
+			wantOut:  "Test command executed",
+		},
+		{
+			name: "options struct",
+			args: []string{"non-existent-cmd"},
+			opts: func(output, errOutput io.Writer, exit func(int)) []BootstrapOption {
+				return []BootstrapOption{
+					WithOptions(BootstrapOptions{OutputWriter: output, ErrorWriter: errOutput, ProcessExit: exit}),
+				}
+			},
+			wantCode: StatusErr,
+			wantErr:  "The command non-existent-cmd does not exist",
+		},
+		{
+			name: "options struct with a default command",
+			args: []string{},
+			opts: func(output, errOutput io.Writer, exit func(int)) []BootstrapOption {
+				return []BootstrapOption{
+					WithOptions(
+						BootstrapOptions{
+							OutputWriter:   output,
+							ErrorWriter:    errOutput,
+							ProcessExit:    exit,
+							DefaultCommand: "test-cmd",
+						},
+					),
+				}
+			},
+			wantCode: StatusOk,
+			wantOut:  "Test command executed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var output, errOutput bytes.Buffer
+				exitCode := -1
+
+				BootstrapWith(tt.args, registry, tt.opts(&output, &errOutput, func(code int) { exitCode = code })...)
+
+				if exitCode != tt.wantCode {
+					t.Errorf("BootstrapWith() exitCode = %v, want %v", exitCode, tt.wantCode)
+				}
+				if output.String() != tt.wantOut {
+					t.Errorf("BootstrapWith() output = %q, want %q", output.String(), tt.wantOut)
+				}
+				if !strings.Contains(errOutput.String(), tt.wantErr) {
+					t.Errorf("BootstrapWith() errors = %q, want %q", errOutput.String(), tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestItWritesFlagErrorsToTheErrorWriter(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommandWithFlags{id: "flag-cmd"})
+
+	var outBuf, errBuf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"flag-cmd", "--unknown-flag"},
+		registry,
+		&outBuf,
+		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
+	)
+
+	if exitCode != StatusUsage {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusUsage)
+	}
+	if outBuf.Len() != 0 {
+		t.Errorf("Bootstrap() output should be empty, got %v", outBuf.String())
+	}
+	if strings.Count(errBuf.String(), "flag provided but not defined: -unknown-flag") != 1 {
+		t.Errorf("Bootstrap() error output should contain the parse error once, got %v", errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "Usage of flag-cmd") {
+		t.Errorf("Bootstrap() error output should contain the usage, got %v", errBuf.String())
+	}
+	if strings.Contains(errBuf.String(), "Failed to execute command") {
+		t.Errorf("Bootstrap() should not report parse errors twice, got %v", errBuf.String())
+	}
+}
+
+func TestItPropagatesContextToContextualCommands(t *testing.T) {
+	registry := NewCommandsRegistry()
+	var received any
+	cmd := &MockContextCommand{
+		MockCommand: MockCommand{
+			id: "ctx-cmd",
+			execFunc: func(writer io.Writer) error {
+				return errors.New("Exec should not be called for a contextual command")
+			},
+		},
+		execContextFunc: func(ctx context.Context, writer io.Writer) error {
+			received = ctx.Value(ctxKey("key"))
+			return nil
+		},
+	}
+	_ = registry.Register(cmd)
+
+	var buf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"ctx-cmd"},
+		registry,
+		&buf,
+		func(code int) { exitCode = code },
+		WithContext(context.WithValue(context.Background(), ctxKey("key"), "value")),
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v, output %s", exitCode, StatusOk, buf.String())
+	}
+	if received != "value" {
+		t.Errorf("ExecContext() received context value = %v, want value", received)
+	}
+}
+
+func TestRunCommandRecoversFromPanics(t *testing.T) {
+	sentinel := errors.New("panicked with error")
+
+	tests := []struct {
+		name       string
+		panicValue any
+		wantErr    string
+		wantIs     error
+	}{
+		{name: "string", panicValue: "boom", wantErr: "command panicked: boom"},
+		{name: "int", panicValue: 42, wantErr: "command panicked: 42"},
+		{name: "error", panicValue: sentinel, wantErr: "panicked with error", wantIs: sentinel},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockCommand{
+					id: "panic-cmd",
+					execFunc: func(writer io.Writer) error {
+						panic(tt.panicValue)
+					},
+				}
+
+				var buf bytes.Buffer
+				err := runCommand(context.Background(), cmd, nil, &buf, &buf)
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("runCommand() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Errorf("runCommand() error should wrap the panicked error")
+				}
+			},
+		)
+	}
+}
+
+func TestRunCommandReportsDefineFlagsPanicsAsSetupFailures(t *testing.T) {
+	executed := false
+	cmd := &MockPanickingFlagsCommand{
+		MockCommand{
+			id: "broken-cmd",
+			execFunc: func(writer io.Writer) error {
+				executed = true
+				return nil
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := runCommand(context.Background(), cmd, nil, &buf, &buf)
+
+	wantErr := "command broken-cmd failed to define flags: command panicked: database unreachable"
+	if err == nil || err.Error() != wantErr {
+		t.Fatalf("runCommand() error = %v, want %q", err, wantErr)
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || len(panicErr.Stack) == 0 {
+		t.Errorf("runCommand() error should wrap a PanicError with the stack")
+	}
+	if executed {
+		t.Errorf("runCommand() should not execute a command which failed to define its flags")
+	}
+}
+
+// MockRedefinedFlagCommand is a command defining the same flag twice for testing
+type MockRedefinedFlagCommand struct {
+	MockCommand
+	flagName string
+}
+
+func (m *MockRedefinedFlagCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.String(m.flagName, "", "The first definition")
+	flagSet.String(m.flagName, "", "The second definition")
+}
+
+func TestRunCommandReportsRedefinedFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		flagName string
+		wantErr  string
+	}{
+		{
+			name:     "long flag",
+			flagName: "name",
+			wantErr:  "command greet failed to define flags: the flag --name is defined more than once",
+		},
+		{
+			name:     "short flag",
+			flagName: "n",
+			wantErr:  "command greet failed to define flags: the flag -n is defined more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockRedefinedFlagCommand{MockCommand{id: "greet"}, tt.flagName}
+
+				var buf bytes.Buffer
+				err := runCommand(context.Background(), cmd, nil, &buf, &buf)
+
+				var redefinedErr *FlagRedefinedError
+				if !errors.As(err, &redefinedErr) {
+					t.Fatalf("runCommand() error = %v, want a FlagRedefinedError", err)
+				}
+				if err.Error() != tt.wantErr {
+					t.Errorf("runCommand() error = %q, want %q", err, tt.wantErr)
+				}
+				if redefinedErr.CommandId != "greet" {
+					t.Errorf("FlagRedefinedError.CommandId = %q, want greet", redefinedErr.CommandId)
+				}
+				var panicErr *PanicError
+				if !errors.As(err, &panicErr) || len(panicErr.Stack) == 0 {
+					t.Errorf("runCommand() error should wrap a PanicError with the stack")
+				}
+				if buf.Len() != 0 {
+					t.Errorf("runCommand() output = %q, want no output", buf.String())
+				}
+			},
+		)
+	}
+}
+
+// MockArgsCommand is an ArgsReceiver implementation for testing
+type MockArgsCommand struct {
+	MockCommandWithFlags
+	args []string
+}
+
+func (m *MockArgsCommand) SetArgs(args []string) {
+	m.args = args
+}
+
+func TestItPassesPositionalArgsToCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantArgs []string
+	}{
+		{
+			name:     "no args",
+			args:     []string{},
+			wantArgs: []string{},
+		},
+		{
+			name:     "args after flags",
+			args:     []string{"--test-flag", "v", "a.txt", "b.txt"},
+			wantArgs: []string{"a.txt", "b.txt"},
+		},
+		{
+			name:     "flag-like args after first positional",
+			args:     []string{"a.txt", "--test-flag", "v"},
+			wantArgs: []string{"a.txt", "--test-flag", "v"},
+		},
+		{
+			name:     "args after terminator",
+			args:     []string{"--test-flag", "v", "--", "-n", "b.txt"},
+			wantArgs: []string{"-n", "b.txt"},
+		},
+		{
+			name:     "only args after terminator",
+			args:     []string{"--", "--test-flag", "v"},
+			wantArgs: []string{"--test-flag", "v"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockArgsCommand{MockCommandWithFlags: MockCommandWithFlags{id: "cat"}}
+				lockableCmd := NewLockableCommand(cmd, t.TempDir())
+
+				var buf bytes.Buffer
+				if err := runCommand(context.Background(), lockableCmd, tt.args, &buf, &buf); err != nil {
+					t.Fatalf("runCommand() error = %v", err)
+				}
+				if strings.Join(cmd.args, " ") != strings.Join(tt.wantArgs, " ") {
+					t.Errorf("SetArgs() received %v, want %v", cmd.args, tt.wantArgs)
+				}
+			},
+		)
+	}
+}
+
+func TestItStopsParsingCommandFlagsAtTheTerminator(t *testing.T) {
+	cmd := &MockArgsCommand{MockCommandWithFlags: MockCommandWithFlags{id: "exec"}}
+	registry := NewCommandsRegistry()
+	_ = registry.Register(cmd)
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"--dry-run", "--", "exec", "--test-flag", "v", "--", "git", "--test-flag", "x"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+	if got := cmd.flagSet.Lookup("test-flag").Value.String(); got != "v" {
+		t.Errorf("test-flag = %v, want v", got)
+	}
+	if strings.Join(cmd.args, " ") != "git --test-flag x" {
+		t.Errorf("SetArgs() received %v, want [git --test-flag x]", cmd.args)
+	}
+}
+
+func TestItCanUnregisterCommands(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "cmd1"})
+	_ = registry.Register(&MockCommand{id: "cmd2"})
+
+	if err := registry.Unregister("cmd1"); err != nil {
+		t.Fatalf("Unregister() error = %v, want nil", err)
+	}
+	if _, exists := registry.Command("cmd1"); exists {
+		t.Error("Command() should not find an unregistered command")
+	}
+	if _, exists := registry.Commands()["cmd1"]; exists {
+		t.Error("Commands() should not contain an unregistered command")
+	}
+	if _, exists := registry.Command("cmd2"); !exists {
+		t.Error("Unregister() should not remove other commands")
+	}
+
+	if err := registry.Unregister("cmd1"); err == nil {
+		t.Error("Unregister() error = nil, want error for a command which is not registered")
+	}
+
+	// The id can be registered again
+	if err := registry.Register(&MockCommand{id: "cmd1"}); err != nil {
+		t.Errorf("Register() error = %v, want nil after unregistering", err)
+	}
+}
+
+func TestItCanRegisterCommandsInBulk(t *testing.T) {
+	registry, err := NewCommandsRegistryFrom(&MockCommand{id: "cmd1"}, &MockCommand{id: "cmd2"})
+	if err != nil {
+		t.Fatalf("NewCommandsRegistryFrom() error = %v, want nil", err)
+	}
+	if len(registry.Commands()) != 2 {
+		t.Errorf("NewCommandsRegistryFrom() registered %d commands, want 2", len(registry.Commands()))
+	}
+
+	err = registry.RegisterAll(
+		&MockCommand{id: "cmd1"},
+		&MockCommand{id: "cmd3"},
+		&MockCommand{id: "cmd2"},
+	)
+	if err == nil || !strings.Contains(err.Error(), "cmd1") || !strings.Contains(err.Error(), "cmd2") {
+		t.Errorf("RegisterAll() error = %v, want an error naming cmd1 and cmd2", err)
+	}
+	if _, exists := registry.Command("cmd3"); !exists {
+		t.Error("RegisterAll() should register the valid commands past a failure")
+	}
+
+	if _, err = NewCommandsRegistryFrom(&MockCommand{id: "dup"}, &MockCommand{id: "dup"}); err == nil {
+		t.Error("NewCommandsRegistryFrom() error = nil, want error for duplicates")
+	}
+}
+
+func TestRegistryCanLookUpCommandsCaseInsensitively(t *testing.T) {
+	caseSensitive := NewCommandsRegistry()
+	_ = caseSensitive.Register(&MockCommand{id: "say-hello"})
+	if _, exists := caseSensitive.Command("Say-Hello"); exists {
+		t.Error("Command() should be case-sensitive by default")
+	}
+	if err := caseSensitive.Register(&MockCommand{id: "Say-Hello"}); err != nil {
+		t.Errorf("Register() error = %v, want nil for ids differing by case by default", err)
+	}
+
+	registry := NewCommandsRegistry(WithCaseInsensitiveLookup())
+	_ = registry.Register(&MockCommand{id: "say-hello"})
+
+	cmd, exists := registry.Command("Say-Hello")
+	if !exists || cmd.Id() != "say-hello" {
+		t.Errorf("Command() should find say-hello regardless of case, got %v, %v", cmd, exists)
+	}
+	if _, exists = registry.Commands()["say-hello"]; !exists {
+		t.Error("Commands() should be keyed by the command ids")
+	}
+
+	err := registry.Register(&MockCommand{id: "SAY-HELLO"})
+	if err == nil || !strings.Contains(err.Error(), "collides") {
+		t.Errorf("Register() error = %v, want a collision error", err)
+	}
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"SAY-hello"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithErrorWriter(&bytes.Buffer{}),
+	)
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+
+	if err = registry.Unregister("Say-Hello"); err != nil {
+		t.Errorf("Unregister() error = %v, want nil", err)
+	}
+}
+
+// MockDeprecatedCommand is a DeprecatableCommand implementation for testing
+type MockDeprecatedCommand struct {
+	MockCommand
+	deprecation string
+}
+
+func (m *MockDeprecatedCommand) Deprecated() string {
+	return m.deprecation
+}
+
+func TestItWarnsAboutDeprecatedCommands(t *testing.T) {
+	executed := false
+	cmd := &MockDeprecatedCommand{
+		MockCommand: MockCommand{
+			id:          "old-cmd",
+			description: "The old command",
+			execFunc: func(writer io.Writer) error {
+				executed = true
+				return nil
+			},
+		},
+		deprecation: "use 'new-cmd' instead",
+	}
+	registry := NewCommandsRegistry()
+	_ = registry.Register(NewLockableCommand(cmd, t.TempDir()))
+
+	var outBuf, errBuf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"old-cmd"},
+		registry,
+		&outBuf,
+		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
+	)
+
+	if exitCode != StatusOk || !executed {
+		t.Errorf("Deprecated command should run normally, exitCode = %v, executed = %v", exitCode, executed)
+	}
+	warning := "Warning: command old-cmd is deprecated, use 'new-cmd' instead"
+	if strings.Count(errBuf.String(), warning) != 1 {
+		t.Errorf("Error output should contain the warning exactly once, got %q", errBuf.String())
+	}
+
+	var helpBuf bytes.Buffer
+	_ = NewHelpCommand([]Command{cmd}).Exec(&helpBuf)
+	if !strings.Contains(helpBuf.String(), "old-cmd [deprecated]") {
+		t.Errorf("Help output should mark the command as deprecated, got %s", helpBuf.String())
+	}
+}
+
+func TestItWritesTheUsageOnFlagValidationErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		cmd          *MockCommandWithFlags
+		want         []string
+		wantExitCode int
+	}{
+		{
+			name:         "parse error",
+			args:         []string{"flag-cmd", "--unknown-flag"},
+			cmd:          &MockCommandWithFlags{id: "flag-cmd"},
+			want:         []string{"flag provided but not defined: -unknown-flag\nUsage of flag-cmd:\n"},
+			wantExitCode: StatusUsage,
+		},
+		{
+			name: "validation error",
+			args: []string{"flag-cmd", "--test-flag", "x"},
+			cmd:  &MockCommandWithFlags{id: "flag-cmd", validateErr: errors.New("test-flag is too short")},
+			want: []string{
+				"test-flag is too short\nUsage of flag-cmd:\n",
+				"-test-flag string",
+				"Failed to execute command flag-cmd with error: test-flag is too short",
+			},
+			wantExitCode: StatusErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(tt.cmd)
+
+				var errBuf bytes.Buffer
+				exitCode := -1
+				Bootstrap(
+					tt.args,
+					registry,
+					&bytes.Buffer{},
+					func(code int) { exitCode = code },
+					WithErrorWriter(&errBuf),
+				)
+
+				if exitCode != tt.wantExitCode {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, tt.wantExitCode)
+				}
+				for _, want := range tt.want {
+					if strings.Count(errBuf.String(), want) != 1 {
+						t.Errorf("Bootstrap() error output should contain %q once, got %v", want, errBuf.String())
+					}
+				}
+			},
+		)
+	}
+}
+
+// MockInitializableCommand is an InitializableCommand implementation recording the
+// lifecycle calls for testing
+type MockInitializableCommand struct {
+	MockCommand
+	calls    *[]string
+	initErr  error
+	closeErr error
+}
+
+func (m *MockInitializableCommand) Init() error {
+	*m.calls = append(*m.calls, "init")
+	return m.initErr
+}
+
+func (m *MockInitializableCommand) DefineFlags(flagSet *flag.FlagSet) {
+	*m.calls = append(*m.calls, "define")
+	flagSet.String("name", "", "The name")
+}
+
+func (m *MockInitializableCommand) ValidateFlags() error {
+	*m.calls = append(*m.calls, "validate")
+	return nil
+}
+
+func (m *MockInitializableCommand) Close() error {
+	*m.calls = append(*m.calls, "close")
+	return m.closeErr
+}
+
+func TestItRunsTheCommandLifecycleInOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		initErr   error
+		closeErr  error
+		execFunc  func(writer io.Writer) error
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name:      "successful run",
+			args:      []string{"--name", "x"},
+			wantCalls: []string{"init", "define", "validate", "exec", "close"},
+		},
+		{
+			name:      "init error aborts before flag parsing",
+			args:      []string{"--unknown"},
+			initErr:   errors.New("init failed"),
+			wantCalls: []string{"init"},
+			wantErr:   true,
+		},
+		{
+			name:      "parse error still closes",
+			args:      []string{"--unknown"},
+			wantCalls: []string{"init", "define", "close"},
+			wantErr:   true,
+		},
+		{
+			name: "exec panic still closes",
+			execFunc: func(writer io.Writer) error {
+				panic("boom")
+			},
+			wantCalls: []string{"init", "define", "validate", "exec", "close"},
+			wantErr:   true,
+		},
+		{
+			name:      "close error",
+			closeErr:  errors.New("close failed"),
+			wantCalls: []string{"init", "define", "validate", "exec", "close"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var calls []string
+				cmd := &MockInitializableCommand{
+					MockCommand: MockCommand{
+						id: "init-cmd",
+						execFunc: func(writer io.Writer) error {
+							calls = append(calls, "exec")
+							if tt.execFunc != nil {
+								return tt.execFunc(writer)
+							}
+							return nil
+						},
+					},
+					calls:    &calls,
+					initErr:  tt.initErr,
+					closeErr: tt.closeErr,
+				}
+
+				err := runCommand(context.Background(), cmd, tt.args, io.Discard, io.Discard)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if strings.Join(calls, " ") != strings.Join(tt.wantCalls, " ") {
+					t.Errorf("Lifecycle calls = %v, want %v", calls, tt.wantCalls)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanRunADefaultCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		opts       []BootstrapOption
+		wantOutput string
+		wantErr    string
+	}{
+		{
+			name:       "no default command",
+			args:       []string{},
+			wantOutput: "Lists all available commands",
+		},
+		{
+			name:       "default command without args",
+			args:       []string{},
+			opts:       []BootstrapOption{WithDefaultCommand("flag-cmd")},
+			wantOutput: "flag-cmd ran with test-flag ''",
+		},
+		{
+			name:       "default command with flags",
+			args:       []string{"--", "--test-flag", "v"},
+			opts:       []BootstrapOption{WithDefaultCommand("flag-cmd")},
+			wantOutput: "flag-cmd ran with test-flag 'v'",
+		},
+		{
+			name:       "default command with flags without a terminator",
+			args:       []string{"--test-flag", "v", "file.txt"},
+			opts:       []BootstrapOption{WithDefaultCommand("flag-cmd")},
+			wantOutput: "flag-cmd ran with test-flag 'v' and args [file.txt]",
+		},
+		{
+			name:       "default command with global and command flags",
+			args:       []string{"--quiet", "--test-flag=v", "file.txt"},
+			opts:       []BootstrapOption{WithDefaultCommand("flag-cmd")},
+			wantOutput: "flag-cmd ran with test-flag 'v' and args [file.txt]",
+		},
+		{
+			name:       "explicit command id",
+			args:       []string{"other-cmd"},
+			opts:       []BootstrapOption{WithDefaultCommand("flag-cmd")},
+			wantOutput: "other-cmd ran",
+		},
+		{
+			name:       "unknown default command",
+			args:       []string{},
+			opts:       []BootstrapOption{WithDefaultCommand("missing-cmd")},
+			wantOutput: "Lists all available commands",
+			wantErr:    "The default command missing-cmd does not exist, showing help instead",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				flagCmd := &MockCommandWithFlags{id: "flag-cmd"}
+				flagCmd.execFunc = func(writer io.Writer) error {
+					_, _ = fmt.Fprintf(
+						writer,
+						"flag-cmd ran with test-flag '%s' and args %v",
+						flagCmd.flagSet.Lookup("test-flag").Value.String(),
+						flagCmd.flagSet.Args(),
+					)
+					return nil
+				}
+				registry := NewCommandsRegistry()
+				_ = registry.Register(flagCmd)
+				_ = registry.Register(
+					&MockCommand{
+						id: "other-cmd",
+						execFunc: func(writer io.Writer) error {
+							_, _ = fmt.Fprint(writer, "other-cmd ran")
+							return nil
+						},
+					},
+				)
+
+				var buf, errBuf bytes.Buffer
+				exitCode := -1
+				opts := append([]BootstrapOption{WithErrorWriter(&errBuf)}, tt.opts...)
+				Bootstrap(tt.args, registry, &buf, func(code int) { exitCode = code }, opts...)
+
+				if exitCode != StatusOk {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+				}
+				if !strings.Contains(buf.String(), tt.wantOutput) {
+					t.Errorf("Bootstrap() output should contain %q, got %v", tt.wantOutput, buf.String())
+				}
+				if !strings.Contains(errBuf.String(), tt.wantErr) {
+					t.Errorf("Bootstrap() error output should contain %q, got %v", tt.wantErr, errBuf.String())
+				}
+			},
+		)
+	}
+}
+
+func TestItReportsUnknownCommandsWithADefaultCommand(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommandWithFlags{id: "flag-cmd"})
+
+	hookCalled := false
+	_, err := Run(
+		[]string{"typo"},
+		registry,
+		io.Discard,
+		WithErrorWriter(io.Discard),
+		WithoutSignalHandling(),
+		WithDefaultCommand("flag-cmd"),
+		WithCommandNotFoundHook(
+			func(id string, args []string) (bool, error) {
+				hookCalled = id == "typo"
+				return false, nil
+			},
+		),
+	)
+
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("Run() error = %v, want %v", err, ErrCommandNotFound)
+	}
+	if !hookCalled {
+		t.Error("Run() should call the command not found hook for unknown ids")
+	}
+}
+
+func TestItHandlesFlagHelpRequests(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantExecuted bool
+	}{
+		{
+			name:         "help flag",
+			args:         []string{"flag-cmd", "--help"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "short help flag after other flags",
+			args:         []string{"flag-cmd", "--test-flag", "v", "-h"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "help word",
+			args:         []string{"flag-cmd", "help"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "help word after positional args",
+			args:         []string{"flag-cmd", "--test-flag", "v", "arg", "help"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "help flag after positional args",
+			args:         []string{"flag-cmd", "arg", "--help", "other"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "help word after the terminator",
+			args:         []string{"flag-cmd", "--", "help"},
+			wantExitCode: StatusOk,
+			wantExecuted: true,
+		},
+		{
+			name:         "help flag after the terminator",
+			args:         []string{"flag-cmd", "arg", "--", "-h"},
+			wantExitCode: StatusOk,
+			wantExecuted: true,
+		},
+		{
+			name:         "help word before other args",
+			args:         []string{"flag-cmd", "help", "arg"},
+			wantExitCode: StatusOk,
+			wantExecuted: true,
+		},
+		{
+			name:         "invalid flag",
+			args:         []string{"flag-cmd", "--invalid"},
+			wantExitCode: StatusUsage,
+		},
+		{
+			name:         "valid flag",
+			args:         []string{"flag-cmd", "--test-flag", "v"},
+			wantExitCode: StatusOk,
+			wantExecuted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				executed := false
+				registry := NewCommandsRegistry()
+				_ = registry.Register(
+					&MockCommandWithFlags{
+						id:          "flag-cmd",
+						description: "A command with flags",
+						execFunc: func(writer io.Writer) error {
+							executed = true
+							return nil
+						},
+					},
+				)
+
+				var errBuf bytes.Buffer
+				exitCode := -1
+				Bootstrap(
+					tt.args,
+					registry,
+					io.Discard,
+					func(code int) { exitCode = code },
+					WithErrorWriter(&errBuf),
+				)
+
+				if exitCode != tt.wantExitCode {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, tt.wantExitCode)
+				}
+				if executed != tt.wantExecuted {
+					t.Errorf("Command executed = %v, want %v", executed, tt.wantExecuted)
+				}
+				wantUsage := "Usage of flag-cmd:\n  A command with flags\n  -test-flag string"
+				if !tt.wantExecuted && !strings.Contains(errBuf.String(), wantUsage) {
+					t.Errorf("Bootstrap() error output should contain the usage, got %v", errBuf.String())
+				}
+				if strings.Contains(errBuf.String(), "Failed to execute command") {
+					t.Errorf("Bootstrap() should not report a command failure, got %v", errBuf.String())
+				}
+			},
+		)
+	}
+}
+
+func TestItPrintsTheSameUsageForTheHelpWordAndFlag(t *testing.T) {
+	var flagErrBuf bytes.Buffer
+	cmd := &MockCommandWithFlags{id: "flag-cmd", description: "A command with flags"}
+	if err := runCommand(context.Background(), cmd, []string{"--help"}, io.Discard, &flagErrBuf); err != nil {
+		t.Fatalf("runCommand() with --help error = %v, want nil", err)
+	}
+
+	var wordErrBuf bytes.Buffer
+	cmd = &MockCommandWithFlags{id: "flag-cmd", description: "A command with flags"}
+	if err := runCommand(context.Background(), cmd, []string{"help"}, io.Discard, &wordErrBuf); err != nil {
+		t.Fatalf("runCommand() with help error = %v, want nil", err)
+	}
+
+	if flagErrBuf.Len() == 0 || wordErrBuf.String() != flagErrBuf.String() {
+		t.Errorf("help word usage = %q, want the help flag usage %q", wordErrBuf.String(), flagErrBuf.String())
 	}
 }
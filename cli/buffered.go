@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// BufferedCommand is a wrapper buffering the output of the wrapped command, writing it to
+// the output writer only once the command succeeded, so that a command failing partway
+// does not print half-written results.
+type BufferedCommand struct {
+	// The command whose output is buffered
+	Command Command
+}
+
+// NewBufferedCommand wraps the given command so that its output is held in memory while it
+// runs, then copied to the output writer if it succeeds, or discarded if it fails or
+// panics. Since nothing is written until the command returns, do not wrap commands
+// streaming live output, like progress reports or long-running watchers, nor commands
+// producing more output than fits in memory. Leaving such commands unwrapped is how they
+// opt out.
+func NewBufferedCommand(cmd Command) Command {
+	return &BufferedCommand{Command: cmd}
+}
+
+// Id returns the ID of the wrapped command.
+func (b *BufferedCommand) Id() string {
+	return b.Command.Id()
+}
+
+// Description returns the description of the wrapped command.
+func (b *BufferedCommand) Description() string {
+	return b.Command.Description()
+}
+
+// Unwrap returns the wrapped command.
+func (b *BufferedCommand) Unwrap() Command {
+	return b.Command
+}
+
+// DefineFlags delegates to the wrapped command.
+func (b *BufferedCommand) DefineFlags(flagSet *flag.FlagSet) {
+	b.Command.DefineFlags(flagSet)
+}
+
+// ValidateFlags delegates to the wrapped command.
+func (b *BufferedCommand) ValidateFlags() error {
+	return b.Command.ValidateFlags()
+}
+
+// Exec executes the wrapped command, buffering its output.
+func (b *BufferedCommand) Exec(stdWriter io.Writer) error {
+	return b.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext executes the wrapped command with the given context, writing its output to
+// stdWriter only if it returns no error. It fails if the buffered output cannot be
+// written.
+func (b *BufferedCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	var buf bytes.Buffer
+	if err := execCommand(ctx, b.Command, &buf); err != nil {
+		return err
+	}
+
+	if _, err := buf.WriteTo(stdWriter); err != nil {
+		return fmt.Errorf("failed to write the buffered output: %w", err)
+	}
+	return nil
+}
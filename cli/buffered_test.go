@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestItCanBufferCommandOutputUntilSuccess(t *testing.T) {
+	errPartial := errors.New("failed halfway")
+
+	tests := []struct {
+		name       string
+		execErr    error
+		writer     io.Writer
+		wantErr    string
+		wantOutput string
+	}{
+		{
+			name:       "flushes the output on success",
+			wantOutput: "row 1\nrow 2\n",
+		},
+		{
+			name:    "discards the output on failure",
+			execErr: errPartial,
+			wantErr: errPartial.Error(),
+		},
+		{
+			name:    "fails when the output cannot be flushed",
+			writer:  failingWriter{},
+			wantErr: "failed to write the buffered output: disk full",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				writer := tt.writer
+				if writer == nil {
+					writer = &buf
+				}
+				cmd := NewBufferedCommand(
+					&MockCommand{
+						id: "export",
+						execFunc: func(writer io.Writer) error {
+							_, _ = io.WriteString(writer, "row 1\n")
+							if buf.Len() != 0 {
+								t.Errorf("output written before the command returned")
+							}
+							_, _ = io.WriteString(writer, "row 2\n")
+							return tt.execErr
+						},
+					},
+				)
+
+				err := cmd.Exec(writer)
+
+				if tt.wantErr == "" && err != nil {
+					t.Errorf("Exec() error = %v, want nil", err)
+				}
+				if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+					t.Errorf("Exec() error = %v, want %q", err, tt.wantErr)
+				}
+				if buf.String() != tt.wantOutput {
+					t.Errorf("Exec() output = %q, want %q", buf.String(), tt.wantOutput)
+				}
+			},
+		)
+	}
+}
+
+func TestItDiscardsBufferedOutputWhenRunningAFailingCommand(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		NewBufferedCommand(
+			&MockCommand{
+				id: "export",
+				execFunc: func(writer io.Writer) error {
+					_, _ = io.WriteString(writer, "partial result\n")
+					return errors.New("export failed")
+				},
+			},
+		),
+	)
+
+	var output, errOutput bytes.Buffer
+	exitCode, err := Run([]string{"export"}, registry, &output, WithErrorWriter(&errOutput))
+
+	if exitCode != StatusErr || err == nil {
+		t.Errorf("Run() = (%d, %v), want (%d, an error)", exitCode, err, StatusErr)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Run() output = %q, want no output", output.String())
+	}
+}
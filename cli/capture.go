@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// teeWriter writes to a live writer while mirroring the written bytes into capture writers
+type teeWriter struct {
+	mu       sync.Mutex
+	live     io.Writer
+	captures []io.Writer
+}
+
+// NewTeeWriter returns a writer writing to live and mirroring everything written into the
+// capture writers, for example to stream command output to os.Stdout while keeping a copy
+// in a buffer for auditing. Unlike io.MultiWriter, failing to write to a capture writer
+// does not fail the write, only errors of the live writer are returned. Nil capture
+// writers are ignored. Writes are serialized, so it is safe for concurrent use.
+func NewTeeWriter(live io.Writer, captures ...io.Writer) io.Writer {
+	tee := &teeWriter{live: live}
+	for _, capture := range captures {
+		if capture != nil {
+			tee.captures = append(tee.captures, capture)
+		}
+	}
+	return tee
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n, err := t.live.Write(p)
+	for _, capture := range t.captures {
+		_, _ = capture.Write(p[:n])
+	}
+	return n, err
+}
+
+// writerFile returns the file the writer writes to, looking through tee writers at their
+// live writer, so that terminal detection is not affected by output capturing
+func writerFile(writer io.Writer) (*os.File, bool) {
+	for {
+		switch w := writer.(type) {
+		case *os.File:
+			return w, true
+		case *teeWriter:
+			writer = w.live
+		default:
+			return nil, false
+		}
+	}
+}
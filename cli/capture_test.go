@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestItCanMirrorWritesIntoCaptureWriters(t *testing.T) {
+	var live, capture bytes.Buffer
+	writer := NewTeeWriter(&live, nil, &capture, failingWriter{})
+
+	n, err := writer.Write([]byte("hello"))
+
+	if err != nil || n != 5 {
+		t.Errorf("Write() = %d, %v, want 5, nil", n, err)
+	}
+	if live.String() != "hello" || capture.String() != "hello" {
+		t.Errorf("Write() live = %q, capture = %q, want both %q", live.String(), capture.String(), "hello")
+	}
+
+	if _, err = NewTeeWriter(failingWriter{}, &capture).Write([]byte("x")); err == nil {
+		t.Errorf("Write() should return the live writer error")
+	}
+}
+
+func TestItCanCaptureTheOutputAndErrorStreams(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "greet",
+			execFunc: func(writer io.Writer) error {
+				_, _ = writer.Write([]byte("Hello"))
+				return errors.New("greeting failed")
+			},
+		},
+	)
+
+	tests := []struct {
+		name           string
+		captureOutput  bool
+		captureErrors  bool
+		wantOutput     string
+		wantErrContent string
+	}{
+		{
+			name:          "output only",
+			captureOutput: true,
+			wantOutput:    "Hello",
+		},
+		{
+			name:           "errors only",
+			captureErrors:  true,
+			wantErrContent: "greeting failed",
+		},
+		{
+			name:           "both streams",
+			captureOutput:  true,
+			captureErrors:  true,
+			wantOutput:     "Hello",
+			wantErrContent: "greeting failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var outBuf, errBuf, outCapture, errCapture bytes.Buffer
+				opts := []BootstrapOption{WithErrorWriter(&errBuf), WithoutSignalHandling()}
+				if tt.captureOutput {
+					opts = append(opts, WithOutputCapture(&outCapture))
+				}
+				if tt.captureErrors {
+					opts = append(opts, WithErrorCapture(&errCapture))
+				}
+
+				_, _ = Run([]string{"greet"}, registry, &outBuf, opts...)
+
+				if outBuf.String() != "Hello" {
+					t.Errorf("Run() output = %q, want %q", outBuf.String(), "Hello")
+				}
+				if !strings.Contains(errBuf.String(), "greeting failed") {
+					t.Errorf("Run() error output = %q, want the failure", errBuf.String())
+				}
+				if outCapture.String() != tt.wantOutput {
+					t.Errorf("captured output = %q, want %q", outCapture.String(), tt.wantOutput)
+				}
+				if tt.wantErrContent == "" && errCapture.Len() != 0 {
+					t.Errorf("captured errors = %q, want none", errCapture.String())
+				}
+				if tt.wantErrContent != "" && errCapture.String() != errBuf.String() {
+					t.Errorf("captured errors = %q, want %q", errCapture.String(), errBuf.String())
+				}
+			},
+		)
+	}
+}
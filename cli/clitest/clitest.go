@@ -0,0 +1,34 @@
+// Package clitest provides helpers for unit testing commands, without going through
+// cli.Bootstrap and its process exit.
+package clitest
+
+import (
+	"bytes"
+	"context"
+	"github.com/rsgcata/go-cli-command/cli"
+	"io"
+	"testing"
+)
+
+// RunCommand parses the args into the command flags, validates them and executes the
+// command, returning its captured output. Flag parse errors are returned as err, the
+// flag usage printed along with them is discarded.
+func RunCommand(cmd cli.Command, args ...string) (output string, err error) {
+	var outputBuf bytes.Buffer
+	err = cli.RunCommand(context.Background(), cmd, args, &outputBuf, io.Discard)
+	return outputBuf.String(), err
+}
+
+// MustOutput runs the command with the given args and fails the test if it returns an
+// error or if its output is not the expected one.
+func MustOutput(t testing.TB, cmd cli.Command, args []string, expected string) {
+	t.Helper()
+
+	output, err := RunCommand(cmd, args...)
+	if err != nil {
+		t.Fatalf("Command %s failed with error: %v\nOutput:\n%s", cmd.Id(), err, output)
+	}
+	if output != expected {
+		t.Errorf("Command %s output = %q, want %q", cmd.Id(), output, expected)
+	}
+}
@@ -0,0 +1,85 @@
+package clitest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type greetCommand struct {
+	name string
+}
+
+func (c *greetCommand) Id() string {
+	return "greet"
+}
+
+func (c *greetCommand) Description() string {
+	return "Greets someone"
+}
+
+func (c *greetCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&c.name, "name", "world", "Who to greet")
+}
+
+func (c *greetCommand) ValidateFlags() error {
+	if c.name == "" {
+		return errors.New("name must not be empty")
+	}
+	return nil
+}
+
+func (c *greetCommand) Exec(writer io.Writer) error {
+	_, err := fmt.Fprintf(writer, "Hello %s", c.name)
+	return err
+}
+
+func TestItCanRunCommands(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantOutput string
+		wantErr    bool
+	}{
+		{
+			name:       "default flags",
+			args:       nil,
+			wantOutput: "Hello world",
+		},
+		{
+			name:       "given flags",
+			args:       []string{"--name", "john"},
+			wantOutput: "Hello john",
+		},
+		{
+			name:    "invalid flags",
+			args:    []string{"--name", ""},
+			wantErr: true,
+		},
+		{
+			name:    "unknown flags",
+			args:    []string{"--unknown"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				output, err := RunCommand(&greetCommand{}, tt.args...)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("RunCommand() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if output != tt.wantOutput {
+					t.Errorf("RunCommand() output = %q, want %q", output, tt.wantOutput)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanAssertCommandOutput(t *testing.T) {
+	MustOutput(t, &greetCommand{}, []string{"--name", "jane"}, "Hello jane")
+}
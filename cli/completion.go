@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"flag"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// CompleteCommandId is the hidden command a shell completion script calls the binary with
+// to fetch suggestions, as in "app __complete db migrate --na". The args are the words of
+// the command line following the binary, the last one being the (possibly empty) word being
+// completed. Bootstrap writes the suggestions to the output writer, one per line, without
+// running any command. It is not listed by the help command.
+const CompleteCommandId = "__complete"
+
+// CompletionProvider is an optional interface for commands providing dynamic completions
+// of their positional args, like available report names. CompleteArg receives the index
+// of the positional arg being completed and the prefix typed so far, and returns the
+// suggestions, which are expected to start with the prefix.
+type CompletionProvider interface {
+	Command
+	CompleteArg(index int, prefix string) []string
+}
+
+// FlagCompletionProvider is an optional interface for commands providing dynamic
+// completions of their flag values. CompleteFlag receives the name of the flag, without
+// dashes, whose value is being completed and the prefix typed so far.
+type FlagCompletionProvider interface {
+	Command
+	CompleteFlag(name string, prefix string) []string
+}
+
+// complete returns the suggestions for the last of the given command line words: command
+// ids, subcommand ids, flag names or, through the optional completion interfaces, flag
+// values and positional args
+func complete(registry *CommandsRegistry, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	prefix := words[len(words)-1]
+	preceding := words[:len(words)-1]
+
+	if len(preceding) == 0 {
+		return matchingPrefix(slices.Collect(maps.Keys(registry.Commands())), prefix)
+	}
+
+	cmd, exists := registry.Command(preceding[0])
+	if !exists {
+		return nil
+	}
+	cmd, _, args, exists := resolveSubcommand(cmd, preceding[1:])
+	if !exists {
+		return nil
+	}
+
+	if group, isGroup := cmd.(*CommandGroup); isGroup {
+		if strings.HasPrefix(prefix, "-") {
+			return nil
+		}
+		var childIds []string
+		for _, child := range group.Commands() {
+			childIds = append(childIds, child.Id())
+		}
+		return matchingPrefix(childIds, prefix)
+	}
+
+	if takesRawArgs(cmd) {
+		return completeArg(cmd, len(args), prefix)
+	}
+
+	// A command failing to define its flags has no completions, rather than crashing the shell
+	flagSet, err := commandFlagSet(cmd)
+	if err != nil {
+		return nil
+	}
+
+	positionalCount, pendingFlag, parsingFlags := 0, "", true
+	for _, arg := range args {
+		switch {
+		case pendingFlag != "":
+			pendingFlag = ""
+		case !parsingFlags:
+			positionalCount++
+		case arg == "--":
+			parsingFlags = false
+		case len(arg) > 1 && strings.HasPrefix(arg, "-"):
+			name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if definedFlag := flagSet.Lookup(name); definedFlag != nil && !hasValue &&
+				!isBoolFlag(definedFlag) {
+				pendingFlag = name
+			}
+		default:
+			// The flag package stops parsing flags at the first positional arg
+			parsingFlags = false
+			positionalCount++
+		}
+	}
+
+	if pendingFlag != "" {
+		if provider, ok := findOptional[FlagCompletionProvider](cmd); ok {
+			return provider.CompleteFlag(pendingFlag, prefix)
+		}
+		return nil
+	}
+
+	if parsingFlags && strings.HasPrefix(prefix, "-") {
+		var flagNames []string
+		flagSet.VisitAll(
+			func(definedFlag *flag.Flag) {
+				flagNames = append(flagNames, "--"+definedFlag.Name)
+			},
+		)
+		return matchingPrefix(flagNames, "--"+strings.TrimLeft(prefix, "-"))
+	}
+
+	return completeArg(cmd, positionalCount, prefix)
+}
+
+// completeArg returns the completions of the positional arg at the given index, provided
+// by the command when it implements CompletionProvider
+func completeArg(cmd Command, index int, prefix string) []string {
+	if provider, ok := findOptional[CompletionProvider](cmd); ok {
+		return provider.CompleteArg(index, prefix)
+	}
+	return nil
+}
+
+// isBoolFlag reports whether the flag can be set without a value, like "--verbose"
+func isBoolFlag(definedFlag *flag.Flag) bool {
+	boolFlag, ok := definedFlag.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// matchingPrefix returns the sorted candidates starting with the prefix
+func matchingPrefix(candidates []string, prefix string) []string {
+	var matching []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matching = append(matching, candidate)
+		}
+	}
+	slices.Sort(matching)
+	return matching
+}
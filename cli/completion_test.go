@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// MockCompletionCommand is a CompletionProvider and FlagCompletionProvider implementation
+// for testing
+type MockCompletionCommand struct {
+	MockCommandWithRequiredFlags
+}
+
+func (m *MockCompletionCommand) CompleteArg(index int, prefix string) []string {
+	return matchingPrefix([]string{fmt.Sprintf("arg%d-daily", index), fmt.Sprintf("arg%d-weekly", index)}, prefix)
+}
+
+func (m *MockCompletionCommand) CompleteFlag(name string, prefix string) []string {
+	return matchingPrefix([]string{name + "-john", name + "-jane"}, prefix)
+}
+
+func TestItCanCompleteCommandLines(t *testing.T) {
+	executed := ""
+	registry := NewCommandsRegistry()
+	_ = registry.RegisterAll(
+		&MockCompletionCommand{
+			MockCommandWithRequiredFlags: MockCommandWithRequiredFlags{
+				MockCommand: MockCommand{id: "report"},
+			},
+		},
+		&MockCommand{id: "reindex"},
+		&MockPanickingFlagsCommand{MockCommand{id: "broken"}},
+		newTestDbGroup(&executed),
+	)
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{name: "all command ids", words: nil, want: []string{"broken", "db", "help", "reindex", "report"}},
+		{name: "command id prefix", words: []string{"re"}, want: []string{"reindex", "report"}},
+		{name: "subcommand ids", words: []string{"db", "migrate", ""}, want: []string{"down", "up"}},
+		{name: "unknown command", words: []string{"missing", ""}, want: nil},
+		{name: "flag names", words: []string{"report", "--"}, want: []string{"--count", "--name", "--verbose"}},
+		{name: "flag name prefix", words: []string{"report", "-n"}, want: []string{"--name"}},
+		{name: "flag value", words: []string{"report", "--name", "name-j"}, want: []string{"name-jane", "name-john"}},
+		{name: "first arg", words: []string{"report", "--name", "x", "--verbose", "arg0-d"}, want: []string{"arg0-daily"}},
+		{name: "second arg", words: []string{"report", "--count=2", "first", ""}, want: []string{"arg1-daily", "arg1-weekly"}},
+		{name: "arg after terminator", words: []string{"report", "--", "-"}, want: nil},
+		{name: "command without provider", words: []string{"reindex", ""}, want: nil},
+		{name: "command failing to define flags", words: []string{"broken", "--"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				exitCode, err := Run(
+					append([]string{CompleteCommandId}, tt.words...),
+					registry,
+					&buf,
+					WithoutSignalHandling(),
+				)
+
+				if exitCode != StatusOk || err != nil {
+					t.Errorf("Run() = %v, %v, want %v, nil", exitCode, err, StatusOk)
+				}
+				got := strings.Fields(buf.String())
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("Run() completions = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
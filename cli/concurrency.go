@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CommandBusy is returned by a ConcurrencyLimitedCommand when the maximum number of
+// concurrent executions of the command is reached
+var CommandBusy = errors.New("command is busy, too many concurrent executions")
+
+// ConcurrencyOption configures a ConcurrencyLimitedCommand
+type ConcurrencyOption func(*ConcurrencyLimitedCommand)
+
+// WithConcurrencyWaitTimeout makes the command wait up to the given duration for a running
+// execution to finish when the limit is reached, instead of failing right away
+func WithConcurrencyWaitTimeout(timeout time.Duration) ConcurrencyOption {
+	return func(c *ConcurrencyLimitedCommand) {
+		c.waitTimeout = timeout
+	}
+}
+
+// ConcurrencyLimitedCommand is a wrapper limiting how many executions of the wrapped
+// command can run at the same time within the process, for example in a long-running
+// process embedding the CLI. Unlike FsLockableCommand, it does not prevent executions in
+// other processes.
+type ConcurrencyLimitedCommand struct {
+	// The command whose executions are limited
+	Command Command
+
+	semaphore   chan struct{}
+	waitTimeout time.Duration
+}
+
+// NewConcurrencyLimitedCommand wraps the given command so that at most maxConcurrent
+// executions of it run at the same time (at least 1). Further executions fail with
+// CommandBusy, unless a wait timeout is set with WithConcurrencyWaitTimeout. The limit is
+// held in memory by the returned wrapper, so register the same wrapper wherever the
+// executions must share it.
+func NewConcurrencyLimitedCommand(cmd Command, maxConcurrent int, opts ...ConcurrencyOption) Command {
+	limited := &ConcurrencyLimitedCommand{
+		Command:   cmd,
+		semaphore: make(chan struct{}, max(maxConcurrent, 1)),
+	}
+	for _, opt := range opts {
+		opt(limited)
+	}
+	return limited
+}
+
+// Id returns the ID of the wrapped command.
+func (c *ConcurrencyLimitedCommand) Id() string {
+	return c.Command.Id()
+}
+
+// Description returns the description of the wrapped command.
+func (c *ConcurrencyLimitedCommand) Description() string {
+	return c.Command.Description()
+}
+
+// Unwrap returns the wrapped command.
+func (c *ConcurrencyLimitedCommand) Unwrap() Command {
+	return c.Command
+}
+
+// DefineFlags delegates to the wrapped command.
+func (c *ConcurrencyLimitedCommand) DefineFlags(flagSet *flag.FlagSet) {
+	c.Command.DefineFlags(flagSet)
+}
+
+// ValidateFlags delegates to the wrapped command.
+func (c *ConcurrencyLimitedCommand) ValidateFlags() error {
+	return c.Command.ValidateFlags()
+}
+
+// Exec executes the wrapped command if the concurrency limit allows it.
+func (c *ConcurrencyLimitedCommand) Exec(stdWriter io.Writer) error {
+	return c.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext executes the wrapped command with the given context once a slot is
+// available. It returns an error wrapping CommandBusy if no slot is available within the
+// wait timeout, or the context error if the context is cancelled while waiting.
+func (c *ConcurrencyLimitedCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() { <-c.semaphore }()
+
+	return execCommand(ctx, c.Command, stdWriter)
+}
+
+// acquire takes a slot of the semaphore, waiting up to the wait timeout
+func (c *ConcurrencyLimitedCommand) acquire(ctx context.Context) error {
+	select {
+	case c.semaphore <- struct{}{}:
+		return nil
+	default:
+	}
+
+	busyErr := fmt.Errorf("%w: command %s, limit %d", CommandBusy, c.Command.Id(), cap(c.semaphore))
+	if c.waitTimeout <= 0 {
+		return busyErr
+	}
+
+	timer := time.NewTimer(c.waitTimeout)
+	defer timer.Stop()
+	select {
+	case c.semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return busyErr
+	}
+}
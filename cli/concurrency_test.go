@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestItLimitsConcurrentExecutions(t *testing.T) {
+	const maxConcurrent, executions = 2, 8
+
+	var running, maxRunning atomic.Int32
+	release := make(chan struct{})
+	cmd := NewConcurrencyLimitedCommand(
+		&MockCommand{
+			id: "limited-fail-fast",
+			execFunc: func(writer io.Writer) error {
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					seen := maxRunning.Load()
+					if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				<-release
+				return nil
+			},
+		},
+		maxConcurrent,
+	)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, executions)
+	for range executions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- cmd.Exec(io.Discard)
+		}()
+	}
+
+	// The executions exceeding the limit fail right away
+	for range executions - maxConcurrent {
+		if err := <-errs; !errors.Is(err, CommandBusy) {
+			t.Errorf("Exec() error = %v, want CommandBusy", err)
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Exec() error = %v, want nil", err)
+		}
+	}
+	if maxRunning.Load() > maxConcurrent {
+		t.Errorf("max concurrent executions = %d, want at most %d", maxRunning.Load(), maxConcurrent)
+	}
+}
+
+func TestItCanWaitForAConcurrencySlot(t *testing.T) {
+	tests := []struct {
+		name        string
+		waitTimeout time.Duration
+		holdFor     time.Duration
+		wantErr     error
+	}{
+		{
+			name:        "acquires the slot released within the timeout",
+			waitTimeout: time.Second,
+			holdFor:     10 * time.Millisecond,
+		},
+		{
+			name:        "fails when the slot is not released within the timeout",
+			waitTimeout: 10 * time.Millisecond,
+			holdFor:     200 * time.Millisecond,
+			wantErr:     CommandBusy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				started := make(chan struct{}, 1)
+				mock := &MockCommand{
+					id: "limited-wait",
+					execFunc: func(writer io.Writer) error {
+						started <- struct{}{}
+						time.Sleep(tt.holdFor)
+						return nil
+					},
+				}
+				cmd := NewConcurrencyLimitedCommand(mock, 1, WithConcurrencyWaitTimeout(tt.waitTimeout))
+
+				done := make(chan error, 1)
+				go func() { done <- cmd.Exec(io.Discard) }()
+				<-started
+
+				err := cmd.Exec(io.Discard)
+
+				if tt.wantErr == nil && err != nil {
+					t.Errorf("Exec() error = %v, want nil", err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				if err := <-done; err != nil {
+					t.Errorf("first Exec() error = %v, want nil", err)
+				}
+			},
+		)
+	}
+}
+
+func TestItStopsWaitingForAConcurrencySlotWhenTheContextIsCancelled(t *testing.T) {
+	release := make(chan struct{})
+	mock := &MockCommand{
+		id: "limited-cancel",
+		execFunc: func(writer io.Writer) error {
+			<-release
+			return nil
+		},
+	}
+	cmd := NewConcurrencyLimitedCommand(mock, 1, WithConcurrencyWaitTimeout(time.Minute))
+	limited := cmd.(*ConcurrencyLimitedCommand)
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Exec(io.Discard) }()
+	for len(limited.semaphore) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := limited.ExecContext(ctx, io.Discard)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("first Exec() error = %v, want nil", err)
+	}
+}
+
+func TestItLimitsEachWrapperWithItsOwnCapacity(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	mock := &MockCommand{
+		id: "limited-capacity",
+		execFunc: func(writer io.Writer) error {
+			<-release
+			return nil
+		},
+	}
+
+	single := NewConcurrencyLimitedCommand(mock, 1).(*ConcurrencyLimitedCommand)
+	double := NewConcurrencyLimitedCommand(mock, 2).(*ConcurrencyLimitedCommand)
+	if cap(single.semaphore) != 1 || cap(double.semaphore) != 2 {
+		t.Fatalf("semaphore capacities = %d, %d, want 1, 2", cap(single.semaphore), cap(double.semaphore))
+	}
+
+	go func() { _ = single.Exec(io.Discard) }()
+	for len(single.semaphore) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if len(double.semaphore) != 0 {
+		t.Errorf("Executions of one wrapper should not use the slots of another one")
+	}
+}
@@ -0,0 +1,295 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ConfigFlagName is the name of the flag defined for commands implementing
+// ConfigurableCommand
+const ConfigFlagName = "config"
+
+// ConfigurableCommand is an optional interface for commands which read default flag
+// values from a config file. A --config flag is defined automatically for such commands,
+// defaulting to DefaultConfigPath(). Values from the file are applied to the flags which
+// were not explicitly set in the CLI args, so args always take precedence. They act as
+// defaults: they do not satisfy required flags, nor conflict with mutually exclusive ones.
+type ConfigurableCommand interface {
+	Command
+	DefaultConfigPath() string
+}
+
+// LoadFlagDefaults reads flag values from a JSON or TOML file (chosen by the ".toml"
+// extension, JSON otherwise) keyed by flag name, and applies them to the flags of the
+// flag set which were not explicitly set. A missing file is ignored, while a malformed
+// file or an invalid flag value results in an error. Keys not matching any flag are
+// ignored, so a single file can be shared by several commands. The values are applied
+// like flag defaults, so the flags are not reported as set by flagSet.Visit.
+func LoadFlagDefaults(flagSet *flag.FlagSet, path string) error {
+	_, err := applyFlagDefaults(flagSet, path, nil)
+	return err
+}
+
+// applyFlagDefaults implements LoadFlagDefaults, returning the names of the flags it set.
+// The preset flags, like the ones set from the environment, are skipped too.
+func applyFlagDefaults(flagSet *flag.FlagSet, path string, preset []string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var values map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		values, err = parseTomlValues(content)
+	} else {
+		values, err = parseJsonValues(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed config file %s: %w", path, err)
+	}
+
+	setFlags := make(map[string]bool)
+	for _, name := range preset {
+		setFlags[name] = true
+	}
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			setFlags[aliasTarget(setFlag)] = true
+		},
+	)
+
+	// Set the values directly, not through flagSet.Set, which would mark the flags as
+	// explicitly set
+	var applied []string
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		definedFlag := flagSet.Lookup(name)
+		if definedFlag == nil || setFlags[aliasTarget(definedFlag)] {
+			continue
+		}
+		value := values[name]
+		if err = definedFlag.Value.Set(value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for flag %s in config file %s: %w", value, name, path, err)
+		}
+		applied = append(applied, aliasTarget(definedFlag))
+	}
+
+	return applied, nil
+}
+
+// parseJsonValues parses a flat JSON object with scalar values
+func parseJsonValues(content []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var raw map[string]any
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("value of key %s must be a string, number or boolean", key)
+		}
+	}
+
+	return values, nil
+}
+
+// tomlBareKeyRegex matches the TOML bare keys
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlNumberRegex matches the TOML decimal, hexadecimal, octal and binary integers and
+// the decimal floats, with their optional underscores between digits
+var tomlNumberRegex = regexp.MustCompile(
+	`^([+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?|` +
+		`0x[0-9A-Fa-f](_?[0-9A-Fa-f])*|0o[0-7](_?[0-7])*|0b[01](_?[01])*)$`,
+)
+
+// parseTomlValues parses the flat "key = value" subset of TOML: comments, bare and quoted
+// keys, single-line basic and literal strings, numbers and booleans. Anything else, like
+// tables, arrays, dotted keys, dates or a key defined twice, is rejected with an error
+// naming the line.
+func parseTomlValues(content []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported", lineNumber)
+		}
+
+		key, rest, err := cutTomlKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		value, err := parseTomlValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if _, defined := values[key]; defined {
+			return nil, fmt.Errorf("line %d: key %s is defined more than once", lineNumber, key)
+		}
+		values[key] = value
+	}
+
+	return values, scanner.Err()
+}
+
+// cutTomlKey splits a "key = value" line into its bare or quoted key and the raw value
+func cutTomlKey(line string) (key string, rest string, err error) {
+	switch {
+	case strings.HasPrefix(line, `"`) || strings.HasPrefix(line, "'"):
+		key, rest, err = cutTomlString(line)
+		if err != nil {
+			return "", "", err
+		}
+	default:
+		end := strings.IndexAny(line, " \t=")
+		if end < 0 {
+			return "", "", errors.New("expected a key = value pair")
+		}
+		key, rest = line[:end], line[end:]
+		if strings.Contains(key, ".") {
+			return "", "", fmt.Errorf("dotted key %s is not supported", key)
+		}
+		if !tomlBareKeyRegex.MatchString(key) {
+			return "", "", fmt.Errorf("invalid key %s", key)
+		}
+	}
+
+	rest, found := strings.CutPrefix(strings.TrimSpace(rest), "=")
+	if !found {
+		return "", "", errors.New("expected a key = value pair")
+	}
+	if key == "" {
+		return "", "", errors.New("empty key")
+	}
+	return key, strings.TrimSpace(rest), nil
+}
+
+// parseTomlValue returns the value of a string, number or boolean, followed by nothing but
+// an optional comment
+func parseTomlValue(raw string) (value string, err error) {
+	rest := ""
+	switch {
+	case strings.HasPrefix(raw, `"""`) || strings.HasPrefix(raw, "'''"):
+		return "", errors.New("multi-line strings are not supported")
+	case strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "'"):
+		if value, rest, err = cutTomlString(raw); err != nil {
+			return "", err
+		}
+	default:
+		value = raw
+		if end := strings.IndexAny(raw, " \t#"); end >= 0 {
+			value, rest = raw[:end], raw[end:]
+		}
+		switch {
+		case value == "":
+			return "", errors.New("missing value")
+		case value == "true" || value == "false":
+		case tomlNumberRegex.MatchString(value):
+			value = strings.ReplaceAll(value, "_", "")
+		case strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{"):
+			return "", errors.New("arrays and inline tables are not supported")
+		default:
+			return "", fmt.Errorf("unsupported value %s, strings must be quoted", value)
+		}
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %s after the value", rest)
+	}
+	return value, nil
+}
+
+// cutTomlString returns the content of the basic (double-quoted) or literal
+// (single-quoted) string starting the text, and the text following it
+func cutTomlString(text string) (value string, rest string, err error) {
+	if text[0] == '\'' {
+		end := strings.IndexByte(text[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("unterminated literal string")
+		}
+		return text[1 : end+1], text[end+2:], nil
+	}
+
+	var unquoted strings.Builder
+	for i := 1; i < len(text); i++ {
+		switch char := text[i]; char {
+		case '"':
+			return unquoted.String(), text[i+1:], nil
+		case '\\':
+			if i+1 >= len(text) {
+				return "", "", errors.New("unterminated basic string")
+			}
+			i++
+			switch escaped := text[i]; escaped {
+			case 'b':
+				unquoted.WriteByte('\b')
+			case 't':
+				unquoted.WriteByte('\t')
+			case 'n':
+				unquoted.WriteByte('\n')
+			case 'f':
+				unquoted.WriteByte('\f')
+			case 'r':
+				unquoted.WriteByte('\r')
+			case '"', '\\':
+				unquoted.WriteByte(escaped)
+			case 'u', 'U':
+				digits := 4
+				if escaped == 'U' {
+					digits = 8
+				}
+				if i+digits >= len(text) {
+					return "", "", fmt.Errorf("invalid escape sequence \\%c", escaped)
+				}
+				code, parseErr := strconv.ParseUint(text[i+1:i+1+digits], 16, 32)
+				if parseErr != nil || !utf8.ValidRune(rune(code)) {
+					return "", "", fmt.Errorf("invalid escape sequence \\%s", text[i:i+1+digits])
+				}
+				unquoted.WriteRune(rune(code))
+				i += digits
+			default:
+				return "", "", fmt.Errorf("invalid escape sequence \\%c", escaped)
+			}
+		default:
+			unquoted.WriteByte(char)
+		}
+	}
+	return "", "", errors.New("unterminated basic string")
+}
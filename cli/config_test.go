@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockConfigurableCommand is a ConfigurableCommand implementation for testing
+type MockConfigurableCommand struct {
+	MockCommand
+	configPath string
+	name       string
+	count      int
+	verbose    bool
+	delay      time.Duration
+}
+
+func (m *MockConfigurableCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&m.name, "name", "default", "The name")
+	flagSet.IntVar(&m.count, "count", 1, "The count")
+	flagSet.BoolVar(&m.verbose, "verbose", false, "Verbose output")
+	flagSet.DurationVar(&m.delay, "delay", time.Second, "The delay")
+}
+
+func (m *MockConfigurableCommand) DefaultConfigPath() string {
+	return m.configPath
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestItCanLoadFlagDefaultsFromConfigFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{
+			name:     "json",
+			fileName: "config.json",
+			content:  `{"name": "john", "count": 3, "verbose": true, "delay": "2s", "unknown": 1}`,
+		},
+		{
+			name:     "toml",
+			fileName: "config.toml",
+			content: "# flag defaults\nname = \"john\"\ncount = 3 # inline comment\n" +
+				"verbose = true\ndelay = '2s'\nunknown = 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockConfigurableCommand{}
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				cmd.DefineFlags(flagSet)
+				_ = flagSet.Parse([]string{"--count", "5"})
+
+				err := LoadFlagDefaults(flagSet, writeConfigFile(t, tt.fileName, tt.content))
+				if err != nil {
+					t.Fatalf("LoadFlagDefaults() error = %v", err)
+				}
+
+				if cmd.name != "john" || !cmd.verbose || cmd.delay != 2*time.Second {
+					t.Errorf(
+						"LoadFlagDefaults() did not apply config values, got name=%q verbose=%v delay=%v",
+						cmd.name,
+						cmd.verbose,
+						cmd.delay,
+					)
+				}
+				if cmd.count != 5 {
+					t.Errorf("Args should override config values, got count=%d, want 5", cmd.count)
+				}
+			},
+		)
+	}
+}
+
+func TestItDoesNotMarkConfigValuesAsExplicitlySet(t *testing.T) {
+	cmd := &MockConfigurableCommand{}
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	cmd.DefineFlags(flagSet)
+	MarkRequired(flagSet, "name")
+	_ = flagSet.Parse([]string{"--count", "5"})
+
+	path := writeConfigFile(t, "config.json", `{"name": "john", "verbose": true}`)
+	if err := LoadFlagDefaults(flagSet, path); err != nil {
+		t.Fatalf("LoadFlagDefaults() error = %v", err)
+	}
+
+	if cmd.name != "john" || !cmd.verbose {
+		t.Errorf("LoadFlagDefaults() did not apply config values, got name=%q verbose=%v", cmd.name, cmd.verbose)
+	}
+	var setFlags []string
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			setFlags = append(setFlags, setFlag.Name)
+		},
+	)
+	if len(setFlags) != 1 || setFlags[0] != "count" {
+		t.Errorf("Visit() reported flags %v, want only [count]", setFlags)
+	}
+	if err := MutuallyExclusive(flagSet, "verbose", "count"); err != nil {
+		t.Errorf("MutuallyExclusive() error = %v, want nil for config values", err)
+	}
+	if err := CheckRequired(flagSet); err == nil {
+		t.Error("CheckRequired() should not be satisfied by config values")
+	}
+}
+
+func TestItHandlesMissingAndMalformedConfigFiles(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	(&MockConfigurableCommand{}).DefineFlags(flagSet)
+
+	missingPath := filepath.Join(t.TempDir(), "missing.json")
+	if err := LoadFlagDefaults(flagSet, missingPath); err != nil {
+		t.Errorf("LoadFlagDefaults() should ignore a missing file, got %v", err)
+	}
+
+	for name, content := range map[string]string{
+		"malformed.json":     `{"name": `,
+		"nested.json":        `{"name": {"first": "john"}}`,
+		"invalid-value.json": `{"count": "many"}`,
+		"malformed.toml":     "[section]\nname = \"john\"",
+	} {
+		if err := LoadFlagDefaults(flagSet, writeConfigFile(t, name, content)); err == nil {
+			t.Errorf("LoadFlagDefaults() should fail for %s", name)
+		}
+	}
+}
+
+func TestItRejectsTomlOutsideOfTheSupportedSubset(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "comment in an unquoted value", content: "name = jo#hn", wantErr: "line 1: unsupported value jo"},
+		{name: "unquoted string", content: "count = 3\nname = john", wantErr: "line 2: unsupported value john"},
+		{name: "text after a basic string", content: `name = "x" garbage`, wantErr: "line 1: unexpected garbage"},
+		{name: "text after a literal string", content: "name = 'x' garbage", wantErr: "line 1: unexpected garbage"},
+		{name: "duplicate key", content: "name = 'a'\nname = 'b'", wantErr: "line 2: key name is defined more than once"},
+		{name: "dotted key", content: "server.name = 'a'", wantErr: "line 1: dotted key server.name"},
+		{name: "quoted key followed by text", content: `"server".name = 'a'`, wantErr: "line 1: expected a key = value pair"},
+		{name: "array", content: "name = ['a', 'b']", wantErr: "line 1: arrays and inline tables"},
+		{name: "inline table", content: "name = { first = 'a' }", wantErr: "line 1: arrays and inline tables"},
+		{name: "table", content: "[section]\nname = 'a'", wantErr: "line 1: tables are not supported"},
+		{name: "multi-line string", content: `name = """a"""`, wantErr: "line 1: multi-line strings"},
+		{name: "go escape", content: `name = "\x41"`, wantErr: `line 1: invalid escape sequence \x`},
+		{name: "missing value", content: "name =", wantErr: "line 1: missing value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := parseTomlValues([]byte(tt.content))
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseTomlValues() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanParseTheSupportedTomlSubset(t *testing.T) {
+	content := `# comment
+name = "tab\tquote\" \u00e9" # comment
+"quoted-key" = 'C:\path\#1'
+count = 1_000
+mask = 0xff
+ratio = -1.5e3
+verbose = false
+`
+	values, err := parseTomlValues([]byte(content))
+	if err != nil {
+		t.Fatalf("parseTomlValues() error = %v, want nil", err)
+	}
+
+	want := map[string]string{
+		"name":       "tab\tquote\" \u00e9",
+		"quoted-key": `C:\path\#1`,
+		"count":      "1000",
+		"mask":       "0xff",
+		"ratio":      "-1.5e3",
+		"verbose":    "false",
+	}
+	if !maps.Equal(values, want) {
+		t.Errorf("parseTomlValues() = %v, want %v", values, want)
+	}
+}
+
+func TestRunCommandAppliesConfigFileThroughConfigFlag(t *testing.T) {
+	defaultPath := writeConfigFile(t, "default.json", `{"name": "from-default"}`)
+	overridePath := writeConfigFile(t, "override.json", `{"name": "from-override"}`)
+
+	var got string
+	cmd := &MockConfigurableCommand{configPath: defaultPath}
+	cmd.execFunc = func(writer io.Writer) error {
+		got = cmd.name
+		return nil
+	}
+	lockableCmd := NewLockableCommand(cmd, t.TempDir())
+
+	var buf bytes.Buffer
+	if err := runCommand(context.Background(), lockableCmd, nil, &buf, &buf); err != nil {
+		t.Fatalf("runCommand() error = %v", err)
+	}
+	if got != "from-default" {
+		t.Errorf("runCommand() name = %q, want from-default", got)
+	}
+
+	err := runCommand(context.Background(), lockableCmd, []string{"--config", overridePath}, &buf, &buf)
+	if err != nil {
+		t.Fatalf("runCommand() error = %v", err)
+	}
+	if got != "from-override" {
+		t.Errorf("runCommand() name = %q, want from-override", got)
+	}
+}
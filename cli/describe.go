@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"flag"
+	"slices"
+	"strings"
+)
+
+// CommandInfo describes a command, for tooling introspecting commands without running
+// them. It is also the json representation of a command in the help output.
+type CommandInfo struct {
+	Id          string         `json:"id"`
+	Description string         `json:"description"`
+	Category    string         `json:"category,omitempty"`
+	Deprecated  string         `json:"deprecated,omitempty"`
+	Aliases     []string       `json:"aliases"`
+	Flags       []FlagInfo     `json:"flags"`
+	RawArgs     bool           `json:"rawArgs,omitempty"`
+	Examples    []string       `json:"examples,omitempty"`
+	ExitCodes   map[int]string `json:"exitCodes,omitempty"`
+	Subcommands []CommandInfo  `json:"subcommands,omitempty"`
+}
+
+// FlagInfo describes a command flag
+type FlagInfo struct {
+	Name     string   `json:"name"`
+	Usage    string   `json:"usage"`
+	Default  string   `json:"default"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Group    string   `json:"group,omitempty"`
+}
+
+// Describe returns the description of the command. Its flags are enumerated by calling
+// DefineFlags on a throwaway flag set, so the command is neither parsed nor executed. If
+// DefineFlags panics, the command is described without flags.
+// Command groups are described with their subcommands instead of flags, and commands
+// taking raw args are described without flags.
+func Describe(command Command) CommandInfo {
+	info := CommandInfo{
+		Id:          command.Id(),
+		Description: command.Description(),
+		Category:    commandCategory(command),
+		Deprecated:  deprecationMessage(command),
+		Aliases:     []string{},
+		Flags:       []FlagInfo{},
+	}
+
+	if group, isGroup := command.(*CommandGroup); isGroup {
+		for _, child := range group.Commands() {
+			info.Subcommands = append(info.Subcommands, Describe(child))
+		}
+		return info
+	}
+
+	if exampleProvider, ok := findOptional[ExampleProvider](command); ok {
+		info.Examples = exampleProvider.Examples()
+	}
+
+	if documenter, ok := findOptional[ExitCodeDocumenter](command); ok {
+		info.ExitCodes = documenter.ExitCodes()
+	}
+
+	if takesRawArgs(command) {
+		info.RawArgs = true
+		return info
+	}
+
+	cmdFlagSet, err := commandFlagSet(command)
+	if err != nil {
+		return info
+	}
+	info.Flags = describeFlags(command, cmdFlagSet)
+
+	return info
+}
+
+// describeFlags returns the description of the flags of the flag set, aliases excluded,
+// along with their group when the command implements FlagGroupsProvider
+func describeFlags(command Command, cmdFlagSet *flag.FlagSet) []FlagInfo {
+	flags := []FlagInfo{}
+	aliases := flagAliases(cmdFlagSet)
+	groups := flagGroups(command)
+	cmdFlagSet.VisitAll(
+		func(flag *flag.Flag) {
+			if isFlagAlias(flag) {
+				return
+			}
+			flags = append(
+				flags,
+				FlagInfo{
+					Name:     flag.Name,
+					Usage:    flag.Usage,
+					Default:  flag.DefValue,
+					Type:     flagType(flag),
+					Required: isRequiredFlag(flag),
+					Aliases:  aliases[flag.Name],
+					Enum:     flagEnumValues(flag),
+					Group:    groups[flag.Name],
+				},
+			)
+		},
+	)
+	return flags
+}
+
+// DescribeAll returns the description of all registered commands, sorted by id
+func (registry *CommandsRegistry) DescribeAll() []CommandInfo {
+	infos := make([]CommandInfo, 0, len(registry.commands))
+	for _, command := range registry.commands {
+		infos = append(infos, Describe(command))
+	}
+	slices.SortFunc(
+		infos, func(a, b CommandInfo) int {
+			return strings.Compare(a.Id, b.Id)
+		},
+	)
+	return infos
+}
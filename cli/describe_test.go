@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestItCanDescribeCommands(t *testing.T) {
+	executed := ""
+	tests := []struct {
+		name    string
+		command Command
+		check   func(t *testing.T, info CommandInfo)
+	}{
+		{
+			name: "command with flags",
+			command: &MockCommandWithRequiredFlags{
+				MockCommand: MockCommand{id: "required-cmd", description: "Needs flags"},
+			},
+			check: func(t *testing.T, info CommandInfo) {
+				expected := []FlagInfo{
+					{Name: "count", Usage: "The count", Default: "1", Type: "int"},
+					{Name: "name", Usage: "The name", Default: "", Type: "string", Required: true},
+					{Name: "verbose", Usage: "Verbose output", Default: "false", Type: "bool", Required: true},
+				}
+				if fmt.Sprint(info.Flags) != fmt.Sprint(expected) {
+					t.Errorf("Describe() flags = %+v, want %+v", info.Flags, expected)
+				}
+				if info.Id != "required-cmd" || info.Description != "Needs flags" ||
+					info.Category != DefaultCategory {
+					t.Errorf("Describe() = %+v, want the command id, description and category", info)
+				}
+			},
+		},
+		{
+			name: "categorized command",
+			command: &MockCategorizedCommand{
+				MockCommand: MockCommand{id: "cache-clear"},
+				category:    "Cache",
+			},
+			check: func(t *testing.T, info CommandInfo) {
+				if info.Category != "Cache" || len(info.Flags) != 0 || info.Aliases == nil {
+					t.Errorf("Describe() = %+v, want the Cache category without flags", info)
+				}
+			},
+		},
+		{
+			name:    "command group",
+			command: newTestDbGroup(&executed),
+			check: func(t *testing.T, info CommandInfo) {
+				if len(info.Subcommands) != 1 || len(info.Subcommands[0].Subcommands) != 2 {
+					t.Fatalf("Describe() subcommands = %+v, want migrate with 2 subcommands", info.Subcommands)
+				}
+				if up := info.Subcommands[0].Subcommands[1]; up.Id != "up" || len(up.Flags) != 1 {
+					t.Errorf("Describe() up subcommand = %+v, want one flag", up)
+				}
+			},
+		},
+		{
+			name:    "raw args command",
+			command: &MockRawArgsCommand{MockArgsCommand{MockCommandWithFlags: MockCommandWithFlags{id: "exec"}}},
+			check: func(t *testing.T, info CommandInfo) {
+				if !info.RawArgs || len(info.Flags) != 0 {
+					t.Errorf("Describe() = %+v, want raw args without flags", info)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tt.check(t, Describe(tt.command))
+			},
+		)
+	}
+}
+
+func TestItCanDescribeAllRegisteredCommands(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.RegisterAll(
+		&MockCommand{id: "test-cmd"},
+		&MockCommandWithFlags{id: "flag-cmd"},
+	)
+
+	infos := registry.DescribeAll()
+
+	if len(infos) != 2 || infos[0].Id != "flag-cmd" || infos[1].Id != "test-cmd" {
+		t.Errorf("DescribeAll() = %+v, want flag-cmd and test-cmd sorted by id", infos)
+	}
+}
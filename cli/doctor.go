@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Validate checks that every registered command, subcommands of groups included, is well
+// formed: its id is valid, its description is not empty and DefineFlags does not panic on
+// a fresh flag set, which the flag package does when a flag name is defined twice. It
+// returns the problems found, joined in a single error, or nil if there are none. Run it
+// from a test or a CI step to catch wiring mistakes before release.
+func (registry *CommandsRegistry) Validate() error {
+	return errors.Join(registry.validationErrors()...)
+}
+
+// validationErrors returns the problems of the registered commands, sorted by command id
+func (registry *CommandsRegistry) validationErrors() []error {
+	var errs []error
+	for _, cmd := range sortedCommands(registry.Commands()) {
+		errs = append(errs, validateCommand(cmd, "")...)
+	}
+	return errs
+}
+
+// validateCommand returns the problems of the command and of its subcommands, if it is a
+// command group. The prefix holds the ids of the parent groups.
+func validateCommand(cmd Command, prefix string) []error {
+	if isNilCommand(cmd) {
+		return []error{fmt.Errorf("command %s: the command is nil", strings.TrimSpace(prefix))}
+	}
+
+	path := prefix + cmd.Id()
+	var errs []error
+	if err := validateCommandId(cmd.Id()); err != nil {
+		errs = append(errs, fmt.Errorf("command %s: %w", path, err))
+	}
+	if strings.TrimSpace(cmd.Description()) == "" {
+		errs = append(errs, fmt.Errorf("command %s: the description is empty", path))
+	}
+
+	if group, isGroup := cmd.(*CommandGroup); isGroup {
+		for _, child := range group.Commands() {
+			errs = append(errs, validateCommand(child, path+" ")...)
+		}
+		return errs
+	}
+
+	if _, err := commandFlagSet(cmd); err != nil {
+		if strings.Contains(err.Error(), "flag redefined") {
+			err = fmt.Errorf("a flag name is defined more than once (%w)", err)
+		}
+		errs = append(errs, fmt.Errorf("command %s: %w", path, err))
+	}
+	return errs
+}
+
+// sortedCommands returns the commands sorted by id
+func sortedCommands(commands map[string]Command) []Command {
+	sorted := make([]Command, 0, len(commands))
+	for _, id := range slices.Sorted(maps.Keys(commands)) {
+		sorted = append(sorted, commands[id])
+	}
+	return sorted
+}
+
+// DoctorCommand checks that the commands of a registry are well formed, see
+// CommandsRegistry.Validate
+type DoctorCommand struct {
+	CommandWithoutFlags
+	registry *CommandsRegistry
+}
+
+// NewDoctorCommand creates a command validating the commands of the registry
+func NewDoctorCommand(registry *CommandsRegistry) *DoctorCommand {
+	return &DoctorCommand{registry: registry}
+}
+
+func (c *DoctorCommand) Id() string {
+	return "doctor"
+}
+
+func (c *DoctorCommand) Description() string {
+	return "Checks that all the registered commands are well formed"
+}
+
+// Exec writes each problem found on its own line, failing if there is any
+func (c *DoctorCommand) Exec(stdWriter io.Writer) error {
+	problems := c.registry.validationErrors()
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintln(stdWriter, "All commands are well formed")
+		return nil
+	}
+
+	for _, problem := range problems {
+		_, _ = fmt.Fprintln(stdWriter, "- "+problem.Error())
+	}
+	return fmt.Errorf("problems found in the registered commands: %d", len(problems))
+}
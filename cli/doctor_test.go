@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// MockDuplicateFlagsCommand is a Command implementation defining a flag twice for testing
+type MockDuplicateFlagsCommand struct {
+	MockCommand
+}
+
+func (m *MockDuplicateFlagsCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.String("name", "", "The name")
+	flagSet.String("name", "", "The name, again")
+}
+
+func TestItCanValidateTheRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []Command
+		wantErrs []string
+	}{
+		{
+			name: "well formed commands",
+			commands: []Command{
+				&MockCommand{id: "greet", description: "Greets"},
+				&MockCommandWithFlags{id: "flag-cmd", description: "Command with flags"},
+			},
+		},
+		{
+			name: "broken commands",
+			commands: []Command{
+				&MockCommand{id: "greet", description: "Greets"},
+				&MockCommand{id: "undocumented", description: " "},
+				&MockDuplicateFlagsCommand{MockCommand{id: "duplicate", description: "Duplicate flags"}},
+				&MockPanickingFlagsCommand{MockCommand{id: "broken", description: "Broken flags"}},
+			},
+			wantErrs: []string{
+				"command broken: defining the flags panicked: database unreachable",
+				"command duplicate: a flag name is defined more than once",
+				"command undocumented: the description is empty",
+			},
+		},
+		{
+			name: "broken subcommands",
+			commands: func() []Command {
+				group := NewCommandGroup("db", "Database commands")
+				_ = group.Register(&MockCommand{id: "migrate"})
+				return []Command{group}
+			}(),
+			wantErrs: []string{"command db migrate: the description is empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				if err := registry.RegisterAll(tt.commands...); err != nil {
+					t.Fatalf("RegisterAll() error = %v", err)
+				}
+
+				err := registry.Validate()
+
+				if len(tt.wantErrs) == 0 {
+					if err != nil {
+						t.Errorf("Validate() error = %v, want nil", err)
+					}
+					return
+				}
+				if err == nil {
+					t.Fatalf("Validate() error = nil, want %q", tt.wantErrs)
+				}
+				lines := strings.Split(err.Error(), "\n")
+				if len(lines) != len(tt.wantErrs) {
+					t.Fatalf("Validate() error = %q, want %d problems", err, len(tt.wantErrs))
+				}
+				for i, wantErr := range tt.wantErrs {
+					if !strings.HasPrefix(lines[i], wantErr) {
+						t.Errorf("Validate() problem %d = %q, want %q", i, lines[i], wantErr)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItCanReportRegistryProblemsWithTheDoctorCommand(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "greet", description: "Greets"})
+	doctor := NewDoctorCommand(registry)
+
+	var buf bytes.Buffer
+	if err := doctor.Exec(&buf); err != nil || buf.String() != "All commands are well formed\n" {
+		t.Errorf("Exec() = (%q, %v), want no problems", buf.String(), err)
+	}
+
+	_ = registry.Register(&MockCommand{id: "undocumented"})
+	buf.Reset()
+	err := doctor.Exec(&buf)
+
+	if err == nil || err.Error() != "problems found in the registered commands: 1" {
+		t.Errorf("Exec() error = %v, want one problem", err)
+	}
+	if buf.String() != "- command undocumented: the description is empty\n" {
+		t.Errorf("Exec() output = %q, want the problem listed", buf.String())
+	}
+}
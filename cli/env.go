@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envPrefixKey is the context key of the environment variables prefix set via
+// WithEnvPrefix
+type envPrefixKey struct{}
+
+// nonEnvCharsRegex matches the runs of characters replaced by an underscore in the
+// environment variable names of flags
+var nonEnvCharsRegex = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// FlagEnvName returns the name of the environment variable holding the value of a flag:
+// the prefix and the flag name, upper-cased, joined by an underscore, each run of other
+// characters than letters and digits being replaced by an underscore. For example, the
+// "count-to" flag with the "MYAPP" prefix is read from MYAPP_COUNT_TO.
+func FlagEnvName(prefix string, flagName string) string {
+	name := nonEnvCharsRegex.ReplaceAllString(strings.ToUpper(flagName), "_")
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(strings.ToUpper(prefix), "_") + "_" + name
+}
+
+// LoadFlagEnv applies the values of the environment variables named after the flags, see
+// FlagEnvName, to the flags of the flag set which were not explicitly set. Variables set
+// to an empty value are ignored. It returns an error if a variable holds an invalid value
+// for its flag. The values are applied like flag defaults, so the flags are not reported
+// as set by flagSet.Visit: they neither satisfy required flags nor conflict with mutually
+// exclusive ones. When combined with LoadFlagDefaults, call it after it, so that the
+// environment takes precedence over the config file.
+func LoadFlagEnv(flagSet *flag.FlagSet, prefix string) error {
+	_, err := applyFlagEnv(flagSet, prefix)
+	return err
+}
+
+// applyFlagEnv implements LoadFlagEnv, returning the names of the flags it set
+func applyFlagEnv(flagSet *flag.FlagSet, prefix string) ([]string, error) {
+	setFlags := make(map[string]bool)
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			setFlags[aliasTarget(setFlag)] = true
+		},
+	)
+
+	var applied []string
+	var err error
+	flagSet.VisitAll(
+		func(definedFlag *flag.Flag) {
+			if err != nil || setFlags[definedFlag.Name] || isFlagAlias(definedFlag) {
+				return
+			}
+			envName := FlagEnvName(prefix, definedFlag.Name)
+			value, isSet := os.LookupEnv(envName)
+			if !isSet || value == "" {
+				return
+			}
+			// Set the value directly, like the config file values, not through flagSet.Set
+			if setErr := definedFlag.Value.Set(value); setErr != nil {
+				err = fmt.Errorf(
+					"invalid value %q for flag %s in environment variable %s: %w",
+					value,
+					definedFlag.Name,
+					envName,
+					setErr,
+				)
+				return
+			}
+			applied = append(applied, definedFlag.Name)
+		},
+	)
+	return applied, err
+}
+
+// withEnvPrefix returns a context holding the environment variables prefix
+func withEnvPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, envPrefixKey{}, prefix)
+}
+
+// envPrefixFrom returns the environment variables prefix of the context, and whether
+// reading flags from the environment is enabled
+func envPrefixFrom(ctx context.Context) (string, bool) {
+	prefix, enabled := ctx.Value(envPrefixKey{}).(string)
+	return prefix, enabled
+}
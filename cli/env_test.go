@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItCanNameFlagEnvVars(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		flagName string
+		want     string
+	}{
+		{prefix: "MYAPP", flagName: "count-to", want: "MYAPP_COUNT_TO"},
+		{prefix: "myapp_", flagName: "name", want: "MYAPP_NAME"},
+		{prefix: "MYAPP", flagName: "db.max--conns", want: "MYAPP_DB_MAX_CONNS"},
+		{prefix: "", flagName: "dry-run", want: "DRY_RUN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.want, func(t *testing.T) {
+				if got := FlagEnvName(tt.prefix, tt.flagName); got != tt.want {
+					t.Errorf("FlagEnvName(%q, %q) = %q, want %q", tt.prefix, tt.flagName, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestItReadsFlagsFromTheEnvironment(t *testing.T) {
+	configPath := writeConfigFile(t, "config.json", `{"name": "from-config", "count": 3, "verbose": true}`)
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantName    string
+		wantCount   int
+		wantVerbose bool
+		wantDelay   time.Duration
+		wantErr     string
+	}{
+		{
+			name:        "env overrides the config file and the defaults",
+			env:         map[string]string{"MYAPP_NAME": "from-env", "MYAPP_DELAY": "5s"},
+			wantName:    "from-env",
+			wantCount:   3,
+			wantVerbose: true,
+			wantDelay:   5 * time.Second,
+		},
+		{
+			name:        "args override the env",
+			args:        []string{"--name", "from-args"},
+			env:         map[string]string{"MYAPP_NAME": "from-env", "MYAPP_COUNT": "4"},
+			wantName:    "from-args",
+			wantCount:   4,
+			wantVerbose: true,
+			wantDelay:   time.Second,
+		},
+		{
+			name:        "empty env vars are ignored",
+			env:         map[string]string{"MYAPP_NAME": ""},
+			wantName:    "from-config",
+			wantCount:   3,
+			wantVerbose: true,
+			wantDelay:   time.Second,
+		},
+		{
+			name:    "invalid env value",
+			env:     map[string]string{"MYAPP_COUNT": "many"},
+			wantErr: `invalid value "many" for flag count in environment variable MYAPP_COUNT`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for name, value := range tt.env {
+					t.Setenv(name, value)
+				}
+				cmd := &MockConfigurableCommand{MockCommand: MockCommand{id: "deploy"}, configPath: configPath}
+				ctx := withEnvPrefix(context.Background(), "MYAPP")
+
+				err := runCommand(ctx, cmd, tt.args, io.Discard, io.Discard)
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("runCommand() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("runCommand() error = %v, want nil", err)
+				}
+				if cmd.name != tt.wantName || cmd.count != tt.wantCount || cmd.verbose != tt.wantVerbose ||
+					cmd.delay != tt.wantDelay {
+					t.Errorf(
+						"flags = (%q, %d, %v, %v), want (%q, %d, %v, %v)",
+						cmd.name, cmd.count, cmd.verbose, cmd.delay,
+						tt.wantName, tt.wantCount, tt.wantVerbose, tt.wantDelay,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestItOnlyReadsFlagsFromTheEnvironmentWithAPrefixOption(t *testing.T) {
+	t.Setenv("MYAPP_NAME", "from-env")
+	t.Setenv("NAME", "from-env")
+
+	for _, opts := range [][]BootstrapOption{{}, {WithEnvPrefix("MYAPP")}} {
+		cmd := &MockConfigurableCommand{MockCommand: MockCommand{id: "deploy"}}
+		registry := NewCommandsRegistry()
+		_ = registry.Register(cmd)
+
+		_, err := Run([]string{"deploy"}, registry, io.Discard, append(opts, WithoutSignalHandling())...)
+
+		wantName := "default"
+		if len(opts) > 0 {
+			wantName = "from-env"
+		}
+		if err != nil || cmd.name != wantName {
+			t.Errorf("Run() = (%v, name %q), want (nil, name %q)", err, cmd.name, wantName)
+		}
+	}
+}
+
+func TestItDoesNotMarkEnvValuesAsExplicitlySet(t *testing.T) {
+	t.Setenv("MYAPP_NAME", "from-env")
+	t.Setenv("MYAPP_VERBOSE", "true")
+
+	cmd := &MockConfigurableCommand{}
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	cmd.DefineFlags(flagSet)
+	MarkRequired(flagSet, "name")
+	_ = flagSet.Parse([]string{"--count", "5"})
+
+	if err := LoadFlagEnv(flagSet, "MYAPP"); err != nil {
+		t.Fatalf("LoadFlagEnv() error = %v", err)
+	}
+
+	if cmd.name != "from-env" || !cmd.verbose {
+		t.Errorf("LoadFlagEnv() did not apply env values, got name=%q verbose=%v", cmd.name, cmd.verbose)
+	}
+	var setFlags []string
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			setFlags = append(setFlags, setFlag.Name)
+		},
+	)
+	if len(setFlags) != 1 || setFlags[0] != "count" {
+		t.Errorf("Visit() reported flags %v, want only [count]", setFlags)
+	}
+	if err := MutuallyExclusive(flagSet, "verbose", "count"); err != nil {
+		t.Errorf("MutuallyExclusive() error = %v, want nil for env values", err)
+	}
+	if err := CheckRequired(flagSet); err == nil {
+		t.Error("CheckRequired() should not be satisfied by env values")
+	}
+}
@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJson = "json"
+)
+
+// ExitCoder can be implemented by errors returned from a command to control the process
+// exit code chosen by Bootstrap, instead of the default StatusErr.
+//
+// Precedence, from highest to lowest: the signal exit code (when the process was
+// interrupted), the code of an ExitCoder found in the error chain (this includes values
+// a command panicked with, as long as they are errors implementing ExitCoder), and
+// finally StatusErr for any other error, including recovered non-ExitCoder panics.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ExitError is an ExitCoder which attaches an exit code to an optional error. When Err
+// is nil, the command is considered successful, so Bootstrap exits with Code without
+// printing a failure message.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// NewExitError returns an error carrying the given exit code. Pass a nil err to signal a
+// successful run which should still exit with a custom code (e.g. "nothing to do").
+func NewExitError(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// TimeoutError is returned when a command does not finish within its timeout. It exits
+// the process with StatusTimeout.
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command timed out after %s", e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) ExitCode() int {
+	return StatusTimeout
+}
+
+// exitCodeFor returns the exit code which should be used for the given command error
+func exitCodeFor(err error) int {
+	if err == nil {
+		return StatusOk
+	}
+
+	var exitCoder ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+
+	return StatusErr
+}
+
+// exitCodeResolverKey is the context key of the function resolving the exit code of a run
+type exitCodeResolverKey struct{}
+
+// withExitCodeResolver returns a context holding the function resolving the exit code of
+// the run from the command error
+func withExitCodeResolver(ctx context.Context, resolve func(err error) int) context.Context {
+	return context.WithValue(ctx, exitCodeResolverKey{}, resolve)
+}
+
+// runExitCode returns the exit code the run exits with for the command error: the one
+// resolved by Bootstrap, honouring the skipped and the signal exit codes, or exitCodeFor
+// outside of Bootstrap
+func runExitCode(ctx context.Context, err error) int {
+	if resolve, ok := ctx.Value(exitCodeResolverKey{}).(func(err error) int); ok {
+		return resolve(err)
+	}
+	return exitCodeFor(err)
+}
+
+// isSilentExit reports whether the error only carries an exit code, with no failure. Only
+// the error itself is checked, not the errors it wraps, since a wrapping error adds its
+// own message, like fmt.Errorf("sync failed: %w", NewExitError(3, nil)) does.
+func isSilentExit(err error) bool {
+	exitErr, ok := err.(*ExitError)
+	return ok && exitErr.Err == nil
+}
+
+// ErrCommandNotFound is matched, with errors.Is, by the error returned when the requested
+// command is not registered
+var ErrCommandNotFound = errors.New("command not found")
+
+// CommandNotFoundError is returned when the requested command is not registered. It
+// carries the requested id and the registered ids similar to it.
+type CommandNotFoundError struct {
+	Id          string
+	Suggestions []string
+}
+
+func (e *CommandNotFoundError) Error() string {
+	if hint := formatSuggestions(e.Suggestions); hint != "" {
+		return fmt.Sprintf("The command %s does not exist, %s", e.Id, hint)
+	}
+	return fmt.Sprintf("The command %s does not exist", e.Id)
+}
+
+func (e *CommandNotFoundError) Is(target error) bool {
+	return target == ErrCommandNotFound
+}
+
+// FlagParseError is returned when the command flags cannot be parsed. The flag package
+// reports the error along with the command usage while parsing, so Bootstrap does not
+// report it again. It exits the process with StatusUsage.
+type FlagParseError struct {
+	Err error
+}
+
+func (e *FlagParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FlagParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FlagParseError) ExitCode() int {
+	return StatusUsage
+}
+
+// FlagRedefinedError is returned when a command defines the same flag more than once in
+// DefineFlags, which makes the flag package panic. Err holds the PanicError, with the stack
+// trace leading to the second definition.
+type FlagRedefinedError struct {
+	CommandId string
+	FlagName  string
+	Err       error
+}
+
+func (e *FlagRedefinedError) Error() string {
+	return fmt.Sprintf(
+		"command %s failed to define flags: the flag %s is defined more than once",
+		e.CommandId,
+		e.FlagName,
+	)
+}
+
+func (e *FlagRedefinedError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned when a command panics. Errors the command panicked with are
+// kept as Err, so they can still be inspected with errors.Is/As, while other values are
+// wrapped in an error. Stack holds the stack trace starting at the panic site.
+type PanicError struct {
+	Err   error
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PanicError) Unwrap() error {
+	return e.Err
+}
+
+// trimPanicStack removes the frames of the recovering code (debug.Stack, the deferred
+// function and the runtime panic) from a stack trace captured during a panic, so that
+// the trace starts at the panic site
+func trimPanicStack(stack []byte) []byte {
+	header, frames, found := bytes.Cut(stack, []byte("\n"))
+	if !found {
+		return stack
+	}
+
+	panicIndex := bytes.Index(frames, []byte("\npanic("))
+	if panicIndex < 0 {
+		return stack
+	}
+
+	// Skip the runtime panic function line and its source location line
+	rest := frames[panicIndex+1:]
+	for i := 0; i < 2; i++ {
+		if _, rest, found = bytes.Cut(rest, []byte("\n")); !found {
+			return stack
+		}
+	}
+
+	return append(append(header, '\n'), rest...)
+}
+
+// errorReport is the json representation of a failure, written by Bootstrap to the error
+// writer with the json error format
+type errorReport struct {
+	Command  string `json:"command"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exitCode"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Stack    string `json:"stack,omitempty"`
+}
+
+// writeErrorReport writes the failure to the error writer, as a json line with the json
+// error format, or as the given human-readable message otherwise. It returns the error
+// of the error writer, see writeFailure.
+func writeErrorReport(
+	errWriter io.Writer,
+	format string,
+	report errorReport,
+	message string,
+) error {
+	if format == ErrorFormatJson {
+		if content, err := json.Marshal(report); err == nil {
+			message = string(content) + "\n"
+		}
+	}
+	return writeFailure(errWriter, message)
+}
@@ -0,0 +1,472 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestItExitsWithTheCodeOfExitCoderErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		execFunc    func(writer io.Writer) error
+		wantCode    int
+		wantMessage bool
+	}{
+		{
+			name:        "plain error",
+			execFunc:    func(writer io.Writer) error { return errors.New("plain failure") },
+			wantCode:    StatusErr,
+			wantMessage: true,
+		},
+		{
+			name: "exit error with cause",
+			execFunc: func(writer io.Writer) error {
+				return NewExitError(3, errors.New("partial failure"))
+			},
+			wantCode:    3,
+			wantMessage: true,
+		},
+		{
+			name:        "success with code",
+			execFunc:    func(writer io.Writer) error { return NewExitError(2, nil) },
+			wantCode:    2,
+			wantMessage: false,
+		},
+		{
+			name: "wrapped exit error without cause",
+			execFunc: func(writer io.Writer) error {
+				return fmt.Errorf("sync failed: %w", NewExitError(3, nil))
+			},
+			wantCode:    3,
+			wantMessage: true,
+		},
+		{
+			name: "panic with exit error",
+			execFunc: func(writer io.Writer) error {
+				panic(NewExitError(4, errors.New("panicked failure")))
+			},
+			wantCode:    4,
+			wantMessage: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(&MockCommand{id: "exit-cmd", execFunc: tt.execFunc})
+
+				var errBuf bytes.Buffer
+				exitCode := -1
+				Bootstrap(
+					[]string{"exit-cmd"},
+					registry,
+					&bytes.Buffer{},
+					func(code int) { exitCode = code },
+					WithErrorWriter(&errBuf),
+				)
+
+				if exitCode != tt.wantCode {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, tt.wantCode)
+				}
+				hasMessage := strings.Contains(errBuf.String(), "Failed to execute command")
+				if hasMessage != tt.wantMessage {
+					t.Errorf(
+						"Bootstrap() failure message present = %v, want %v, got %q",
+						hasMessage,
+						tt.wantMessage,
+						errBuf.String(),
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestItPrintsPanicStackTracesInVerboseMode(t *testing.T) {
+	for _, verbose := range []bool{false, true} {
+		registry := NewCommandsRegistry()
+		_ = registry.Register(
+			&MockCommand{
+				id: "panic-cmd",
+				execFunc: func(writer io.Writer) error {
+					panic("boom")
+				},
+			},
+		)
+
+		var errBuf bytes.Buffer
+		Bootstrap(
+			[]string{"panic-cmd"},
+			registry,
+			&bytes.Buffer{},
+			func(code int) {},
+			WithErrorWriter(&errBuf),
+			WithVerbose(verbose),
+		)
+
+		output := errBuf.String()
+		if !strings.Contains(output, "Failed to execute command panic-cmd with error: command panicked: boom") {
+			t.Errorf("Bootstrap() should report the panic, got %q", output)
+		}
+		if strings.Contains(output, "goroutine ") != verbose {
+			t.Errorf("Bootstrap() verbose=%v, stack trace present = %v", verbose, !verbose)
+		}
+		if !verbose {
+			continue
+		}
+
+		// The first frame after the goroutine header is the panic site
+		lines := strings.Split(output, "\n")
+		if len(lines) < 3 || !strings.Contains(lines[2], "TestItPrintsPanicStackTracesInVerboseMode") {
+			t.Errorf("Stack trace should start at the panic site, got:\n%s", output)
+		}
+		if strings.Contains(output, "panicToError") {
+			t.Errorf("Stack trace should not contain the recovering frames, got:\n%s", output)
+		}
+	}
+}
+
+func TestItCanReportFailuresAsJson(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		execErr      error
+		wantCommand  string
+		wantError    string
+		wantExitCode int
+	}{
+		{
+			name:         "command failure",
+			args:         []string{"failing-cmd"},
+			execErr:      errors.New("something broke"),
+			wantCommand:  "failing-cmd",
+			wantError:    "something broke",
+			wantExitCode: StatusErr,
+		},
+		{
+			name:         "exit coder failure",
+			args:         []string{"failing-cmd"},
+			execErr:      NewExitError(3, errors.New("bad input")),
+			wantCommand:  "failing-cmd",
+			wantError:    "bad input",
+			wantExitCode: 3,
+		},
+		{
+			name:         "unknown command",
+			args:         []string{"unknown-cmd"},
+			wantCommand:  "unknown-cmd",
+			wantError:    "The command unknown-cmd does not exist",
+			wantExitCode: StatusErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(
+					&MockCommand{
+						id: "failing-cmd",
+						execFunc: func(writer io.Writer) error {
+							return tt.execErr
+						},
+					},
+				)
+
+				var errBuf bytes.Buffer
+				exitCode := -1
+				Bootstrap(
+					tt.args,
+					registry,
+					&bytes.Buffer{},
+					func(code int) { exitCode = code },
+					WithErrorWriter(&errBuf),
+					WithErrorFormat(ErrorFormatJson),
+				)
+
+				var report struct {
+					Command  string `json:"command"`
+					Error    string `json:"error"`
+					ExitCode int    `json:"exitCode"`
+				}
+				if err := json.Unmarshal(errBuf.Bytes(), &report); err != nil {
+					t.Fatalf("Error output is not valid json: %v\n%s", err, errBuf.String())
+				}
+				if report.Command != tt.wantCommand || report.Error != tt.wantError {
+					t.Errorf("Unexpected error report %+v", report)
+				}
+				if report.ExitCode != tt.wantExitCode || exitCode != tt.wantExitCode {
+					t.Errorf(
+						"Reported exitCode = %v, process exitCode = %v, want %v",
+						report.ExitCode,
+						exitCode,
+						tt.wantExitCode,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestItOnlyWritesJsonLinesWithTheJsonErrorFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+	}{
+		{
+			name:         "command flag parse error",
+			args:         []string{"required-cmd", "--unknown"},
+			wantExitCode: StatusUsage,
+		},
+		{
+			name:         "missing required flag",
+			args:         []string{"required-cmd"},
+			wantExitCode: StatusErr,
+		},
+		{
+			name:         "global flag parse error",
+			args:         []string{"--timeout", "soon", "required-cmd"},
+			wantExitCode: StatusUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(&MockCommandWithRequiredFlags{MockCommand: MockCommand{id: "required-cmd"}})
+
+				var errBuf bytes.Buffer
+				exitCode, _ := Run(
+					tt.args,
+					registry,
+					io.Discard,
+					WithErrorWriter(&errBuf),
+					WithErrorFormat(ErrorFormatJson),
+					WithoutSignalHandling(),
+				)
+
+				lines := strings.Split(strings.TrimSuffix(errBuf.String(), "\n"), "\n")
+				if len(lines) != 1 {
+					t.Fatalf("Error output has %d lines, want a single json line:\n%s", len(lines), errBuf.String())
+				}
+				var report errorReport
+				if err := json.Unmarshal([]byte(lines[0]), &report); err != nil {
+					t.Fatalf("Error output is not a json line: %v\n%s", err, errBuf.String())
+				}
+				if report.ExitCode != tt.wantExitCode || exitCode != tt.wantExitCode {
+					t.Errorf("Reported exitCode = %v, process exitCode = %v, want %v", report.ExitCode, exitCode, tt.wantExitCode)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanReturnCommandNotFoundErrorsFromRun(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "say-hello"})
+
+	var errBuf bytes.Buffer
+	exitCode, err := Run(
+		[]string{"say-helo"},
+		registry,
+		io.Discard,
+		WithErrorWriter(&errBuf),
+		WithoutSignalHandling(),
+	)
+
+	if exitCode != StatusErr {
+		t.Errorf("Run() exitCode = %v, want %v", exitCode, StatusErr)
+	}
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Fatalf("Run() error = %v, want it to match ErrCommandNotFound", err)
+	}
+
+	var notFoundErr *CommandNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("Run() error = %T, want *CommandNotFoundError", err)
+	}
+	if notFoundErr.Id != "say-helo" {
+		t.Errorf("CommandNotFoundError.Id = %q, want %q", notFoundErr.Id, "say-helo")
+	}
+	if len(notFoundErr.Suggestions) != 1 || notFoundErr.Suggestions[0] != "say-hello" {
+		t.Errorf("CommandNotFoundError.Suggestions = %v, want [say-hello]", notFoundErr.Suggestions)
+	}
+	if strings.HasSuffix(err.Error(), "\n") {
+		t.Errorf("CommandNotFoundError.Error() should not end with a newline, got %q", err.Error())
+	}
+	if strings.Contains(errBuf.String(), "\n\n") {
+		t.Errorf("Run() error output should not contain blank lines, got %q", errBuf.String())
+	}
+}
+
+func TestItCanHandleUnknownCommandsWithAHook(t *testing.T) {
+	hookErr := NewExitError(4, errors.New("external tool failed"))
+
+	tests := []struct {
+		name         string
+		args         []string
+		handled      bool
+		hookErr      error
+		wantId       string
+		wantArgs     []string
+		wantExitCode int
+		wantErr      error
+		wantOutput   string
+	}{
+		{
+			name:         "handled successfully",
+			args:         []string{"lazy", "--name", "john"},
+			handled:      true,
+			wantId:       "lazy",
+			wantArgs:     []string{"--name", "john"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "handled with an error",
+			args:         []string{"lazy"},
+			handled:      true,
+			hookErr:      hookErr,
+			wantId:       "lazy",
+			wantArgs:     []string{},
+			wantExitCode: 4,
+			wantErr:      hookErr,
+			wantOutput:   "external tool failed",
+		},
+		{
+			name:         "unknown subcommand handled",
+			args:         []string{"db", "seed", "users"},
+			handled:      true,
+			wantId:       "db seed",
+			wantArgs:     []string{"users"},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "declined",
+			args:         []string{"say-helo"},
+			wantId:       "say-helo",
+			wantArgs:     []string{},
+			wantExitCode: StatusErr,
+			wantErr:      ErrCommandNotFound,
+			wantOutput:   "did you mean 'say-hello'?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				executed := ""
+				registry := NewCommandsRegistry()
+				_ = registry.Register(&MockCommand{id: "say-hello"})
+				_ = registry.Register(newTestDbGroup(&executed))
+
+				var hookId string
+				var hookArgs []string
+				hook := func(id string, args []string) (bool, error) {
+					hookId, hookArgs = id, args
+					return tt.handled, tt.hookErr
+				}
+
+				var errBuf bytes.Buffer
+				exitCode, err := Run(
+					tt.args,
+					registry,
+					io.Discard,
+					WithErrorWriter(&errBuf),
+					WithoutSignalHandling(),
+					WithCommandNotFoundHook(hook),
+				)
+
+				if hookId != tt.wantId || !slices.Equal(hookArgs, tt.wantArgs) {
+					t.Errorf("hook called with (%q, %q), want (%q, %q)", hookId, hookArgs, tt.wantId, tt.wantArgs)
+				}
+				if exitCode != tt.wantExitCode {
+					t.Errorf("Run() exitCode = %v, want %v", exitCode, tt.wantExitCode)
+				}
+				if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+					t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantOutput == "" && errBuf.Len() > 0 || !strings.Contains(errBuf.String(), tt.wantOutput) {
+					t.Errorf("Run() error output = %q, want %q", errBuf.String(), tt.wantOutput)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanReturnTheCommandErrorFromRun(t *testing.T) {
+	execErr := NewExitError(3, errors.New("bad input"))
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "failing-cmd",
+			execFunc: func(writer io.Writer) error {
+				return execErr
+			},
+		},
+	)
+
+	exitCode, err := Run(
+		[]string{"failing-cmd"},
+		registry,
+		io.Discard,
+		WithErrorWriter(io.Discard),
+		WithoutSignalHandling(),
+	)
+
+	if exitCode != 3 {
+		t.Errorf("Run() exitCode = %v, want %v", exitCode, 3)
+	}
+	if !errors.Is(err, execErr) {
+		t.Errorf("Run() error = %v, want %v", err, execErr)
+	}
+	if errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("Run() error should not match ErrCommandNotFound")
+	}
+}
+
+func TestItExitsWithADistinctCodeWhenTheFailureCannotBeWritten(t *testing.T) {
+	var fallback bytes.Buffer
+	previousFallback := fallbackErrWriter
+	fallbackErrWriter = &fallback
+	defer func() {
+		fallbackErrWriter = previousFallback
+	}()
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "failing-cmd",
+			execFunc: func(writer io.Writer) error {
+				return NewExitError(3, errors.New("something broke"))
+			},
+		},
+	)
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"failing-cmd"},
+		registry,
+		io.Discard,
+		func(code int) { exitCode = code },
+		WithErrorWriter(failingWriter{}),
+		WithoutSignalHandling(),
+	)
+
+	if exitCode != StatusOutputErr {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOutputErr)
+	}
+	for _, expected := range []string{"failingWriter", "disk full", "something broke"} {
+		if !strings.Contains(fallback.String(), expected) {
+			t.Errorf("fallback output = %q, want it to contain %q", fallback.String(), expected)
+		}
+	}
+}
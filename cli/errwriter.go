@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// errWriterKey is the context key of the command error writer
+type errWriterKey struct{}
+
+// withErrWriter returns a context holding the command error writer
+func withErrWriter(ctx context.Context, errWriter io.Writer) context.Context {
+	return context.WithValue(ctx, errWriterKey{}, errWriter)
+}
+
+// ErrWriter returns the error writer of the command from the context passed to commands
+// implementing ContextualCommand, the one set with WithErrorWriter, defaulting to
+// os.Stderr. Commands running external processes use it as their stderr, so that it is
+// captured along with the other error messages.
+func ErrWriter(ctx context.Context) io.Writer {
+	if errWriter, ok := ctx.Value(errWriterKey{}).(io.Writer); ok && errWriter != nil {
+		return errWriter
+	}
+	return os.Stderr
+}
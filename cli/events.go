@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"sync"
+	"time"
+)
+
+// The lifecycle events written to the events writer, see WithEventWriter
+const (
+	EventCommandStart     = "command_start"
+	EventFlagParsed       = "flag_parsed"
+	EventValidationFailed = "validation_failed"
+	EventCommandEnd       = "command_end"
+)
+
+// eventsKey is the context key of the event emitter
+type eventsKey struct{}
+
+// executionEvent is the json representation of a lifecycle event
+type executionEvent struct {
+	Event      string            `json:"event"`
+	Time       time.Time         `json:"time"`
+	Command    string            `json:"command"`
+	Flags      map[string]string `json:"flags,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	ExitCode   *int              `json:"exitCode,omitempty"`
+	DurationMs *int64            `json:"durationMs,omitempty"`
+}
+
+// eventEmitter writes the lifecycle events of a command execution as json lines
+type eventEmitter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	command string
+	start   time.Time
+}
+
+func newEventEmitter(writer io.Writer, command string) *eventEmitter {
+	return &eventEmitter{encoder: json.NewEncoder(writer), command: command}
+}
+
+// emit writes the event, stamped with the current time and the command id. Events are
+// best effort, a failure to write them does not fail the command.
+func (e *eventEmitter) emit(event executionEvent) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	event.Time = time.Now()
+	event.Command = e.command
+	_ = e.encoder.Encode(event)
+}
+
+// commandStarted emits the command_start event
+func (e *eventEmitter) commandStarted() {
+	if e == nil {
+		return
+	}
+	e.start = time.Now()
+	e.emit(executionEvent{Event: EventCommandStart})
+}
+
+// flagsParsed emits the flag_parsed event, with the flags set in the args
+func (e *eventEmitter) flagsParsed(flagSet *flag.FlagSet) {
+	if e == nil {
+		return
+	}
+
+	flags := make(map[string]string)
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			flags[setFlag.Name] = setFlag.Value.String()
+		},
+	)
+	e.emit(executionEvent{Event: EventFlagParsed, Flags: flags})
+}
+
+// validationFailed emits the validation_failed event
+func (e *eventEmitter) validationFailed(err error) {
+	e.emit(executionEvent{Event: EventValidationFailed, Error: err.Error()})
+}
+
+// commandEnded emits the command_end event, with the exit code, the error, if any, and
+// the duration of the execution
+func (e *eventEmitter) commandEnded(exitCode int, err error) {
+	if e == nil {
+		return
+	}
+
+	duration := time.Since(e.start).Milliseconds()
+	event := executionEvent{Event: EventCommandEnd, ExitCode: &exitCode, DurationMs: &duration}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	e.emit(event)
+}
+
+// withEvents returns a context holding the event emitter
+func withEvents(ctx context.Context, emitter *eventEmitter) context.Context {
+	return context.WithValue(ctx, eventsKey{}, emitter)
+}
+
+// eventsFrom returns the event emitter of the context, nil if events are not enabled,
+// emitting on a nil emitter being a no-op
+func eventsFrom(ctx context.Context) *eventEmitter {
+	emitter, _ := ctx.Value(eventsKey{}).(*eventEmitter)
+	return emitter
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestItCanEmitExecutionEvents(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.RegisterAll(
+		&MockCommandWithFlags{id: "flag-cmd"},
+		&MockCommandWithRequiredFlags{MockCommand: MockCommand{id: "required-cmd"}},
+		&MockCommand{
+			id: "failing-cmd",
+			execFunc: func(writer io.Writer) error {
+				return errors.New("something broke")
+			},
+		},
+	)
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantEvents   []string
+		wantExitCode int
+		wantError    string
+	}{
+		{
+			name:         "successful command",
+			args:         []string{"flag-cmd", "--test-flag", "value"},
+			wantEvents:   []string{EventCommandStart, EventFlagParsed, EventCommandEnd},
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "invalid flags",
+			args:         []string{"required-cmd"},
+			wantEvents:   []string{EventCommandStart, EventFlagParsed, EventValidationFailed, EventCommandEnd},
+			wantExitCode: StatusErr,
+			wantError:    "--name",
+		},
+		{
+			name:         "failing command",
+			args:         []string{"failing-cmd"},
+			wantEvents:   []string{EventCommandStart, EventFlagParsed, EventCommandEnd},
+			wantExitCode: StatusErr,
+			wantError:    "something broke",
+		},
+		{
+			name:         "unknown command",
+			args:         []string{"missing-cmd"},
+			wantEvents:   []string{EventCommandStart, EventCommandEnd},
+			wantExitCode: StatusErr,
+			wantError:    "does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var events bytes.Buffer
+				_, _ = Run(
+					tt.args,
+					registry,
+					io.Discard,
+					WithEventWriter(&events),
+					WithErrorWriter(io.Discard),
+					WithoutSignalHandling(),
+				)
+
+				var decoded []executionEvent
+				for _, line := range strings.Split(strings.TrimSpace(events.String()), "\n") {
+					var event executionEvent
+					if err := json.Unmarshal([]byte(line), &event); err != nil {
+						t.Fatalf("event %q is not valid json: %v", line, err)
+					}
+					decoded = append(decoded, event)
+				}
+
+				if len(decoded) != len(tt.wantEvents) {
+					t.Fatalf("events = %s, want %v", events.String(), tt.wantEvents)
+				}
+				for i, event := range decoded {
+					if event.Event != tt.wantEvents[i] || event.Command != tt.args[0] || event.Time.IsZero() {
+						t.Errorf("event %d = %+v, want %s of command %s", i, event, tt.wantEvents[i], tt.args[0])
+					}
+				}
+
+				end := decoded[len(decoded)-1]
+				if end.ExitCode == nil || *end.ExitCode != tt.wantExitCode || end.DurationMs == nil {
+					t.Errorf("command_end event = %s, want exit code %d and a duration", events.String(), tt.wantExitCode)
+				}
+				if !strings.Contains(end.Error, tt.wantError) || (tt.wantError == "" && end.Error != "") {
+					t.Errorf("command_end error = %q, want %q", end.Error, tt.wantError)
+				}
+			},
+		)
+	}
+}
+
+func TestItReportsTheParsedFlagsInEvents(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommandWithFlags{id: "flag-cmd"})
+
+	var events bytes.Buffer
+	_, _ = Run(
+		[]string{"flag-cmd", "--test-flag", "value"},
+		registry,
+		io.Discard,
+		WithEventWriter(&events),
+		WithoutSignalHandling(),
+	)
+
+	if !strings.Contains(events.String(), `"event":"flag_parsed"`) ||
+		!strings.Contains(events.String(), `"flags":{"test-flag":"value"}`) {
+		t.Errorf("events = %s, want a flag_parsed event with the test-flag value", events.String())
+	}
+}
+
+func TestItReportsTheResolvedCommandInEvents(t *testing.T) {
+	group := NewCommandGroup("db", "Database commands")
+	_ = group.Register(&MockCommand{id: "migrate"})
+	registry := NewCommandsRegistry(WithCaseInsensitiveLookup())
+	_ = registry.RegisterAll(group, &MockCommandWithFlags{id: "flag-cmd"})
+
+	tests := []struct {
+		name        string
+		args        []string
+		opts        []BootstrapOption
+		wantCommand string
+	}{
+		{
+			name:        "subcommand",
+			args:        []string{"db", "migrate"},
+			wantCommand: "db migrate",
+		},
+		{
+			name:        "case-insensitive id",
+			args:        []string{"FLAG-CMD"},
+			wantCommand: "flag-cmd",
+		},
+		{
+			name:        "default command",
+			args:        []string{"--test-flag", "value"},
+			opts:        []BootstrapOption{WithDefaultCommand("flag-cmd")},
+			wantCommand: "flag-cmd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var events bytes.Buffer
+				opts := append([]BootstrapOption{WithEventWriter(&events), WithoutSignalHandling()}, tt.opts...)
+				_, _ = Run(tt.args, registry, io.Discard, opts...)
+
+				lines := strings.Split(strings.TrimSpace(events.String()), "\n")
+				for _, line := range lines {
+					var event executionEvent
+					if err := json.Unmarshal([]byte(line), &event); err != nil || event.Command != tt.wantCommand {
+						t.Errorf("event %s, want it to report command %q", line, tt.wantCommand)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItEmitsEventsWhenTheGlobalFlagsCannotBeParsed(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "test-cmd"})
+
+	var events bytes.Buffer
+	_, _ = Run(
+		[]string{"--unknown-global", "test-cmd"},
+		registry,
+		io.Discard,
+		WithEventWriter(&events),
+		WithErrorWriter(io.Discard),
+		WithoutSignalHandling(),
+	)
+
+	var decoded []executionEvent
+	for _, line := range strings.Split(strings.TrimSpace(events.String()), "\n") {
+		var event executionEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("event %q is not valid json: %v", line, err)
+		}
+		decoded = append(decoded, event)
+	}
+
+	if len(decoded) != 2 || decoded[0].Event != EventCommandStart || decoded[1].Event != EventCommandEnd {
+		t.Fatalf("events = %s, want a command_start and a command_end event", events.String())
+	}
+	end := decoded[1]
+	if end.ExitCode == nil || *end.ExitCode != StatusUsage || !strings.Contains(end.Error, "failed to parse global flags") {
+		t.Errorf("command_end event = %+v, want the usage exit code and the parse error", end)
+	}
+}
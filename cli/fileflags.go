@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// FileFlagsCommand is an optional interface for commands accepting large flag values,
+// like a request body, read from a file with the "@path" syntax, as in
+// "--body @payload.json", "@-" reading the value from the command input (os.Stdin by
+// default). FileFlags returns the names of the flags supporting it. Other flags, and the
+// flags of commands not implementing it, keep values starting with "@" as is.
+type FileFlagsCommand interface {
+	Command
+	FileFlags() []string
+}
+
+// ExpandFileFlags replaces the values of the named flags starting with "@" with the
+// content of the file they point to, "@-" reading it from stdin. Only the flags explicitly
+// set are expanded. The content is used verbatim, trailing newline included.
+// runCommand calls it automatically, before ValidateFlags, for commands implementing
+// FileFlagsCommand. It returns an error if a file cannot be read or its content is not a
+// valid value of the flag.
+func ExpandFileFlags(flagSet *flag.FlagSet, stdin io.Reader, names ...string) error {
+	var expandable []string
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			name := aliasTarget(setFlag)
+			if slices.Contains(names, name) && !slices.Contains(expandable, name) {
+				expandable = append(expandable, name)
+			}
+		},
+	)
+
+	for _, name := range expandable {
+		path, isFileRef := strings.CutPrefix(flagSet.Lookup(name).Value.String(), "@")
+		if !isFileRef {
+			continue
+		}
+
+		content, err := readFlagFile(path, stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read the value of flag --%s: %w", name, err)
+		}
+		if err = flagSet.Set(name, string(content)); err != nil {
+			return fmt.Errorf("invalid value for flag --%s read from %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// readFlagFile reads the file at the given path, or stdin when the path is "-"
+func readFlagFile(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return content, nil
+	}
+	if path == "" {
+		return nil, fmt.Errorf("missing file path after '@'")
+	}
+	return os.ReadFile(path)
+}
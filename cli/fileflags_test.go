@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestItCanExpandFileFlags(t *testing.T) {
+	payloadPath := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(payloadPath, []byte(`{"name": "john"}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantBody string
+		wantTag  string
+		wantErr  string
+	}{
+		{
+			name:     "literal value",
+			args:     []string{"--body", "plain"},
+			wantBody: "plain",
+		},
+		{
+			name:     "file value",
+			args:     []string{"--body", "@" + payloadPath},
+			wantBody: `{"name": "john"}`,
+		},
+		{
+			name:     "stdin value",
+			args:     []string{"--body", "@-"},
+			stdin:    "from stdin\n",
+			wantBody: "from stdin\n",
+		},
+		{
+			name:     "flag without file support keeps the @ value",
+			args:     []string{"--body", "plain", "--tag", "@" + payloadPath},
+			wantBody: "plain",
+			wantTag:  "@" + payloadPath,
+		},
+		{
+			name:    "missing file",
+			args:    []string{"--body", "@" + filepath.Join(t.TempDir(), "missing.json")},
+			wantErr: "failed to read the value of flag --body",
+		},
+		{
+			name:    "missing path",
+			args:    []string{"--body", "@"},
+			wantErr: "missing file path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				body := flagSet.String("body", "", "The request body")
+				tag := flagSet.String("tag", "", "The tag")
+				if err := flagSet.Parse(tt.args); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				err := ExpandFileFlags(flagSet, strings.NewReader(tt.stdin), "body")
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("ExpandFileFlags() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ExpandFileFlags() error = %v, want nil", err)
+				}
+				if *body != tt.wantBody || *tag != tt.wantTag {
+					t.Errorf(
+						"flags = (%q, %q), want (%q, %q)",
+						*body, *tag, tt.wantBody, tt.wantTag,
+					)
+				}
+			},
+		)
+	}
+}
+
+// MockFileFlagsCommand is a FileFlagsCommand implementation for testing
+type MockFileFlagsCommand struct {
+	MockCommand
+	body        string
+	bodyAtValid string
+}
+
+func (m *MockFileFlagsCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&m.body, "body", "", "The request body")
+}
+
+func (m *MockFileFlagsCommand) ValidateFlags() error {
+	m.bodyAtValid = m.body
+	return nil
+}
+
+func (m *MockFileFlagsCommand) FileFlags() []string {
+	return []string{"body"}
+}
+
+func TestItExpandsFileFlagsBeforeValidatingThem(t *testing.T) {
+	cmd := &MockFileFlagsCommand{MockCommand: MockCommand{id: "post"}}
+	ctx := withInput(context.Background(), strings.NewReader("piped body"))
+
+	err := runCommand(ctx, cmd, []string{"--body", "@-"}, io.Discard, io.Discard)
+
+	if err != nil {
+		t.Fatalf("runCommand() error = %v, want nil", err)
+	}
+	if cmd.bodyAtValid != "piped body" {
+		t.Errorf("body at validation = %q, want %q", cmd.bodyAtValid, "piped body")
+	}
+}
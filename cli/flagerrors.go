@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"io"
+)
+
+// flagErrorPolicyKey is the context key of the flag error policy
+type flagErrorPolicyKey struct{}
+
+// flagErrorPolicy controls how the command flag sets handle parse errors, see
+// WithFlagErrorHandling and WithSilentFlagErrors
+type flagErrorPolicy struct {
+	handling flag.ErrorHandling
+	silent   bool
+}
+
+// apply sets the error handling of the flag set and, in silent mode, discards what the
+// flag package prints: parse errors, usages and help requests
+func (p flagErrorPolicy) apply(flagSet *flag.FlagSet) {
+	flagSet.Init(flagSet.Name(), p.handling)
+	if p.silent {
+		flagSet.SetOutput(io.Discard)
+	}
+}
+
+// withFlagErrorPolicy returns a context holding the flag error policy
+func withFlagErrorPolicy(ctx context.Context, policy flagErrorPolicy) context.Context {
+	return context.WithValue(ctx, flagErrorPolicyKey{}, policy)
+}
+
+// flagErrorPolicyFrom returns the flag error policy of the context, flag.ContinueOnError
+// without silent mode by default
+func flagErrorPolicyFrom(ctx context.Context) flagErrorPolicy {
+	policy, _ := ctx.Value(flagErrorPolicyKey{}).(flagErrorPolicy)
+	return policy
+}
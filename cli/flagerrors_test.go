@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestItCanSilenceFlagErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		opts         []BootstrapOption
+		wantExitCode int
+		wantErr      string
+		wantOutput   []string
+		unwanted     []string
+	}{
+		{
+			name:         "parse errors are printed with the usage by default",
+			args:         []string{"greet", "--unknown"},
+			wantExitCode: StatusUsage,
+			wantErr:      "flag provided but not defined: -unknown",
+			wantOutput:   []string{"flag provided but not defined: -unknown", "Usage of greet:"},
+			unwanted:     []string{"Failed to execute command"},
+		},
+		{
+			name:         "silent parse errors are reported once, without the usage",
+			args:         []string{"greet", "--unknown"},
+			opts:         []BootstrapOption{WithSilentFlagErrors()},
+			wantExitCode: StatusUsage,
+			wantErr:      "flag provided but not defined: -unknown",
+			wantOutput:   []string{"Failed to execute command greet with error: flag provided"},
+			unwanted:     []string{"Usage of greet:"},
+		},
+		{
+			name:         "silent and quiet parse errors",
+			args:         []string{"greet", "--test-flag"},
+			opts:         []BootstrapOption{WithSilentFlagErrors(), WithQuiet(true)},
+			wantExitCode: StatusUsage,
+			wantErr:      "flag needs an argument: -test-flag",
+		},
+		{
+			name:         "silent and quiet validation errors",
+			args:         []string{"invalid", "--test-flag", "value"},
+			opts:         []BootstrapOption{WithSilentFlagErrors(), WithQuiet(true)},
+			wantExitCode: StatusErr,
+			wantErr:      "invalid test flag",
+		},
+		{
+			name:         "silent global flag errors are reported once, without the usage",
+			args:         []string{"--timeout", "soon", "greet"},
+			opts:         []BootstrapOption{WithSilentFlagErrors()},
+			wantExitCode: StatusUsage,
+			wantErr:      "failed to parse global flags",
+			wantOutput:   []string{"Failed to parse global flags with error"},
+			unwanted:     []string{"Usage of global flags:"},
+		},
+		{
+			name:         "silent help request",
+			args:         []string{"greet", "--help"},
+			opts:         []BootstrapOption{WithSilentFlagErrors()},
+			wantExitCode: StatusOk,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(&MockCommandWithFlags{id: "greet"})
+				_ = registry.Register(
+					&MockCommandWithFlags{id: "invalid", validateErr: errors.New("invalid test flag")},
+				)
+				var output, errOutput bytes.Buffer
+				opts := append([]BootstrapOption{WithErrorWriter(&errOutput), WithoutSignalHandling()}, tt.opts...)
+
+				exitCode, err := Run(tt.args, registry, &output, opts...)
+
+				if exitCode != tt.wantExitCode {
+					t.Errorf("Run() exit code = %d, want %d", exitCode, tt.wantExitCode)
+				}
+				if tt.wantErr == "" && err != nil {
+					t.Errorf("Run() error = %v, want nil", err)
+				}
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Errorf("Run() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+
+				written := output.String() + errOutput.String()
+				if len(tt.wantOutput) == 0 && written != "" {
+					t.Errorf("Run() output = %q, want none", written)
+				}
+				for _, want := range tt.wantOutput {
+					if !strings.Contains(written, want) {
+						t.Errorf("Run() output should contain %q:\n%s", want, written)
+					}
+				}
+				for _, unwanted := range tt.unwanted {
+					if strings.Contains(written, unwanted) {
+						t.Errorf("Run() output should not contain %q:\n%s", unwanted, written)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItCanSetTheFlagErrorHandling(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommandWithFlags{id: "greet"})
+
+	result := RunWithResult(
+		[]string{"greet", "--unknown"},
+		registry,
+		&bytes.Buffer{},
+		WithErrorWriter(&bytes.Buffer{}),
+		WithoutSignalHandling(),
+		WithFlagErrorHandling(flag.PanicOnError),
+	)
+
+	var panicErr *PanicError
+	if !result.Panicked || !errors.As(result.Err, &panicErr) {
+		t.Errorf("RunWithResult() = %+v, want a PanicError", result)
+	}
+}
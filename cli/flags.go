@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FlagSetValidator is an optional interface for commands whose flag validation needs the
+// parsed flag set, for example to check if a flag was explicitly set with flagSet.Visit,
+// to enforce mutually exclusive or conditionally required flags. When a command
+// implements it, ValidateFlagSet is called instead of ValidateFlags.
+type FlagSetValidator interface {
+	Command
+	ValidateFlagSet(flagSet *flag.FlagSet) error
+}
+
+// validateFlags validates the parsed flags, preferring FlagSetValidator over ValidateFlags
+func validateFlags(cmd Command, flagSet *flag.FlagSet) error {
+	if validator, ok := findOptional[FlagSetValidator](cmd); ok {
+		return validator.ValidateFlagSet(flagSet)
+	}
+	return cmd.ValidateFlags()
+}
+
+// reportInvalidFlags writes the validation error followed by the command usage to the
+// flag set output
+func reportInvalidFlags(flagSet *flag.FlagSet, err error) {
+	_, _ = fmt.Fprintln(flagSet.Output(), err)
+	flagSet.Usage()
+}
+
+// requiredFlagValue wraps the flag.Value of a flag marked as required
+type requiredFlagValue struct {
+	flag.Value
+}
+
+func (v *requiredFlagValue) String() string {
+	// The flag package calls String on zero values to detect default values
+	if v == nil || v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+// IsBoolFlag preserves the boolean flag behaviour (e.g. "-verbose" without a value)
+func (v *requiredFlagValue) IsBoolFlag() bool {
+	boolFlag, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// Get preserves the flag.Getter behaviour of the wrapped value
+func (v *requiredFlagValue) Get() any {
+	if getter, ok := v.Value.(flag.Getter); ok {
+		return getter.Get()
+	}
+	return v.Value.String()
+}
+
+// printDefaults prints the flag usages like flagSet.PrintDefaults does. The flag package
+// only names the type of its own values, so required flags are unwrapped while printing,
+// to be listed as "-name string" rather than "-name value".
+func printDefaults(flagSet *flag.FlagSet) {
+	var requiredFlags []*flag.Flag
+	flagSet.VisitAll(
+		func(definedFlag *flag.Flag) {
+			if required, isRequired := definedFlag.Value.(*requiredFlagValue); isRequired {
+				definedFlag.Value = required.Value
+				requiredFlags = append(requiredFlags, definedFlag)
+			}
+		},
+	)
+	defer func() {
+		for _, requiredFlag := range requiredFlags {
+			requiredFlag.Value = &requiredFlagValue{requiredFlag.Value}
+		}
+	}()
+
+	flagSet.PrintDefaults()
+}
+
+// MarkRequired marks the given, already defined, flags as required. Call it from
+// DefineFlags after defining the flags. Missing required flags are reported by
+// CheckRequired, which runCommand calls automatically before ValidateFlags.
+// It panics if a flag is not defined, like the flag package does on redefinition.
+func MarkRequired(flagSet *flag.FlagSet, names ...string) {
+	for _, name := range names {
+		definedFlag := flagSet.Lookup(name)
+		if definedFlag == nil {
+			panic(fmt.Sprintf("cannot mark undefined flag %q as required", name))
+		}
+		if _, alreadyRequired := definedFlag.Value.(*requiredFlagValue); !alreadyRequired {
+			definedFlag.Value = &requiredFlagValue{definedFlag.Value}
+		}
+	}
+}
+
+// enumValue is the flag.Value of a flag restricted to a set of allowed values
+type enumValue struct {
+	target  *string
+	allowed []string
+}
+
+func (v *enumValue) String() string {
+	// The flag package calls String on zero values to detect default values
+	if v == nil || v.target == nil {
+		return ""
+	}
+	return *v.target
+}
+
+func (v *enumValue) Set(value string) error {
+	if !slices.Contains(v.allowed, value) {
+		return fmt.Errorf("must be one of %s", strings.Join(v.allowed, ", "))
+	}
+	*v.target = value
+	return nil
+}
+
+// Get implements flag.Getter, like the flag package string values
+func (v *enumValue) Get() any {
+	return *v.target
+}
+
+// EnumVar defines a string flag accepting only the allowed values, like
+// "--format json|text|yaml", storing the value into target. Other values are rejected
+// while parsing, with an error listing the valid choices, and the help output lists them
+// next to the flag. The default value may be empty, to tell whether the flag was given.
+// It panics if a non-empty default is not allowed, as it is a programming error.
+func EnumVar(
+	flagSet *flag.FlagSet,
+	target *string,
+	name string,
+	allowed []string,
+	def string,
+	usage string,
+) {
+	if def != "" && !slices.Contains(allowed, def) {
+		panic(fmt.Sprintf("default %q of flag %q is not one of %s", def, name, strings.Join(allowed, ", ")))
+	}
+	*target = def
+	flagSet.Var(&enumValue{target: target, allowed: slices.Clone(allowed)}, name, usage)
+}
+
+// flagEnumValues returns the allowed values of a flag defined with EnumVar, nil for other
+// flags
+func flagEnumValues(definedFlag *flag.Flag) []string {
+	value := definedFlag.Value
+	if required, isRequired := value.(*requiredFlagValue); isRequired {
+		value = required.Value
+	}
+	if enum, isEnum := value.(*enumValue); isEnum {
+		return enum.allowed
+	}
+	return nil
+}
+
+// flagAliasValue is the flag.Value of an alias flag, reading and setting the value of
+// its target flag
+type flagAliasValue struct {
+	target *flag.Flag
+}
+
+func (v *flagAliasValue) String() string {
+	// The flag package calls String on zero values to detect default values
+	if v == nil || v.target == nil {
+		return ""
+	}
+	return v.target.Value.String()
+}
+
+func (v *flagAliasValue) Set(value string) error {
+	return v.target.Value.Set(value)
+}
+
+// IsBoolFlag preserves the boolean flag behaviour of the target (e.g. "-v" without a value)
+func (v *flagAliasValue) IsBoolFlag() bool {
+	boolFlag, ok := v.target.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// Get preserves the flag.Getter behaviour of the target value
+func (v *flagAliasValue) Get() any {
+	if getter, ok := v.target.Value.(flag.Getter); ok {
+		return getter.Get()
+	}
+	return v.target.Value.String()
+}
+
+// AliasFlag defines aliases of an already defined flag, like a short "n" form of "name",
+// reading and setting the same value, so that both "-n john" and "--name john" set the
+// name. When several forms are given, the last one wins, like when a flag is repeated.
+// Call it from DefineFlags after defining the flag. Aliases count as their target for
+// required flags and MutuallyExclusive checks, and are listed along with their target in
+// the help output. It panics if the flag is not defined, like MarkRequired does.
+func AliasFlag(flagSet *flag.FlagSet, name string, aliases ...string) {
+	target := flagSet.Lookup(name)
+	if target == nil {
+		panic(fmt.Sprintf("cannot alias undefined flag %q", name))
+	}
+	for _, alias := range aliases {
+		flagSet.Var(&flagAliasValue{target: target}, alias, "Alias of --"+name)
+	}
+}
+
+// aliasTarget returns the name of the flag aliased by the given flag, or the flag name
+// itself if it is not an alias
+func aliasTarget(definedFlag *flag.Flag) string {
+	if alias, isAlias := definedFlag.Value.(*flagAliasValue); isAlias {
+		return alias.target.Name
+	}
+	return definedFlag.Name
+}
+
+// isFlagAlias reports whether the flag was defined with AliasFlag
+func isFlagAlias(definedFlag *flag.Flag) bool {
+	_, isAlias := definedFlag.Value.(*flagAliasValue)
+	return isAlias
+}
+
+// flagAliases returns the aliases of the flags of the flag set, keyed by the name of the
+// aliased flag
+func flagAliases(flagSet *flag.FlagSet) map[string][]string {
+	aliases := make(map[string][]string)
+	flagSet.VisitAll(
+		func(definedFlag *flag.Flag) {
+			if isFlagAlias(definedFlag) {
+				target := aliasTarget(definedFlag)
+				aliases[target] = append(aliases[target], definedFlag.Name)
+			}
+		},
+	)
+	return aliases
+}
+
+// isRequiredFlag reports whether the flag was marked as required with MarkRequired
+func isRequiredFlag(definedFlag *flag.Flag) bool {
+	_, required := definedFlag.Value.(*requiredFlagValue)
+	return required
+}
+
+// CheckRequired returns an error listing all flags marked as required which were not
+// explicitly set while parsing, or nil if all of them were provided
+func CheckRequired(flagSet *flag.FlagSet) error {
+	setFlags := make(map[string]bool)
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			setFlags[aliasTarget(setFlag)] = true
+		},
+	)
+
+	var missing []string
+	flagSet.VisitAll(
+		func(definedFlag *flag.Flag) {
+			if isRequiredFlag(definedFlag) && !setFlags[definedFlag.Name] {
+				missing = append(missing, "--"+definedFlag.Name)
+			}
+		},
+	)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required flags: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// MutuallyExclusive returns an error naming the conflicting flags when more than one of
+// the named flags was explicitly set while parsing, or nil otherwise. Call it from
+// ValidateFlagSet (see FlagSetValidator), or from ValidateFlags with a flag set stored
+// in DefineFlags.
+func MutuallyExclusive(flagSet *flag.FlagSet, names ...string) error {
+	var conflicting []string
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			name := aliasTarget(setFlag)
+			if slices.Contains(names, name) && !slices.Contains(conflicting, "--"+name) {
+				conflicting = append(conflicting, "--"+name)
+			}
+		},
+	)
+
+	if len(conflicting) > 1 {
+		return fmt.Errorf(
+			"flags %s are mutually exclusive, only one of them can be set",
+			strings.Join(conflicting, ", "),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+// MockCommandWithRequiredFlags is a Command implementation with required flags for testing
+type MockCommandWithRequiredFlags struct {
+	MockCommand
+	name    string
+	verbose bool
+}
+
+func (m *MockCommandWithRequiredFlags) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&m.name, "name", "", "The name")
+	flagSet.BoolVar(&m.verbose, "verbose", false, "Verbose output")
+	flagSet.Int("count", 1, "The count")
+	MarkRequired(flagSet, "name", "verbose")
+}
+
+func TestItCanCheckRequiredFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantErr     bool
+		wantMissing []string
+	}{
+		{
+			name:        "all missing",
+			args:        []string{"--count", "2"},
+			wantErr:     true,
+			wantMissing: []string{"--name", "--verbose"},
+		},
+		{
+			name:        "one missing",
+			args:        []string{"--name", "john"},
+			wantErr:     true,
+			wantMissing: []string{"--verbose"},
+		},
+		{
+			name:    "all provided, bool flag without value",
+			args:    []string{"--name", "john", "--verbose"},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockCommandWithRequiredFlags{}
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				cmd.DefineFlags(flagSet)
+				if err := flagSet.Parse(tt.args); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				err := CheckRequired(flagSet)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("CheckRequired() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				for _, missing := range tt.wantMissing {
+					if !strings.Contains(err.Error(), missing) {
+						t.Errorf("CheckRequired() error %q should name %s", err, missing)
+					}
+				}
+				if !tt.wantErr && (cmd.name != "john" || !cmd.verbose) {
+					t.Errorf("Required flags were not bound, got name=%q verbose=%v", cmd.name, cmd.verbose)
+				}
+			},
+		)
+	}
+}
+
+func TestItPanicsWhenMarkingAnUndefinedFlagAsRequired(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MarkRequired() should panic for an undefined flag")
+		}
+	}()
+	MarkRequired(flag.NewFlagSet("test", flag.ContinueOnError), "undefined")
+}
+
+func TestRunCommandReportsMissingRequiredFlagsWithUsage(t *testing.T) {
+	executed := false
+	cmd := &MockCommandWithRequiredFlags{
+		MockCommand: MockCommand{
+			id: "required-cmd",
+			execFunc: func(writer io.Writer) error {
+				executed = true
+				return nil
+			},
+		},
+	}
+
+	var outBuf, errBuf bytes.Buffer
+	err := runCommand(context.Background(), cmd, []string{}, &outBuf, &errBuf)
+	if err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Fatalf("runCommand() error = %v, want missing required flags error", err)
+	}
+	if executed {
+		t.Errorf("runCommand() should not execute a command with missing required flags")
+	}
+	wantUsage := "Usage of required-cmd:\n" +
+		"  -count int\n    \tThe count (default 1)\n" +
+		"  -name string\n    \tThe name\n" +
+		"  -verbose\n    \tVerbose output\n"
+	if !strings.HasSuffix(errBuf.String(), wantUsage) {
+		t.Errorf("runCommand() usage = %q, want %q", errBuf.String(), wantUsage)
+	}
+
+	// Flag sets printed directly must not panic on the required flags either
+	flagSet := flag.NewFlagSet("required-cmd", flag.ContinueOnError)
+	var defaultsBuf bytes.Buffer
+	flagSet.SetOutput(&defaultsBuf)
+	cmd.DefineFlags(flagSet)
+	flagSet.PrintDefaults()
+	if strings.Contains(defaultsBuf.String(), "panic") {
+		t.Errorf("PrintDefaults() output = %q, want no panic", defaultsBuf.String())
+	}
+
+	var helpBuf bytes.Buffer
+	_ = NewHelpCommand([]Command{cmd}).Exec(&helpBuf)
+	if !strings.Contains(helpBuf.String(), "--name (required)") {
+		t.Errorf("Help output should mark required flags, got %s", helpBuf.String())
+	}
+}
+
+// MockFlagSetValidatorCommand is a FlagSetValidator implementation with mutually
+// exclusive flags for testing
+type MockFlagSetValidatorCommand struct {
+	MockCommand
+}
+
+func (m *MockFlagSetValidatorCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.String("file", "", "Read from a file")
+	flagSet.Bool("stdin", false, "Read from stdin")
+}
+
+func (m *MockFlagSetValidatorCommand) ValidateFlags() error {
+	return errors.New("ValidateFlags should not be called")
+}
+
+func (m *MockFlagSetValidatorCommand) ValidateFlagSet(flagSet *flag.FlagSet) error {
+	return MutuallyExclusive(flagSet, "file", "stdin")
+}
+
+func TestItValidatesFlagsWithTheFlagSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "no flags",
+			args:    []string{},
+			wantErr: false,
+		},
+		{
+			name:    "one of the exclusive flags",
+			args:    []string{"--stdin"},
+			wantErr: false,
+		},
+		{
+			name:    "both exclusive flags",
+			args:    []string{"--file", "a.txt", "--stdin"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := NewLockableCommand(
+					&MockFlagSetValidatorCommand{MockCommand{id: "read"}},
+					t.TempDir(),
+				)
+
+				err := runCommand(context.Background(), cmd, tt.args, io.Discard, io.Discard)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanDetectMutuallyExclusiveFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name: "no flag set",
+			args: []string{},
+		},
+		{
+			name: "one flag set",
+			args: []string{"--json"},
+		},
+		{
+			name: "one flag and an unrelated flag set",
+			args: []string{"--json", "--verbose"},
+		},
+		{
+			name:    "two flags set",
+			args:    []string{"--quiet", "--json"},
+			wantErr: "flags --json, --quiet are mutually exclusive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				flagSet.Bool("json", false, "Json output")
+				flagSet.Bool("quiet", false, "No output")
+				flagSet.Bool("verbose", false, "Verbose output")
+				if err := flagSet.Parse(tt.args); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				err := MutuallyExclusive(flagSet, "json", "quiet")
+				if tt.wantErr == "" && err != nil {
+					t.Errorf("MutuallyExclusive() error = %v, want nil", err)
+				}
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Errorf("MutuallyExclusive() error = %v, want %q", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+// MockAliasedFlagsCommand is a Command implementation with short flag aliases for testing
+type MockAliasedFlagsCommand struct {
+	MockCommand
+	name    string
+	verbose bool
+}
+
+func (m *MockAliasedFlagsCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&m.name, "name", "", "The name")
+	flagSet.BoolVar(&m.verbose, "verbose", false, "Verbose output")
+	MarkRequired(flagSet, "name")
+	AliasFlag(flagSet, "name", "n")
+	AliasFlag(flagSet, "verbose", "v")
+}
+
+func TestItCanAliasFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantName    string
+		wantVerbose bool
+		wantErr     string
+	}{
+		{
+			name:     "long form",
+			args:     []string{"--name", "john"},
+			wantName: "john",
+		},
+		{
+			name:        "short form",
+			args:        []string{"-n", "john", "-v"},
+			wantName:    "john",
+			wantVerbose: true,
+		},
+		{
+			name:     "both forms, the last one wins",
+			args:     []string{"--name", "john", "-n", "jane"},
+			wantName: "jane",
+		},
+		{
+			name:    "required flag missing in both forms",
+			args:    []string{"-v"},
+			wantErr: "missing required flags: --name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &MockAliasedFlagsCommand{}
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				flagSet.SetOutput(io.Discard)
+				cmd.DefineFlags(flagSet)
+				if err := flagSet.Parse(tt.args); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				err := CheckRequired(flagSet)
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("CheckRequired() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Errorf("CheckRequired() error = %v, want nil", err)
+				}
+				if cmd.name != tt.wantName || cmd.verbose != tt.wantVerbose {
+					t.Errorf(
+						"flags = (%q, %v), want (%q, %v)",
+						cmd.name, cmd.verbose, tt.wantName, tt.wantVerbose,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestItPanicsWhenAliasingAnUndefinedFlag(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("AliasFlag() should panic for an undefined flag")
+		}
+	}()
+
+	AliasFlag(flag.NewFlagSet("test", flag.ContinueOnError), "undefined", "u")
+}
+
+func TestItTreatsFlagAliasesAsTheirTargetWhenCheckingExclusiveFlags(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.Bool("json", false, "Json output")
+	flagSet.Bool("quiet", false, "No output")
+	AliasFlag(flagSet, "json", "j")
+	AliasFlag(flagSet, "quiet", "q")
+	if err := flagSet.Parse([]string{"-j", "--json", "-q"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	err := MutuallyExclusive(flagSet, "json", "quiet")
+
+	if err == nil || !strings.HasPrefix(err.Error(), "flags --json, --quiet are mutually exclusive") {
+		t.Errorf("MutuallyExclusive() error = %v, want the json and quiet flags conflict", err)
+	}
+}
+
+func TestItDescribesAndListsFlagAliasesInHelp(t *testing.T) {
+	cmd := &MockAliasedFlagsCommand{MockCommand: MockCommand{id: "greet", description: "Greets"}}
+
+	info := Describe(cmd)
+	if len(info.Flags) != 2 {
+		t.Fatalf("Describe() flags = %+v, want the name and verbose flags only", info.Flags)
+	}
+	for _, flagInfo := range info.Flags {
+		if flagInfo.Name == "name" && (len(flagInfo.Aliases) != 1 || flagInfo.Aliases[0] != "n") {
+			t.Errorf("Describe() name aliases = %v, want [n]", flagInfo.Aliases)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := runCommand(context.Background(), NewHelpCommand([]Command{cmd}), nil, &buf, &buf); err != nil {
+		t.Fatalf("HelpCommand error = %v, want nil", err)
+	}
+
+	if !strings.Contains(buf.String(), "--name, -n") || strings.Contains(buf.String(), "Alias of") {
+		t.Errorf("help output = %q, want the name flag listed as %q", buf.String(), "--name, -n")
+	}
+}
+
+func TestItCanRestrictFlagsToEnumValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantFormat string
+		wantErr    string
+	}{
+		{
+			name:       "default value",
+			args:       []string{},
+			wantFormat: "text",
+		},
+		{
+			name:       "valid value",
+			args:       []string{"--format", "yaml"},
+			wantFormat: "yaml",
+		},
+		{
+			name:    "invalid value",
+			args:    []string{"--format=xml"},
+			wantErr: `invalid value "xml" for flag -format: must be one of json, text, yaml`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+				flagSet.SetOutput(io.Discard)
+				var format string
+				EnumVar(flagSet, &format, "format", []string{"json", "text", "yaml"}, "text", "The format")
+
+				err := flagSet.Parse(tt.args)
+
+				if tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Parse() error = %v, want nil", err)
+				}
+				if format != tt.wantFormat {
+					t.Errorf("format = %q, want %q", format, tt.wantFormat)
+				}
+			},
+		)
+	}
+}
+
+func TestItPanicsWhenTheEnumDefaultIsNotAllowed(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("EnumVar() should panic for a default which is not allowed")
+		}
+	}()
+
+	var format string
+	EnumVar(flag.NewFlagSet("test", flag.ContinueOnError), &format, "format", []string{"json"}, "xml", "")
+}
+
+// MockEnumFlagsCommand is a Command implementation with an enum flag for testing
+type MockEnumFlagsCommand struct {
+	MockCommand
+	format string
+}
+
+func (m *MockEnumFlagsCommand) DefineFlags(flagSet *flag.FlagSet) {
+	EnumVar(flagSet, &m.format, "format", []string{"json", "text"}, "text", "The output format")
+}
+
+func TestItListsTheEnumValuesInHelp(t *testing.T) {
+	cmd := &MockEnumFlagsCommand{MockCommand: MockCommand{id: "report"}}
+
+	info := Describe(cmd)
+	if len(info.Flags) != 1 || strings.Join(info.Flags[0].Enum, ",") != "json,text" {
+		t.Errorf("Describe() flags = %+v, want the format flag with its enum values", info.Flags)
+	}
+
+	var buf bytes.Buffer
+	if err := runCommand(context.Background(), NewHelpCommand([]Command{cmd}), nil, &buf, &buf); err != nil {
+		t.Fatalf("HelpCommand error = %v, want nil", err)
+	}
+	if !strings.Contains(buf.String(), "--format (default text, one of json, text)") {
+		t.Errorf("help output = %q, want the allowed values of the format flag", buf.String())
+	}
+}
@@ -0,0 +1,27 @@
+package cli
+
+import "io"
+
+// Flusher is implemented by writers buffering their output, like bufio.Writer or
+// tabwriter.Writer, which only write it once Flush is called or their buffer fills.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush writes the output buffered by the writer, if it implements Flusher, for example
+// from a progress-heavy command wanting its output to show up right after key
+// milestones, even when Bootstrap was given a buffered output writer. The writers of the
+// framework, like the tee writer capturing the output, are looked through. Flushing a
+// writer which does not buffer its output, like os.Stdout, is a no-op returning nil.
+func Flush(writer io.Writer) error {
+	for {
+		switch w := writer.(type) {
+		case Flusher:
+			return w.Flush()
+		case *teeWriter:
+			writer = w.live
+		default:
+			return nil
+		}
+	}
+}
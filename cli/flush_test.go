@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// MockFlushWriter is a Flusher implementation for testing
+type MockFlushWriter struct {
+	bytes.Buffer
+	flushes  int
+	flushErr error
+}
+
+func (m *MockFlushWriter) Flush() error {
+	m.flushes++
+	return m.flushErr
+}
+
+func TestItCanFlushBufferedWriters(t *testing.T) {
+	flushErr := errors.New("disk full")
+
+	tests := []struct {
+		name        string
+		flushWriter *MockFlushWriter
+		writer      func(flushWriter *MockFlushWriter) io.Writer
+		wantFlushes int
+		wantErr     error
+	}{
+		{
+			name:        "flushable writer",
+			flushWriter: &MockFlushWriter{},
+			writer: func(flushWriter *MockFlushWriter) io.Writer {
+				return flushWriter
+			},
+			wantFlushes: 1,
+		},
+		{
+			name:        "flushable writer behind a tee writer",
+			flushWriter: &MockFlushWriter{},
+			writer: func(flushWriter *MockFlushWriter) io.Writer {
+				return NewTeeWriter(flushWriter, &bytes.Buffer{})
+			},
+			wantFlushes: 1,
+		},
+		{
+			name:        "failing flush",
+			flushWriter: &MockFlushWriter{flushErr: flushErr},
+			writer: func(flushWriter *MockFlushWriter) io.Writer {
+				return flushWriter
+			},
+			wantFlushes: 1,
+			wantErr:     flushErr,
+		},
+		{
+			name:        "writer which does not buffer",
+			flushWriter: &MockFlushWriter{},
+			writer: func(flushWriter *MockFlushWriter) io.Writer {
+				return &bytes.Buffer{}
+			},
+			wantFlushes: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := Flush(tt.writer(tt.flushWriter))
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Flush() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.flushWriter.flushes != tt.wantFlushes {
+					t.Errorf("Flush() flushes = %d, want %d", tt.flushWriter.flushes, tt.wantFlushes)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanFlushTheOutputOfARunningCommand(t *testing.T) {
+	var out bytes.Buffer
+	bufferedOut := bufio.NewWriter(&out)
+	var outputAtMilestone string
+
+	cmd := &MockCommand{
+		id: "import",
+		execFunc: func(writer io.Writer) error {
+			_, _ = io.WriteString(writer, "Imported 100 rows\n")
+			if err := Flush(writer); err != nil {
+				return err
+			}
+			outputAtMilestone = out.String()
+			_, _ = io.WriteString(writer, "Imported 200 rows\n")
+			return nil
+		},
+	}
+
+	if err := runCommand(context.Background(), cmd, nil, NewTeeWriter(bufferedOut), io.Discard); err != nil {
+		t.Fatalf("runCommand() error = %v, want nil", err)
+	}
+
+	if outputAtMilestone != "Imported 100 rows\n" {
+		t.Errorf("Output after the flush = %q, want %q", outputAtMilestone, "Imported 100 rows\n")
+	}
+	if out.String() != "Imported 100 rows\n" {
+		t.Errorf("Output = %q, want the output written after the flush to stay buffered", out.String())
+	}
+}
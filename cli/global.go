@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DryRunFlagName is the name of the built-in global flag enabling the dry-run mode
+const DryRunFlagName = "dry-run"
+
+// TimeoutFlagName is the name of the built-in global flag setting the command timeout
+const TimeoutFlagName = "timeout"
+
+// QuietFlagName is the name of the built-in global flag enabling the quiet mode
+const QuietFlagName = "quiet"
+
+// VerboseFlagName is the name of the built-in global flag enabling verbose error reporting
+const VerboseFlagName = "verbose"
+
+// globalFlagsKey is the context key of the parsed global flag set
+type globalFlagsKey struct{}
+
+// dryRunKey is the context key of the dry-run mode
+type dryRunKey struct{}
+
+// timeoutKey is the context key of the command timeout given via the global flag
+type timeoutKey struct{}
+
+// quietKey is the context key of the quiet mode
+type quietKey struct{}
+
+// splitGlobalFlagArgs splits the args into the leading global flags, with their values,
+// and the args following them. The split happens at the first arg which is not a global
+// flag, like a command flag, a command id or a positional arg, or right after a "--"
+// terminator. The -h and --help flags are global, listing the global flags.
+func splitGlobalFlagArgs(flagSet *flag.FlagSet, args []string) (globalArgs []string, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[:i+1], args[i+1:]
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return args[:i], args[i:]
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name == "h" || name == "help" {
+			continue
+		}
+		definedFlag := flagSet.Lookup(name)
+		if definedFlag == nil {
+			return args[:i], args[i:]
+		}
+
+		// Non-boolean flags given without "=" take the next arg as their value
+		boolFlag, isBool := definedFlag.Value.(interface{ IsBoolFlag() bool })
+		if !hasValue && !(isBool && boolFlag.IsBoolFlag()) {
+			i++
+		}
+	}
+	return args, nil
+}
+
+// newGlobalFlagSet creates the flag set of the global flags, which are parsed from the
+// args before the command id
+func newGlobalFlagSet(defineFlags []func(flagSet *flag.FlagSet), errWriter io.Writer) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("global", flag.ContinueOnError)
+	flagSet.SetOutput(errWriter)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintln(errWriter, "Usage of global flags:")
+		flagSet.PrintDefaults()
+	}
+
+	for _, define := range defineFlags {
+		if define != nil {
+			define(flagSet)
+		}
+	}
+
+	// Defined after the caller flags, which may already define flags with these names
+	if flagSet.Lookup(DryRunFlagName) == nil {
+		flagSet.Bool(
+			DryRunFlagName,
+			false,
+			"Preview what the command would do, without executing its side effects",
+		)
+	}
+	if flagSet.Lookup(TimeoutFlagName) == nil {
+		flagSet.Duration(
+			TimeoutFlagName,
+			0,
+			"Maximum duration of the command execution (e.g. 30s, 5m), 0 for no timeout",
+		)
+	}
+	if flagSet.Lookup(QuietFlagName) == nil {
+		flagSet.Bool(
+			QuietFlagName,
+			false,
+			"Suppress warnings and failure messages, failures being reported by the exit code only",
+		)
+	}
+	if flagSet.Lookup(VerboseFlagName) == nil {
+		flagSet.Bool(
+			VerboseFlagName,
+			false,
+			"Report failures in detail, including panic stack traces, overriding --quiet",
+		)
+	}
+	if flagSet.Lookup(OutputFlagName) == nil {
+		EnumVar(
+			flagSet,
+			new(string),
+			OutputFlagName,
+			[]string{OutputFormatText, OutputFormatJson, OutputFormatYaml},
+			OutputFormatText,
+			"The output format of the commands supporting it",
+		)
+	}
+
+	return flagSet
+}
+
+// withGlobalFlags returns a context holding the parsed global flag set and the built-in
+// global flag values
+func withGlobalFlags(ctx context.Context, flagSet *flag.FlagSet) context.Context {
+	ctx = context.WithValue(ctx, globalFlagsKey{}, flagSet)
+
+	if dryRun, ok := globalFlagValue[bool](flagSet, DryRunFlagName); ok {
+		ctx = context.WithValue(ctx, dryRunKey{}, dryRun)
+	}
+	if timeout, ok := globalFlagValue[time.Duration](flagSet, TimeoutFlagName); ok {
+		ctx = context.WithValue(ctx, timeoutKey{}, timeout)
+	}
+	if format, ok := globalFlagValue[string](flagSet, OutputFlagName); ok {
+		ctx = context.WithValue(ctx, outputFormatKey{}, format)
+	}
+
+	return ctx
+}
+
+// globalFlagValue returns the typed value of the named flag, if it is defined
+func globalFlagValue[T any](flagSet *flag.FlagSet, name string) (T, bool) {
+	var value T
+	definedFlag := flagSet.Lookup(name)
+	if definedFlag == nil {
+		return value, false
+	}
+
+	getter, ok := definedFlag.Value.(flag.Getter)
+	if !ok {
+		return value, false
+	}
+
+	value, ok = getter.Get().(T)
+	return value, ok
+}
+
+// GlobalFlags returns the parsed global flag set from the context passed to commands
+// (implementing ContextualCommand) and middlewares, or nil if there is none. Use
+// flagSet.Lookup(name) to read a global flag value, or bind the flags to variables in
+// the WithGlobalFlags callback.
+func GlobalFlags(ctx context.Context) *flag.FlagSet {
+	flagSet, _ := ctx.Value(globalFlagsKey{}).(*flag.FlagSet)
+	return flagSet
+}
+
+// IsDryRun reports whether the --dry-run global flag was given, from the context passed to
+// commands (implementing ContextualCommand) and middlewares. The flag only provides the
+// plumbing: enforcing it, by skipping side effects and reporting what would be done, is
+// the responsibility of each command.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// withQuiet returns a context holding the quiet mode
+func withQuiet(ctx context.Context, quiet bool) context.Context {
+	return context.WithValue(ctx, quietKey{}, quiet)
+}
+
+// IsQuiet reports whether the quiet mode is enabled, via the --quiet global flag or the
+// WithQuiet option, from the context passed to commands (implementing ContextualCommand)
+// and middlewares. In quiet mode, Bootstrap does not write warnings and failure messages,
+// commands being expected to only write their essential output.
+func IsQuiet(ctx context.Context) bool {
+	quiet, _ := ctx.Value(quietKey{}).(bool)
+	return quiet
+}
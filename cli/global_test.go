@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestItCanParseGlobalFlagsBeforeTheCommand(t *testing.T) {
+	var globalTestFlag, commandTestFlag, middlewareLogLevel string
+	cmd := &MockCommandWithFlags{id: "test-cmd"}
+	cmd.execFunc = func(writer io.Writer) error {
+		commandTestFlag = cmd.flagSet.Lookup("test-flag").Value.String()
+		return nil
+	}
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(cmd)
+
+	recordingMiddleware := func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, invocation Invocation) error {
+			middlewareLogLevel = invocation.GlobalFlags.Lookup("log-level").Value.String()
+			return next(ctx, invocation)
+		}
+	}
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"--log-level", "debug", "--test-flag", "global", "test-cmd", "--test-flag", "cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithGlobalFlags(
+			func(flagSet *flag.FlagSet) {
+				flagSet.String("log-level", "info", "The log level")
+				flagSet.StringVar(&globalTestFlag, "test-flag", "", "A global test flag")
+			},
+		),
+		WithMiddlewares(recordingMiddleware),
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+	if middlewareLogLevel != "debug" {
+		t.Errorf("Middleware log-level = %v, want debug", middlewareLogLevel)
+	}
+	if globalTestFlag != "global" {
+		t.Errorf("Global test-flag = %v, want global", globalTestFlag)
+	}
+	if commandTestFlag != "cmd" {
+		t.Errorf("Command test-flag = %v, want cmd", commandTestFlag)
+	}
+}
+
+func TestItExposesGlobalFlagsToContextualCommands(t *testing.T) {
+	var verbose string
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockContextCommand{
+			MockCommand: MockCommand{id: "test-cmd"},
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				verbose = GlobalFlags(ctx).Lookup("verbose").Value.String()
+				return nil
+			},
+		},
+	)
+
+	Bootstrap(
+		[]string{"--verbose", "test-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) {},
+		WithGlobalFlags(
+			func(flagSet *flag.FlagSet) {
+				flagSet.Bool("verbose", false, "Verbose output")
+			},
+		),
+	)
+
+	if verbose != "true" {
+		t.Errorf("GlobalFlags(ctx) verbose = %v, want true", verbose)
+	}
+	if GlobalFlags(context.Background()) != nil {
+		t.Errorf("GlobalFlags() should be nil for a context without global flags")
+	}
+}
+
+func TestItCanRedefineTheBuiltInGlobalFlags(t *testing.T) {
+	var dryRun bool
+	var timeout string
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockContextCommand{
+			MockCommand: MockCommand{id: "test-cmd"},
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				dryRun = IsDryRun(ctx)
+				timeout = GlobalFlags(ctx).Lookup(TimeoutFlagName).Value.String()
+				return nil
+			},
+		},
+	)
+
+	exitCode, err := Run(
+		[]string{"--dry-run", "--timeout", "soon", "test-cmd"},
+		registry,
+		io.Discard,
+		WithoutSignalHandling(),
+		WithGlobalFlags(
+			func(flagSet *flag.FlagSet) {
+				flagSet.Bool(DryRunFlagName, false, "Simulate the run")
+				flagSet.String(TimeoutFlagName, "", "When to give up")
+			},
+		),
+	)
+
+	if exitCode != StatusOk || err != nil {
+		t.Fatalf("Run() = %d, %v, want %d, nil", exitCode, err, StatusOk)
+	}
+	if !dryRun {
+		t.Error("IsDryRun() = false, want true for a redefined flag of the same type")
+	}
+	if timeout != "soon" {
+		t.Errorf("Global timeout flag = %q, want soon", timeout)
+	}
+}
+
+func TestItFailsOnInvalidGlobalFlags(t *testing.T) {
+	executed := false
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "test-cmd",
+			execFunc: func(writer io.Writer) error {
+				executed = true
+				return nil
+			},
+		},
+	)
+
+	var errBuf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"--unknown", "test-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
+	)
+
+	if exitCode != StatusUsage {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusUsage)
+	}
+	if executed {
+		t.Errorf("Command should not be executed on invalid global flags")
+	}
+	if !strings.Contains(errBuf.String(), "Failed to parse global flags") {
+		t.Errorf("Error output should report the global flags failure, got %v", errBuf.String())
+	}
+}
+
+func TestItCanEnableDryRunModeViaGlobalFlag(t *testing.T) {
+	scenarios := map[string]struct {
+		args       []string
+		wantDryRun bool
+	}{
+		"dry-run given before the command": {
+			args:       []string{"--dry-run", "test-cmd"},
+			wantDryRun: true,
+		},
+		"dry-run not given": {
+			args:       []string{"test-cmd"},
+			wantDryRun: false,
+		},
+	}
+
+	for name, scenario := range scenarios {
+		t.Run(
+			name, func(t *testing.T) {
+				dryRun := !scenario.wantDryRun
+				registry := NewCommandsRegistry()
+				_ = registry.Register(
+					&MockContextCommand{
+						MockCommand: MockCommand{id: "test-cmd"},
+						execContextFunc: func(ctx context.Context, writer io.Writer) error {
+							dryRun = IsDryRun(ctx)
+							return nil
+						},
+					},
+				)
+
+				exitCode := -1
+				Bootstrap(
+					scenario.args,
+					registry,
+					&bytes.Buffer{},
+					func(code int) { exitCode = code },
+				)
+
+				if exitCode != StatusOk {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+				}
+				if dryRun != scenario.wantDryRun {
+					t.Errorf("IsDryRun() = %v, want %v", dryRun, scenario.wantDryRun)
+				}
+			},
+		)
+	}
+}
+
+func TestItSuppressesWarningsAndFailuresInQuietMode(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockDeprecatedCommand{
+			MockCommand: MockCommand{
+				id: "old-cmd",
+				execFunc: func(writer io.Writer) error {
+					_, _ = writer.Write([]byte("essential output"))
+					return NewExitError(3, errors.New("something broke"))
+				},
+			},
+			deprecation: "use new-cmd instead",
+		},
+	)
+
+	tests := []struct {
+		name       string
+		args       []string
+		opts       []BootstrapOption
+		wantErrOut bool
+	}{
+		{name: "default", args: []string{"old-cmd"}, wantErrOut: true},
+		{name: "quiet flag", args: []string{"--quiet", "old-cmd"}},
+		{name: "quiet option", args: []string{"old-cmd"}, opts: []BootstrapOption{WithQuiet(true)}},
+		{
+			name:       "quiet option disabled",
+			args:       []string{"old-cmd"},
+			opts:       []BootstrapOption{WithQuiet(false)},
+			wantErrOut: true,
+		},
+		{name: "verbose flag overriding quiet", args: []string{"--quiet", "--verbose", "old-cmd"}, wantErrOut: true},
+		{
+			name:       "verbose option overriding quiet",
+			args:       []string{"old-cmd"},
+			opts:       []BootstrapOption{WithQuiet(true), WithVerbose(true)},
+			wantErrOut: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf, errBuf bytes.Buffer
+				exitCode := -1
+				Bootstrap(
+					tt.args,
+					registry,
+					&buf,
+					func(code int) { exitCode = code },
+					append(tt.opts, WithErrorWriter(&errBuf), WithoutSignalHandling())...,
+				)
+
+				if exitCode != 3 {
+					t.Errorf("Bootstrap() exitCode = %v, want 3", exitCode)
+				}
+				if buf.String() != "essential output" {
+					t.Errorf("Bootstrap() output = %q, want %q", buf.String(), "essential output")
+				}
+				if tt.wantErrOut && (!strings.Contains(errBuf.String(), "deprecated") ||
+					!strings.Contains(errBuf.String(), "something broke")) {
+					t.Errorf("Bootstrap() error output = %q, want the warning and the failure", errBuf.String())
+				}
+				if !tt.wantErrOut && errBuf.Len() != 0 {
+					t.Errorf("Bootstrap() error output = %q, want none in quiet mode", errBuf.String())
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"io"
+	"slices"
+	"strings"
+)
+
+// CommandGroup is a Command which holds child commands, allowing hierarchical
+// invocations like "db migrate up". Groups can be nested and registered in a
+// CommandsRegistry like any other command. Only the resolved leaf command parses flags.
+type CommandGroup struct {
+	CommandWithoutFlags
+	id          string
+	description string
+	children    *CommandsRegistry
+}
+
+// NewCommandGroup creates an empty CommandGroup. Use Register to add child commands.
+func NewCommandGroup(id string, description string) *CommandGroup {
+	return &CommandGroup{
+		id:          id,
+		description: description,
+		children:    NewCommandsRegistry(),
+	}
+}
+
+func (g *CommandGroup) Id() string {
+	return g.id
+}
+
+func (g *CommandGroup) Description() string {
+	return g.description
+}
+
+// Exec lists the group's child commands. It is only reached when no subcommand was given.
+func (g *CommandGroup) Exec(baseWriter io.Writer) error {
+	layout := newHelpLayout(detectHelpWidth(baseWriter), helpIdColumn(g.Commands(), ""))
+	writer := layout.newWriter(baseWriter)
+	for _, command := range g.Commands() {
+		writeCommandHelp(writer, command, "", layout.descriptionWidth)
+	}
+	return writer.Flush()
+}
+
+// Register adds a child command to the group
+func (g *CommandGroup) Register(cmd Command) error {
+	return g.children.Register(cmd)
+}
+
+// Commands returns the group's child commands, sorted by id
+func (g *CommandGroup) Commands() []Command {
+	commands := make([]Command, 0, len(g.children.commands))
+	for _, cmd := range g.children.commands {
+		commands = append(commands, cmd)
+	}
+	slices.SortFunc(
+		commands, func(a, b Command) int {
+			return strings.Compare(a.Id(), b.Id())
+		},
+	)
+	return commands
+}
+
+// Command returns a child command by its ID
+func (g *CommandGroup) Command(id string) (Command, bool) {
+	return g.children.Command(id)
+}
+
+// resolveSubcommand walks the args through nested command groups, starting from cmd,
+// and returns the resolved leaf command, its full path (e.g. "db migrate up") and the
+// remaining args. If an arg which is not a flag does not match any child of a group,
+// exists is false and resolved is the group in which the lookup failed.
+func resolveSubcommand(
+	cmd Command,
+	args []string,
+) (resolved Command, path string, remainingArgs []string, exists bool) {
+	resolved = cmd
+	path = cmd.Id()
+	remainingArgs = args
+
+	for {
+		group, isGroup := resolved.(*CommandGroup)
+		if !isGroup || len(remainingArgs) == 0 || strings.HasPrefix(remainingArgs[0], "-") {
+			return resolved, path, remainingArgs, true
+		}
+
+		childId := strings.TrimSpace(remainingArgs[0])
+		path += " " + childId
+		child, childExists := group.Command(childId)
+		if !childExists {
+			return group, path, remainingArgs[1:], false
+		}
+
+		resolved = child
+		remainingArgs = remainingArgs[1:]
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestDbGroup(executed *string) *CommandGroup {
+	migrate := NewCommandGroup("migrate", "Database migrations")
+	_ = migrate.Register(
+		&MockCommandWithFlags{
+			id:          "up",
+			description: "Apply migrations",
+			execFunc: func(writer io.Writer) error {
+				*executed = "up"
+				return nil
+			},
+		},
+	)
+	_ = migrate.Register(
+		&MockCommand{
+			id:          "down",
+			description: "Revert migrations",
+			execFunc: func(writer io.Writer) error {
+				*executed = "down"
+				return nil
+			},
+		},
+	)
+
+	db := NewCommandGroup("db", "Database commands")
+	_ = db.Register(migrate)
+	return db
+}
+
+func TestItCanResolveNestedSubcommands(t *testing.T) {
+	executed := ""
+	db := newTestDbGroup(&executed)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantPath   string
+		wantArgs   []string
+		wantExists bool
+	}{
+		{
+			name:       "leaf with flags",
+			args:       []string{"migrate", "up", "--test-flag", "value"},
+			wantPath:   "db migrate up",
+			wantArgs:   []string{"--test-flag", "value"},
+			wantExists: true,
+		},
+		{
+			name:       "group without subcommand",
+			args:       []string{"migrate"},
+			wantPath:   "db migrate",
+			wantArgs:   []string{},
+			wantExists: true,
+		},
+		{
+			name:       "unknown subcommand",
+			args:       []string{"migrate", "sideways"},
+			wantPath:   "db migrate sideways",
+			wantArgs:   []string{},
+			wantExists: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, path, args, exists := resolveSubcommand(db, tt.args)
+				if path != tt.wantPath {
+					t.Errorf("resolveSubcommand() path = %q, want %q", path, tt.wantPath)
+				}
+				if exists != tt.wantExists {
+					t.Errorf("resolveSubcommand() exists = %v, want %v", exists, tt.wantExists)
+				}
+				if strings.Join(args, " ") != strings.Join(tt.wantArgs, " ") {
+					t.Errorf("resolveSubcommand() args = %v, want %v", args, tt.wantArgs)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanBootstrapNestedSubcommands(t *testing.T) {
+	executed := ""
+	registry := NewCommandsRegistry()
+	_ = registry.Register(newTestDbGroup(&executed))
+
+	var buf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"db", "migrate", "up", "--test-flag", "value"},
+		registry,
+		&buf,
+		func(code int) { exitCode = code },
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v, output %s", exitCode, StatusOk, buf.String())
+	}
+	if executed != "up" {
+		t.Errorf("Bootstrap() executed %q, want up", executed)
+	}
+
+	buf.Reset()
+	Bootstrap(
+		[]string{"db", "migrate", "sideways"},
+		registry,
+		&buf,
+		func(code int) { exitCode = code },
+		WithErrorWriter(&buf),
+	)
+
+	if exitCode != StatusErr {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusErr)
+	}
+	if !strings.Contains(buf.String(), "db migrate sideways does not exist") {
+		t.Errorf("Bootstrap() output should report the missing subcommand, got %v", buf.String())
+	}
+}
+
+func TestItRendersGroupChildrenIndentedInHelp(t *testing.T) {
+	executed := ""
+	helpCmd := NewHelpCommand([]Command{newTestDbGroup(&executed)})
+
+	var buf bytes.Buffer
+	if err := helpCmd.Exec(&buf); err != nil {
+		t.Fatalf("HelpCommand.Exec() error = %v, want nil", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"db ", "  migrate ", "    up ", "    down ", "Subcommands:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Help output doesn't contain %q:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	group, _ := newTestDbGroup(&executed).Command("migrate")
+	_ = group.Exec(&buf)
+	if !strings.Contains(buf.String(), "Apply migrations") {
+		t.Errorf("Group Exec() should list its children, got %s", buf.String())
+	}
+}
@@ -1,22 +1,107 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strings"
 	"text/tabwriter"
+	"text/template"
+	"unicode/utf8"
 )
 
+const (
+	HelpFormatText = "text"
+	HelpFormatJson = "json"
+)
+
+// DefaultCategory is the help category of commands not implementing CategorizedCommand
+const DefaultCategory = "General"
+
+// CategorizedCommand is an optional interface for commands which should be listed under
+// a category header in the help output. Other commands are listed under DefaultCategory.
+type CategorizedCommand interface {
+	Command
+	Category() string
+}
+
+// HideableCommand is an optional interface for internal commands, like maintenance-only
+// jobs, which should not clutter the help output. Commands whose Hidden method returns true
+// are only listed when help is run with --all, and remain invokable by id.
+type HideableCommand interface {
+	Command
+	Hidden() bool
+}
+
+// DefaultHelpWidth is the help output width, in columns, used when the output is not a
+// terminal or its width is unknown
+const DefaultHelpWidth = 80
+
+// minHelpDescriptionWidth is the minimum width at which descriptions are wrapped, even
+// when the id column leaves less room than that
+const minHelpDescriptionWidth = 20
+
 type HelpCommand struct {
-	CommandWithoutFlags
 	availableCommands []Command
+	format            string
+	filter            string
+	width             int
+	list              bool
+	all               bool
+	commandPath       []string
+	template          *template.Template
+}
+
+// ExampleProvider is an optional interface for commands which provide invocation
+// examples, rendered verbatim in an "Examples:" section of the help output
+type ExampleProvider interface {
+	Command
+	Examples() []string
+}
+
+// FlagGroupsProvider is an optional interface for commands with many flags, which can be
+// listed by the help output under group sub-headers, like "Connection flags:". FlagGroups
+// maps each group name to the names of its flags. Groups are listed sorted by name,
+// followed by the ungrouped flags, under "Other flags:".
+type FlagGroupsProvider interface {
+	Command
+	FlagGroups() map[string][]string
 }
 
 func NewHelpCommand(availableCommands []Command) *HelpCommand {
 	return &HelpCommand{availableCommands: availableCommands}
 }
 
+// NewHelpCommandWithTemplate creates a help command rendering the command list with the
+// given text/template instead of the built-in layout. The template is executed with the
+// []CommandInfo of the listed commands, sorted by category and id, and must be parsed with
+// the HelpTemplateFuncs functions if it uses them. Its output is aligned like the built-in
+// layout: tabs separate the id column from the description column, and lines made of a lone
+// tab are blank lines which do not break the alignment. DefaultHelpTemplate is a starting
+// point. The --format json, --list and single command outputs are not templated.
+// It returns an error if the template is nil or fails to render the given commands.
+func NewHelpCommandWithTemplate(
+	availableCommands []Command,
+	tmpl *template.Template,
+) (*HelpCommand, error) {
+	if tmpl == nil {
+		return nil, errors.New("invalid help template, the template must not be nil")
+	}
+
+	commands := visibleCommands(availableCommands)
+	err := executeHelpTemplate(tmpl, io.Discard, commands, DefaultHelpWidth/2)
+	if err != nil {
+		return nil, fmt.Errorf("invalid help template: %w", err)
+	}
+
+	return &HelpCommand{availableCommands: availableCommands, template: tmpl}, nil
+}
+
 func (c *HelpCommand) Id() string {
 	return "help"
 }
@@ -25,81 +110,823 @@ func (c *HelpCommand) Description() string {
 	return "Lists all available commands"
 }
 
+func (c *HelpCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(
+		&c.format,
+		"format",
+		HelpFormatText,
+		fmt.Sprintf("The output format, %s or %s", HelpFormatText, HelpFormatJson),
+	)
+	flagSet.IntVar(
+		&c.width,
+		"width",
+		0,
+		"The output width in columns, 0 to use the terminal width",
+	)
+	flagSet.BoolVar(
+		&c.list,
+		"list",
+		false,
+		"List only the command ids, one per line, subcommands being prefixed by their group id",
+	)
+	flagSet.BoolVar(&c.all, "all", false, "Include the hidden commands")
+}
+
+func (c *HelpCommand) ValidateFlags() error {
+	if c.format != HelpFormatText && c.format != HelpFormatJson {
+		return fmt.Errorf(
+			"invalid format %s, expected %s or %s",
+			c.format,
+			HelpFormatText,
+			HelpFormatJson,
+		)
+	}
+	if c.width < 0 {
+		return fmt.Errorf("invalid width %d, expected 0 or more columns", c.width)
+	}
+	if c.list && c.format == HelpFormatJson {
+		return fmt.Errorf("--list cannot be used with the %s format", HelpFormatJson)
+	}
+	return nil
+}
+
+// SetArgs receives the positional args of the help command. When the first one is the id
+// of a command, followed by the ids of its subcommands if any, like "help db migrate", the
+// detailed help of that command is rendered. Otherwise, the first one filters the listed
+// commands to those whose id or description contains it, case-insensitively. The --list
+// output is always filtered.
+func (c *HelpCommand) SetArgs(args []string) {
+	c.filter = ""
+	c.commandPath = nil
+	if len(args) > 0 {
+		c.filter = args[0]
+		c.commandPath = args
+	}
+}
+
 func (c *HelpCommand) Exec(baseWriter io.Writer) error {
-	writer := tabwriter.NewWriter(baseWriter, 0, 0, 4, ' ', 0)
-	_, _ = fmt.Fprintln(writer, "\t")
-	_, _ = fmt.Fprintln(writer, c.Id()+"\t"+c.Description())
-	_, _ = fmt.Fprintln(writer, "\t")
+	if len(c.commandPath) > 0 && !c.list {
+		if command, exists := c.findCommand(c.commandPath[0]); exists {
+			return c.execCommandHelp(baseWriter, command, c.commandPath[1:])
+		}
+	}
 
-	for _, command := range c.availableCommands {
+	commands := c.availableCommands
+	if !c.all {
+		commands = visibleCommands(commands)
+	}
+	visible := commands
+	commands = filterCommands(commands, c.filter)
+
+	if c.list {
+		return c.execList(baseWriter, commands)
+	}
+
+	if c.format == HelpFormatJson {
+		return c.execJson(baseWriter, commands)
+	}
+
+	categories := groupByCategory(commands)
+	firstColumn := []string{c.Id()}
+	for _, category := range categories {
+		firstColumn = append(firstColumn, category.name+":")
+		firstColumn = append(firstColumn, helpIdColumn(category.commands, "")...)
+	}
+
+	width := c.width
+	if width == 0 {
+		width = detectHelpWidth(baseWriter)
+	}
+	layout := newHelpLayout(width, firstColumn)
+
+	writer := layout.newWriter(baseWriter)
+	if c.template == nil {
+		_, _ = fmt.Fprintln(writer, "\t")
+		_, _ = fmt.Fprintln(writer, c.Id()+"\t"+c.Description())
 		_, _ = fmt.Fprintln(writer, "\t")
+	}
+
+	if len(commands) == 0 && c.filter != "" {
+		_, _ = fmt.Fprintf(writer, "No commands match %q\n", c.filter)
+		if hint := formatSuggestions(suggestCommandIds(c.filter, commandIds(visible))); hint != "" {
+			_, _ = fmt.Fprintln(writer, strings.ToUpper(hint[:1])+hint[1:])
+		}
+	}
+
+	if c.template != nil {
+		err := executeHelpTemplate(c.template, writer, commands, layout.descriptionWidth)
+		if flushErr := writer.Flush(); err == nil {
+			err = flushErr
+		}
+		return err
+	}
+
+	for _, category := range categories {
+		_, _ = fmt.Fprintln(writer, category.name+":\t")
+		for _, command := range category.commands {
+			writeCommandHelp(writer, command, "", layout.descriptionWidth)
+		}
+	}
+	_ = writer.Flush()
+
+	return nil
+}
+
+// DefaultHelpTemplate is a help template, for NewHelpCommandWithTemplate, following the
+// built-in layout: commands listed under their category, with their description, flags,
+// examples and exit codes, and the subcommands of groups with their description.
+const DefaultHelpTemplate = `{{- $category := "" -}}
+{{- range . -}}
+{{- if ne .Category $category -}}
+{{- $category = .Category -}}
+{{ .Category }}:{{ "\t" }}
+{{ end -}}
+{{ .Id }}{{ if .Deprecated }} [deprecated]{{ end -}}
+{{- range $i, $line := wrap .Description }}{{ if $i }}{{ "\n" }}{{ end }}{{ "\t" }}{{ $line }}{{ end }}
+{{ if .Deprecated }}{{ "\t" }}Deprecated: {{ .Deprecated }}
+{{ end -}}
+{{- if .Subcommands }}{{ "\t" }}Subcommands:
+{{ range .Subcommands }}  {{ .Id }}{{ "\t" }}{{ .Description }}
+{{ end -}}
+{{- else if .RawArgs }}{{ "\t" }}Args: raw, passed through without flag parsing
+{{ else if .Flags }}{{ "\t" }}Flags:
+{{ range .Flags }}{{ "\t" }}{{ flagNames . }} ({{ flagDetails . }})
+{{ range wrap .Usage }}{{ "\t" }}{{ . }}
+{{ end -}}
+{{- end -}}
+{{- else }}{{ "\t" }}Flags: none
+{{ end -}}
+{{- if .Examples }}{{ "\t" }}Examples:
+{{ range .Examples }}{{ "\t" }}{{ . }}
+{{ end -}}
+{{- end -}}
+{{- if .ExitCodes }}{{ "\t" }}Exit codes:
+{{ range $code, $meaning := .ExitCodes }}{{ "\t" }}{{ $code }}: {{ $meaning }}
+{{ end -}}
+{{- end -}}
+{{ "\t" }}
+{{ end -}}
+`
+
+// HelpTemplateFuncs returns the functions available to help templates, which must be
+// added with Funcs before parsing them:
+//   - wrap: word-wraps a text at the width of the description column, returning its lines
+//   - flagNames: the names of a FlagInfo, like "--name, -n"
+//   - flagDetails: the default of a FlagInfo, or "required", and its allowed values
+func HelpTemplateFuncs() template.FuncMap {
+	return helpTemplateFuncs(DefaultHelpWidth / 2)
+}
+
+// helpTemplateFuncs returns the help template functions, wrapping texts at the given width
+func helpTemplateFuncs(descriptionWidth int) template.FuncMap {
+	return template.FuncMap{
+		"wrap": func(text string) []string {
+			return chunkDescription(strings.Trim(text, "\n "), descriptionWidth)
+		},
+		"flagNames":   flagNames,
+		"flagDetails": flagDetails,
+	}
+}
+
+// executeHelpTemplate renders the commands, sorted by category and id, with the template,
+// its functions wrapping texts at the given width
+func executeHelpTemplate(
+	tmpl *template.Template,
+	writer io.Writer,
+	commands []Command,
+	descriptionWidth int,
+) error {
+	infos := []CommandInfo{}
+	for _, category := range groupByCategory(commands) {
+		for _, command := range category.commands {
+			infos = append(infos, Describe(command))
+		}
+	}
+
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	return tmpl.Funcs(helpTemplateFuncs(descriptionWidth)).Execute(writer, infos)
+}
+
+// findCommand returns the available command with the given id, hidden commands included
+func (c *HelpCommand) findCommand(id string) (Command, bool) {
+	for _, command := range c.availableCommands {
+		if command.Id() == id {
+			return command, true
+		}
+	}
+	return nil, false
+}
+
+// execCommandHelp writes the detailed help of the command, or of its subcommand designated
+// by the subcommand ids, as a json CommandInfo or as text. An unknown subcommand is
+// reported along with the closest subcommand ids.
+func (c *HelpCommand) execCommandHelp(
+	baseWriter io.Writer,
+	command Command,
+	subcommandIds []string,
+) error {
+	command, path, _, exists := resolveSubcommand(command, subcommandIds)
+	if !exists {
+		group, _ := command.(*CommandGroup)
+		_, _ = fmt.Fprintf(baseWriter, "No command %q found\n", path)
+		requestedId := path[strings.LastIndex(path, " ")+1:]
+		hint := formatSuggestions(suggestCommandIds(requestedId, commandIds(group.Commands())))
+		if hint != "" {
+			_, _ = fmt.Fprintln(baseWriter, strings.ToUpper(hint[:1])+hint[1:])
+		}
+		return nil
+	}
+
+	info := Describe(command)
+	if c.format == HelpFormatJson {
+		encoder := json.NewEncoder(baseWriter)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	width := c.width
+	if width == 0 {
+		width = detectHelpWidth(baseWriter)
+	}
+	writeCommandDetail(baseWriter, info, commandUsage(command, info, path), width)
+	return nil
+}
+
+// commandUsage returns the usage line of the command invoked by the given path, like
+// "db migrate [flags] [args]"
+func commandUsage(command Command, info CommandInfo, path string) string {
+	if len(info.Subcommands) > 0 {
+		return path + " <subcommand>"
+	}
+
+	usage := path
+	if len(info.Flags) > 0 {
+		usage += " [flags]"
+	}
+	if _, receivesArgs := findOptional[ArgsReceiver](command); receivesArgs || info.RawArgs {
+		usage += " [args]"
+	}
+	return usage
+}
+
+// writeCommandDetail writes the detailed text help of a single command: its usage line,
+// description, flags with their type and default, subcommands, examples and exit codes.
+// Descriptions and flag usages are wrapped at the given width.
+func writeCommandDetail(writer io.Writer, info CommandInfo, usage string, width int) {
+	const indent = "  "
+	usageWidth := max(minHelpDescriptionWidth, width-2*len(indent))
+
+	_, _ = fmt.Fprintf(writer, "Usage: %s\n\n", usage)
+
+	for _, descChunk := range chunkDescription(info.Description, width) {
+		_, _ = fmt.Fprintln(writer, strings.TrimRight(descChunk, " "))
+	}
+	if info.Deprecated != "" {
+		_, _ = fmt.Fprintf(writer, "\nDeprecated: %s\n", info.Deprecated)
+	}
+
+	if len(info.Subcommands) > 0 {
+		var firstColumn []string
+		for _, subcommand := range info.Subcommands {
+			firstColumn = append(firstColumn, indent+subcommand.Id)
+		}
+		layout := newHelpLayout(width, firstColumn)
 
-		descChunks := chunkDescription(command.Description(), 80)
-		_, _ = fmt.Fprintln(writer, command.Id()+"\t"+descChunks[0])
-		if len(descChunks) > 1 {
+		_, _ = fmt.Fprintln(writer, "\nSubcommands:")
+		tabWriter := layout.newWriter(writer)
+		for _, subcommand := range info.Subcommands {
+			descChunks := chunkDescription(subcommand.Description, layout.descriptionWidth)
+			_, _ = fmt.Fprintln(tabWriter, indent+subcommand.Id+"\t"+descChunks[0])
 			for _, descChunk := range descChunks[1:] {
-				_, _ = fmt.Fprintln(writer, "\t"+descChunk)
+				_, _ = fmt.Fprintln(tabWriter, "\t"+descChunk)
 			}
 		}
+		_ = tabWriter.Flush()
+		return
+	}
 
-		cmdFlagSet := setupFlagSet(command, writer)
-		if cmdFlagSet != nil {
-			command.DefineFlags(cmdFlagSet)
-			countFlags := 0
-			flagsListOutput := ""
-
-			cmdFlagSet.VisitAll(
-				func(flag *flag.Flag) {
-					if flag != nil {
-						countFlags++
-						flagsListOutput += fmt.Sprintf(
-							"\t--%s (default %s)\n",
-							flag.Name,
-							flag.DefValue,
-						)
-						usageChunks := chunkDescription(strings.Trim(flag.Usage, "\n "), 80)
-						if len(usageChunks) > 0 {
-							for _, usageChunk := range usageChunks {
-								flagsListOutput += fmt.Sprintf("\t%s\n", usageChunk)
-							}
-						}
+	if info.RawArgs {
+		_, _ = fmt.Fprintln(writer, "\nArgs: raw, passed through without flag parsing")
+	} else if len(info.Flags) == 0 {
+		_, _ = fmt.Fprintln(writer, "\nFlags: none")
+	} else {
+		for _, section := range flagSections(info.Flags) {
+			_, _ = fmt.Fprintf(writer, "\n%s:\n", section.title)
+			for _, flagInfo := range section.flags {
+				_, _ = fmt.Fprintf(
+					writer,
+					"%s%s %s (%s)\n",
+					indent,
+					flagNames(flagInfo),
+					flagInfo.Type,
+					flagDetails(flagInfo),
+				)
+
+				if usage := strings.Trim(flagInfo.Usage, "\n "); usage != "" {
+					for _, usageChunk := range chunkDescription(usage, usageWidth) {
+						_, _ = fmt.Fprintln(writer, indent+indent+usageChunk)
 					}
-				},
-			)
-
-			if countFlags > 0 {
-				_, _ = fmt.Fprintln(writer, "\tFlags:")
-				_, _ = fmt.Fprint(writer, flagsListOutput)
-			} else {
-				_, _ = fmt.Fprintln(writer, "\tFlags: none")
+				}
 			}
 		}
+	}
 
-		_, _ = fmt.Fprintln(writer, "\t")
+	if len(info.Examples) > 0 {
+		_, _ = fmt.Fprintln(writer, "\nExamples:")
+		for _, example := range info.Examples {
+			_, _ = fmt.Fprintln(writer, indent+example)
+		}
 	}
-	_ = writer.Flush()
 
+	if len(info.ExitCodes) > 0 {
+		_, _ = fmt.Fprintln(writer, "\nExit codes:")
+		for _, code := range slices.Sorted(maps.Keys(info.ExitCodes)) {
+			_, _ = fmt.Fprintf(writer, "%s%d: %s\n", indent, code, info.ExitCodes[code])
+		}
+	}
+}
+
+// flagNames returns the names of the flag as shown in the help output, like "--name, -n"
+func flagNames(flagInfo FlagInfo) string {
+	names := "--" + flagInfo.Name
+	for _, alias := range flagInfo.Aliases {
+		names += ", " + flagDisplayName(alias)
+	}
+	return names
+}
+
+// flagDetails returns the default, or the required marker, of the flag, followed by its
+// allowed values if any, like "default text, one of json, text"
+func flagDetails(flagInfo FlagInfo) string {
+	details := "default " + flagInfo.Default
+	if flagInfo.Required {
+		details = "required"
+	}
+	if flagInfo.Enum != nil {
+		details += ", one of " + strings.Join(flagInfo.Enum, ", ")
+	}
+	return details
+}
+
+// flagGroups returns the group of each flag of the command implementing
+// FlagGroupsProvider, keyed by flag name. A flag listed in several groups belongs to the
+// first one by name.
+func flagGroups(command Command) map[string]string {
+	provider, ok := findOptional[FlagGroupsProvider](command)
+	if !ok {
+		return nil
+	}
+
+	groups := provider.FlagGroups()
+	byFlag := make(map[string]string)
+	for _, group := range slices.Sorted(maps.Keys(groups)) {
+		for _, name := range groups[group] {
+			if _, grouped := byFlag[name]; !grouped && strings.TrimSpace(group) != "" {
+				byFlag[name] = strings.TrimSpace(group)
+			}
+		}
+	}
+	return byFlag
+}
+
+// flagSection holds the flags listed under a help sub-header
+type flagSection struct {
+	title string
+	flags []FlagInfo
+}
+
+// flagSections splits the flags into their groups, sorted by name, followed by the
+// ungrouped flags. Without groups, all the flags are in a single "Flags" section.
+func flagSections(flags []FlagInfo) []flagSection {
+	byGroup := make(map[string][]FlagInfo)
+	var ungrouped []FlagInfo
+	for _, flagInfo := range flags {
+		if flagInfo.Group == "" {
+			ungrouped = append(ungrouped, flagInfo)
+		} else {
+			byGroup[flagInfo.Group] = append(byGroup[flagInfo.Group], flagInfo)
+		}
+	}
+	if len(byGroup) == 0 {
+		return []flagSection{{title: "Flags", flags: flags}}
+	}
+
+	var sections []flagSection
+	for _, group := range slices.Sorted(maps.Keys(byGroup)) {
+		sections = append(sections, flagSection{title: group + " flags", flags: byGroup[group]})
+	}
+	if len(ungrouped) > 0 {
+		sections = append(sections, flagSection{title: "Other flags", flags: ungrouped})
+	}
+	return sections
+}
+
+// commandIds returns the ids of the commands
+func commandIds(commands []Command) []string {
+	ids := make([]string, 0, len(commands))
+	for _, command := range commands {
+		ids = append(ids, command.Id())
+	}
+	return ids
+}
+
+// ExitCodeDocumenter is an optional interface for commands documenting the exit codes
+// they return (see ExitCoder), rendered with their meaning in an "Exit codes:" section of
+// the help output
+type ExitCodeDocumenter interface {
+	Command
+	ExitCodes() map[int]string
+}
+
+// helpLayout holds the dimensions of the text help output
+type helpLayout struct {
+	// The padding between the id and the description columns
+	padding int
+
+	// The width at which descriptions and flag usages are wrapped
+	descriptionWidth int
+}
+
+// newHelpLayout fits the help output into the given width, in columns, given the cells
+// of its first (id) column
+func newHelpLayout(width int, firstColumn []string) helpLayout {
+	padding := 4
+	if width < DefaultHelpWidth {
+		padding = 2
+	}
+
+	firstColumnWidth := 0
+	for _, cell := range firstColumn {
+		firstColumnWidth = max(firstColumnWidth, utf8.RuneCountInString(cell))
+	}
+
+	return helpLayout{
+		padding:          padding,
+		descriptionWidth: max(minHelpDescriptionWidth, width-firstColumnWidth-padding),
+	}
+}
+
+// helpWriter is a tabwriter aligning the help columns, whose output lines are stripped of
+// trailing whitespace. Blank lines are written as a lone tab, to not end the tabwriter
+// column block, which would break the alignment between entries.
+type helpWriter struct {
+	*tabwriter.Writer
+	trimmer *trailingSpaceTrimmer
+}
+
+// Flush flushes the tabwriter and writes any remaining partial line
+func (w *helpWriter) Flush() error {
+	if err := w.Writer.Flush(); err != nil {
+		return err
+	}
+	return w.trimmer.flush()
+}
+
+// newWriter returns a writer aligning the help columns
+func (l helpLayout) newWriter(baseWriter io.Writer) *helpWriter {
+	trimmer := &trailingSpaceTrimmer{writer: baseWriter}
+	return &helpWriter{
+		Writer:  tabwriter.NewWriter(trimmer, 0, 0, l.padding, ' ', 0),
+		trimmer: trimmer,
+	}
+}
+
+// trailingSpaceTrimmer writes complete lines, stripped of trailing spaces and tabs, to
+// the underlying writer
+type trailingSpaceTrimmer struct {
+	writer io.Writer
+	line   []byte
+}
+
+func (t *trailingSpaceTrimmer) Write(p []byte) (int, error) {
+	for _, char := range p {
+		if char != '\n' {
+			t.line = append(t.line, char)
+			continue
+		}
+
+		line := append(bytes.TrimRight(t.line, " \t"), '\n')
+		t.line = t.line[:0]
+		if _, err := t.writer.Write(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush writes the remaining partial line, if any
+func (t *trailingSpaceTrimmer) flush() error {
+	if len(t.line) == 0 {
+		return nil
+	}
+	_, err := t.writer.Write(bytes.TrimRight(t.line, " \t"))
+	t.line = t.line[:0]
+	return err
+}
+
+// detectHelpWidth returns the width of the terminal the writer refers to, or
+// DefaultHelpWidth if it is not a terminal or its width is unknown
+func detectHelpWidth(writer io.Writer) int {
+	if file, ok := writerFile(writer); ok && isTerminal(file) {
+		if width := terminalWidth(file); width > 0 {
+			return width
+		}
+	}
+	return DefaultHelpWidth
+}
+
+// helpIdColumn returns the id column cells of the commands, including the children of
+// command groups
+func helpIdColumn(commands []Command, indent string) []string {
+	var cells []string
+	for _, command := range commands {
+		cell := indent + command.Id()
+		if deprecationMessage(command) != "" {
+			cell += " [deprecated]"
+		}
+		cells = append(cells, cell)
+
+		if group, isGroup := command.(*CommandGroup); isGroup {
+			cells = append(cells, helpIdColumn(group.Commands(), indent+"  ")...)
+		}
+	}
+	return cells
+}
+
+// helpCategory holds the commands listed under a help category header
+type helpCategory struct {
+	name     string
+	commands []Command
+}
+
+// groupByCategory groups the commands by category. Categories are sorted by name and
+// the commands within each category by id.
+func groupByCategory(commands []Command) []helpCategory {
+	byName := make(map[string][]Command)
+	for _, command := range commands {
+		category := commandCategory(command)
+		byName[category] = append(byName[category], command)
+	}
+
+	categories := make([]helpCategory, 0, len(byName))
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		categoryCommands := byName[name]
+		slices.SortFunc(
+			categoryCommands, func(a, b Command) int {
+				return strings.Compare(a.Id(), b.Id())
+			},
+		)
+		categories = append(categories, helpCategory{name, categoryCommands})
+	}
+
+	return categories
+}
+
+// commandCategory returns the help category of the command, DefaultCategory for commands
+// not implementing CategorizedCommand
+func commandCategory(command Command) string {
+	if categorized, ok := findOptional[CategorizedCommand](command); ok &&
+		strings.TrimSpace(categorized.Category()) != "" {
+		return strings.TrimSpace(categorized.Category())
+	}
+	return DefaultCategory
+}
+
+// visibleCommands returns the commands which are not hidden, see HideableCommand
+func visibleCommands(commands []Command) []Command {
+	var visible []Command
+	for _, command := range commands {
+		if hideable, ok := findOptional[HideableCommand](command); !ok || !hideable.Hidden() {
+			visible = append(visible, command)
+		}
+	}
+	return visible
+}
+
+// filterCommands returns the commands whose id or description contains the filter,
+// case-insensitively. An empty filter matches all commands.
+func filterCommands(commands []Command, filter string) []Command {
+	if filter == "" {
+		return commands
+	}
+
+	filter = strings.ToLower(filter)
+	var matching []Command
+	for _, command := range commands {
+		if strings.Contains(strings.ToLower(command.Id()), filter) ||
+			strings.Contains(strings.ToLower(command.Description()), filter) {
+			matching = append(matching, command)
+		}
+	}
+	return matching
+}
+
+// execList writes the sorted ids of the commands, one per line, followed by the ids of
+// the subcommands of command groups, prefixed by the group id
+func (c *HelpCommand) execList(writer io.Writer, commands []Command) error {
+	ids := listCommandIds(commands, "")
+	slices.Sort(ids)
+	for _, id := range ids {
+		if _, err := fmt.Fprintln(writer, id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// listCommandIds returns the ids of the commands, including the children of command
+// groups, prefixed by the given prefix
+func listCommandIds(commands []Command, prefix string) []string {
+	var ids []string
+	for _, command := range commands {
+		id := prefix + command.Id()
+		ids = append(ids, id)
+
+		if group, isGroup := command.(*CommandGroup); isGroup {
+			ids = append(ids, listCommandIds(group.Commands(), id+" ")...)
+		}
+	}
+	return ids
+}
+
+// execJson writes the commands as a json array of their CommandInfo
+func (c *HelpCommand) execJson(writer io.Writer, commands []Command) error {
+	infos := make([]CommandInfo, 0, len(commands))
+	for _, category := range groupByCategory(commands) {
+		for _, command := range category.commands {
+			infos = append(infos, Describe(command))
+		}
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(infos)
+}
+
+// deprecationMessage returns the deprecation message of the command, or an empty string
+// if it is not deprecated
+func deprecationMessage(command Command) string {
+	if deprecatable, ok := findOptional[DeprecatableCommand](command); ok {
+		return deprecatable.Deprecated()
+	}
+	return ""
+}
+
+// flagType returns the type name of the flag value, like string, int or time.Duration
+func flagType(definedFlag *flag.Flag) string {
+	if getter, ok := definedFlag.Value.(flag.Getter); ok && getter.Get() != nil {
+		return fmt.Sprintf("%T", getter.Get())
+	}
+	return "value"
+}
+
+// writeCommandHelp writes the description and flags of a command to the (tab)writer.
+// Children of command groups are written recursively, indented underneath the group.
+func writeCommandHelp(writer io.Writer, command Command, indent string, descriptionWidth int) {
+	deprecation := deprecationMessage(command)
+	idColumn := indent + command.Id()
+	if deprecation != "" {
+		idColumn += " [deprecated]"
+	}
+
+	descChunks := chunkDescription(command.Description(), descriptionWidth)
+	_, _ = fmt.Fprintln(writer, idColumn+"\t"+descChunks[0])
+	if len(descChunks) > 1 {
+		for _, descChunk := range descChunks[1:] {
+			_, _ = fmt.Fprintln(writer, "\t"+descChunk)
+		}
+	}
+	if deprecation != "" {
+		_, _ = fmt.Fprintln(writer, "\tDeprecated: "+deprecation)
+	}
+
+	if group, isGroup := command.(*CommandGroup); isGroup {
+		children := group.Commands()
+		if len(children) == 0 {
+			_, _ = fmt.Fprintln(writer, "\tSubcommands: none")
+			_, _ = fmt.Fprintln(writer, "\t")
+			return
+		}
+
+		// Each child ends with the blank line separating it from the next entry
+		_, _ = fmt.Fprintln(writer, "\tSubcommands:")
+		for _, child := range children {
+			writeCommandHelp(writer, child, indent+"  ", descriptionWidth)
+		}
+		return
+	}
+
+	if takesRawArgs(command) {
+		_, _ = fmt.Fprintln(writer, "\tArgs: raw, passed through without flag parsing")
+	} else if cmdFlagSet, err := commandFlagSet(command); err != nil {
+		_, _ = fmt.Fprintf(writer, "\tFlags: (failed to render: %s)\n", err)
+	} else if flags := describeFlags(command, cmdFlagSet); len(flags) == 0 {
+		_, _ = fmt.Fprintln(writer, "\tFlags: none")
+	} else {
+		for _, section := range flagSections(flags) {
+			_, _ = fmt.Fprintf(writer, "\t%s:\n", section.title)
+			for _, flagInfo := range section.flags {
+				_, _ = fmt.Fprintf(writer, "\t%s (%s)\n", flagNames(flagInfo), flagDetails(flagInfo))
+				usage := strings.Trim(flagInfo.Usage, "\n ")
+				for _, usageChunk := range chunkDescription(usage, descriptionWidth) {
+					_, _ = fmt.Fprintf(writer, "\t%s\n", usageChunk)
+				}
+			}
+		}
+	}
+
+	if exampleProvider, ok := findOptional[ExampleProvider](command); ok {
+		if examples := exampleProvider.Examples(); len(examples) > 0 {
+			_, _ = fmt.Fprintln(writer, "\tExamples:")
+			for _, example := range examples {
+				_, _ = fmt.Fprintln(writer, "\t"+example)
+			}
+		}
+	}
+
+	if documenter, ok := findOptional[ExitCodeDocumenter](command); ok {
+		if exitCodes := documenter.ExitCodes(); len(exitCodes) > 0 {
+			_, _ = fmt.Fprintln(writer, "\tExit codes:")
+			for _, code := range slices.Sorted(maps.Keys(exitCodes)) {
+				_, _ = fmt.Fprintf(writer, "\t%d: %s\n", code, exitCodes[code])
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintln(writer, "\t")
+}
+
+// flagDisplayName returns the name of the flag as shown in the help output: "-n" for
+// single letter names, "--name" otherwise
+func flagDisplayName(name string) string {
+	if utf8.RuneCountInString(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// commandFlagSet returns a throwaway flag set holding the flags of the command. A panic
+// in DefineFlags, which some commands use for side effects, is recovered and returned as
+// an error, so that the help of the other commands can still be rendered.
+func commandFlagSet(command Command) (flagSet *flag.FlagSet, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			flagSet, err = nil, fmt.Errorf("defining the flags panicked: %v", recovered)
+		}
+	}()
+
+	flagSet = setupFlagSet(command, io.Discard)
+	defineCommandFlags(command, flagSet)
+	return flagSet, nil
+}
+
+// chunkDescription word-wraps the description into lines of at most size characters
+// (counted in runes), keeping the line breaks of the description. Words longer than size
+// are hard-broken.
 func chunkDescription(description string, size int) []string {
 	if len(description) == 0 {
 		return []string{""}
 	}
 
 	var chunks []string
-	accumulator := ""
-	for _, char := range description {
-		accumulator += string(char)
-		if (len(accumulator) >= size && string(char) == " ") || string(char) == "\n" {
-			chunks = append(chunks, strings.TrimSpace(accumulator))
-			accumulator = ""
+	for _, paragraph := range strings.Split(strings.TrimRight(description, "\n"), "\n") {
+		line := ""
+		lineWidth := 0
+		for _, word := range strings.Fields(paragraph) {
+			for _, piece := range breakWord(word, size) {
+				pieceWidth := utf8.RuneCountInString(piece)
+				if lineWidth > 0 && lineWidth+1+pieceWidth > size {
+					chunks = append(chunks, line)
+					line, lineWidth = "", 0
+				}
+				if lineWidth > 0 {
+					line += " "
+					lineWidth++
+				}
+				line += piece
+				lineWidth += pieceWidth
+			}
 		}
+		chunks = append(chunks, line)
 	}
 
-	if len(accumulator) > 0 {
-		chunks = append(chunks, accumulator)
+	return chunks
+}
+
+// breakWord splits the word into pieces of at most size runes
+func breakWord(word string, size int) []string {
+	runes := []rune(word)
+	if size <= 0 || len(runes) <= size {
+		return []string{word}
 	}
 
-	return chunks
+	var pieces []string
+	for len(runes) > size {
+		pieces = append(pieces, string(runes[:size]))
+		runes = runes[size:]
+	}
+	return append(pieces, string(runes))
 }
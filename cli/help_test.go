@@ -2,8 +2,15 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
 	"strings"
 	"testing"
+	"text/template"
 )
 
 func TestItCanDisplayHelpfulInformationAboutAvailableCommands(t *testing.T) {
@@ -60,6 +67,19 @@ func TestItCanDisplayHelpfulInformationAboutAvailableCommands(t *testing.T) {
 	if !strings.Contains(output, "--test-flag") {
 		t.Errorf("Help output doesn't contain flag name")
 	}
+
+	// Check the spacing is made of clean blank lines, a single one between entries
+	for i, line := range strings.Split(output, "\n") {
+		if line == "\t" || strings.TrimRight(line, " \t") != line {
+			t.Errorf("Help output line %d has trailing whitespace: %q", i+1, line)
+		}
+	}
+	if strings.Contains(output, "\n\n\n") {
+		t.Errorf("Help output should not contain consecutive blank lines:\n%s", output)
+	}
+	if !strings.Contains(output, "A test flag\n\ntest-cmd") {
+		t.Errorf("Help output entries should be separated by one blank line:\n%s", output)
+	}
 }
 
 func TestItCanChunkDescription(t *testing.T) {
@@ -86,9 +106,10 @@ func TestItCanChunkDescription(t *testing.T) {
 			description: "This is a longer description that should be split into multiple chunks",
 			size:        20,
 			want: []string{
-				"This is a longer description",
-				"that should be split",
-				"into multiple chunks",
+				"This is a longer",
+				"description that",
+				"should be split into",
+				"multiple chunks",
 			},
 		},
 		{
@@ -101,6 +122,50 @@ func TestItCanChunkDescription(t *testing.T) {
 				"Third line",
 			},
 		},
+		{
+			name:        "accented description at the wrap boundary",
+			description: "éé éé éé",
+			size:        5,
+			want: []string{
+				"éé éé",
+				"éé",
+			},
+		},
+		{
+			name:        "emoji description at the wrap boundary",
+			description: "🙂🙂 🙂🙂 🙂",
+			size:        5,
+			want: []string{
+				"🙂🙂 🙂🙂",
+				"🙂",
+			},
+		},
+		{
+			name:        "word longer than the size",
+			description: "Short " + strings.Repeat("x", 100) + " end",
+			size:        20,
+			want: []string{
+				"Short",
+				strings.Repeat("x", 20),
+				strings.Repeat("x", 20),
+				strings.Repeat("x", 20),
+				strings.Repeat("x", 20),
+				strings.Repeat("x", 20),
+				"end",
+			},
+		},
+		{
+			name:        "no-space description",
+			description: strings.Repeat("abcde", 20),
+			size:        20,
+			want: []string{
+				strings.Repeat("abcde", 4),
+				strings.Repeat("abcde", 4),
+				strings.Repeat("abcde", 4),
+				strings.Repeat("abcde", 4),
+				strings.Repeat("abcde", 4),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -124,3 +189,829 @@ func TestItCanChunkDescription(t *testing.T) {
 		)
 	}
 }
+
+func TestItCanDisplayHelpAsJson(t *testing.T) {
+	helpCmd := NewHelpCommand(
+		[]Command{
+			&MockCommand{id: "test-cmd", description: "Test command description"},
+			&MockCommandWithFlags{id: "flag-cmd", description: "Command with flagSet"},
+		},
+	)
+
+	var buf bytes.Buffer
+	if err := runCommand(context.Background(), helpCmd, []string{"--format", "json"}, &buf, &buf); err != nil {
+		t.Fatalf("HelpCommand error = %v, want nil", err)
+	}
+
+	var entries []struct {
+		Id          string   `json:"id"`
+		Description string   `json:"description"`
+		Aliases     []string `json:"aliases"`
+		Flags       []struct {
+			Name    string `json:"name"`
+			Usage   string `json:"usage"`
+			Default string `json:"default"`
+			Type    string `json:"type"`
+		} `json:"flags"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("Help output is not valid json: %v\n%s", err, buf.String())
+	}
+
+	// Commands are sorted by id
+	if len(entries) != 2 || entries[0].Id != "flag-cmd" || entries[1].Id != "test-cmd" {
+		t.Fatalf("Unexpected help entries %+v", entries)
+	}
+	if entries[1].Description != "Test command description" || len(entries[1].Flags) != 0 {
+		t.Errorf("Unexpected entry for test-cmd %+v", entries[1])
+	}
+	if entries[1].Aliases == nil {
+		t.Errorf("Aliases should be an empty array, not null")
+	}
+
+	flags := entries[0].Flags
+	if len(flags) != 1 || flags[0].Name != "test-flag" || flags[0].Usage != "A test flag" ||
+		flags[0].Default != "" || flags[0].Type != "string" {
+		t.Errorf("Unexpected flags for flag-cmd %+v", flags)
+	}
+}
+
+func TestItRejectsUnknownHelpFormats(t *testing.T) {
+	var buf bytes.Buffer
+	err := runCommand(context.Background(), NewHelpCommand(nil), []string{"--format", "xml"}, &buf, &buf)
+	if err == nil {
+		t.Errorf("HelpCommand should reject an unknown format")
+	}
+}
+
+func TestItCanListOnlyCommandIdsInHelp(t *testing.T) {
+	executed := ""
+	helpCmd := NewHelpCommand(
+		[]Command{
+			&MockCommand{id: "test-cmd", description: "Test command description"},
+			&MockCommandWithFlags{id: "flag-cmd", description: "Command with flagSet"},
+			newTestDbGroup(&executed),
+		},
+	)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "all commands",
+			args: []string{"--list"},
+			want: "db\ndb migrate\ndb migrate down\ndb migrate up\nflag-cmd\ntest-cmd\n",
+		},
+		{
+			name: "filtered commands",
+			args: []string{"--list", "cmd"},
+			want: "flag-cmd\ntest-cmd\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := runCommand(context.Background(), helpCmd, tt.args, &buf, &buf); err != nil {
+					t.Fatalf("HelpCommand error = %v, want nil", err)
+				}
+				if buf.String() != tt.want {
+					t.Errorf("HelpCommand output = %q, want %q", buf.String(), tt.want)
+				}
+			},
+		)
+	}
+
+	var buf bytes.Buffer
+	err := runCommand(context.Background(), helpCmd, []string{"--list", "--format", "json"}, &buf, &buf)
+	if err == nil {
+		t.Errorf("HelpCommand should reject --list with the json format")
+	}
+}
+
+// MockPanickingFlagsCommand is a Command implementation whose DefineFlags panics
+type MockPanickingFlagsCommand struct {
+	MockCommand
+}
+
+func (m *MockPanickingFlagsCommand) DefineFlags(flagSet *flag.FlagSet) {
+	panic("database unreachable")
+}
+
+func TestItRendersHelpWhenACommandFailsToDefineItsFlags(t *testing.T) {
+	helpCmd := NewHelpCommand(
+		[]Command{
+			&MockPanickingFlagsCommand{MockCommand{id: "broken-cmd", description: "Broken command"}},
+			&MockCommandWithFlags{id: "flag-cmd", description: "Command with flagSet"},
+			&MockCommand{id: "test-cmd", description: "Test command description"},
+		},
+	)
+
+	var buf bytes.Buffer
+	if err := helpCmd.Exec(&buf); err != nil {
+		t.Fatalf("HelpCommand.Exec() error = %v, want nil", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"broken-cmd",
+		"Broken command",
+		"Flags: (failed to render: defining the flags panicked: database unreachable)",
+		"--test-flag",
+		"test-cmd",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Help output should contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	if info := Describe(helpCmd.availableCommands[0]); len(info.Flags) != 0 {
+		t.Errorf("Describe() flags = %+v, want none", info.Flags)
+	}
+}
+
+// MockCategorizedCommand is a CategorizedCommand implementation for testing
+type MockCategorizedCommand struct {
+	MockCommand
+	category string
+}
+
+func (m *MockCategorizedCommand) Category() string {
+	return m.category
+}
+
+func TestItGroupsCommandsByCategoryInHelp(t *testing.T) {
+	helpCmd := NewHelpCommand(
+		[]Command{
+			&MockCategorizedCommand{MockCommand{id: "report-b"}, "Reporting"},
+			&MockCommand{id: "plain"},
+			&MockCategorizedCommand{MockCommand{id: "migrate"}, "Database"},
+			&MockCategorizedCommand{MockCommand{id: "report-a"}, "Reporting"},
+			NewLockableCommand(&MockCategorizedCommand{MockCommand{id: "backup"}, "Database"}, t.TempDir()),
+		},
+	)
+
+	var buf bytes.Buffer
+	if err := helpCmd.Exec(&buf); err != nil {
+		t.Fatalf("HelpCommand.Exec() error = %v, want nil", err)
+	}
+
+	output := buf.String()
+	wantOrder := []string{"Database:", "backup", "migrate", "General:", "plain", "Reporting:", "report-a", "report-b"}
+	lastIndex := -1
+	for _, want := range wantOrder {
+		index := strings.Index(output, want)
+		if index <= lastIndex {
+			t.Fatalf("Help output should list %q after the previous entries:\n%s", want, output)
+		}
+		lastIndex = index
+	}
+}
+
+// MockExampleCommand is an ExampleProvider implementation for testing
+type MockExampleCommand struct {
+	MockCommandWithFlags
+	examples []string
+}
+
+func (m *MockExampleCommand) Examples() []string {
+	return m.examples
+}
+
+func TestItRendersCommandExamplesInHelp(t *testing.T) {
+	examples := []string{"app greet --test-flag john", "app greet --test-flag 'jane doe'"}
+	helpCmd := NewHelpCommand(
+		[]Command{
+			&MockExampleCommand{MockCommandWithFlags{id: "greet"}, examples},
+			&MockCommand{id: "plain"},
+		},
+	)
+
+	var buf bytes.Buffer
+	if err := helpCmd.Exec(&buf); err != nil {
+		t.Fatalf("HelpCommand.Exec() error = %v, want nil", err)
+	}
+
+	output := buf.String()
+	if strings.Count(output, "Examples:") != 1 {
+		t.Errorf("Help output should contain exactly one examples section:\n%s", output)
+	}
+	flagsIndex := strings.Index(output, "--test-flag (default")
+	for _, example := range examples {
+		index := strings.Index(output, example)
+		if index < 0 || index < flagsIndex {
+			t.Errorf("Help output should contain example %q beneath the flags:\n%s", example, output)
+		}
+	}
+
+	// The examples are aligned with the flags section
+	flagsColumn, examplesColumn := -1, -1
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasSuffix(strings.TrimSpace(line), "Flags:") {
+			flagsColumn = strings.Index(line, "Flags:")
+		}
+		if strings.Contains(line, examples[0]) {
+			examplesColumn = strings.Index(line, examples[0])
+		}
+	}
+	if flagsColumn < 0 || flagsColumn != examplesColumn {
+		t.Errorf("Examples should be aligned with the flags section:\n%s", output)
+	}
+}
+
+// MockExitCodeCommand is an ExitCodeDocumenter implementation for testing
+type MockExitCodeCommand struct {
+	MockCommand
+	exitCodes map[int]string
+}
+
+func (m *MockExitCodeCommand) ExitCodes() map[int]string {
+	return m.exitCodes
+}
+
+func TestItRendersCommandExitCodesInHelp(t *testing.T) {
+	exitCodes := map[int]string{3: "the input is invalid", 0: "the report was sent"}
+	helpCmd := NewHelpCommand(
+		[]Command{
+			&MockExitCodeCommand{MockCommand{id: "report"}, exitCodes},
+			&MockCommand{id: "plain"},
+		},
+	)
+
+	var buf bytes.Buffer
+	if err := helpCmd.Exec(&buf); err != nil {
+		t.Fatalf("HelpCommand.Exec() error = %v, want nil", err)
+	}
+
+	output := buf.String()
+	if strings.Count(output, "Exit codes:") != 1 {
+		t.Errorf("Help output should contain exactly one exit codes section:\n%s", output)
+	}
+	sectionIndex := strings.Index(output, "Exit codes:")
+	successIndex := strings.Index(output, "0: the report was sent")
+	invalidIndex := strings.Index(output, "3: the input is invalid")
+	if sectionIndex < 0 || successIndex < sectionIndex || invalidIndex < successIndex {
+		t.Errorf("Help output should list the exit codes sorted, beneath the section:\n%s", output)
+	}
+	if plainIndex := strings.Index(output, "plain"); plainIndex > sectionIndex {
+		t.Errorf("Help output should render the exit codes of the report command only:\n%s", output)
+	}
+
+	if info := Describe(helpCmd.availableCommands[0]); len(info.ExitCodes) != 2 {
+		t.Errorf("Describe() exit codes = %v, want %v", info.ExitCodes, exitCodes)
+	}
+}
+
+func TestItCanFilterCommandsInHelp(t *testing.T) {
+	availableCommands := []Command{
+		&MockCommand{id: "db-migrate", description: "Runs the migrations"},
+		&MockCommand{id: "cache-clear", description: "Clears the DB query cache"},
+		&MockCommand{id: "greet", description: "Says hello"},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "no filter",
+			args:     nil,
+			want:     []string{"db-migrate", "cache-clear", "greet"},
+			unwanted: []string{"No commands match"},
+		},
+		{
+			name:     "filter matching id and description case-insensitively",
+			args:     []string{"DB"},
+			want:     []string{"db-migrate", "cache-clear"},
+			unwanted: []string{"greet", "No commands match"},
+		},
+		{
+			name:     "filter matching nothing",
+			args:     []string{"deploy"},
+			want:     []string{`No commands match "deploy"`},
+			unwanted: []string{"db-migrate", "cache-clear", "greet"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				err := runCommand(
+					context.Background(),
+					NewHelpCommand(availableCommands),
+					tt.args,
+					&buf,
+					&buf,
+				)
+				if err != nil {
+					t.Fatalf("HelpCommand error = %v, want nil", err)
+				}
+
+				output := buf.String()
+				for _, want := range tt.want {
+					if !strings.Contains(output, want) {
+						t.Errorf("Help output should contain %q:\n%s", want, output)
+					}
+				}
+				for _, unwanted := range tt.unwanted {
+					if strings.Contains(output, unwanted) {
+						t.Errorf("Help output should not contain %q:\n%s", unwanted, output)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItCanOverrideTheBuiltInHelpCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		registerHelp bool
+		opts         []BootstrapOption
+		wantOutput   string
+		wantExitCode int
+	}{
+		{
+			name:         "built-in help",
+			wantOutput:   "Lists all available commands",
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "user help takes precedence",
+			registerHelp: true,
+			wantOutput:   "custom help",
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "user help without default help",
+			registerHelp: true,
+			opts:         []BootstrapOption{WithoutDefaultHelp()},
+			wantOutput:   "custom help",
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "no help at all",
+			opts:         []BootstrapOption{WithoutDefaultHelp()},
+			wantOutput:   "",
+			wantExitCode: StatusErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(&MockCommand{id: "test-cmd"})
+				if tt.registerHelp {
+					_ = registry.Register(
+						&MockCommand{
+							id: "help",
+							execFunc: func(writer io.Writer) error {
+								_, _ = fmt.Fprint(writer, "custom help")
+								return nil
+							},
+						},
+					)
+				}
+
+				var buf bytes.Buffer
+				exitCode := -1
+				opts := append([]BootstrapOption{WithErrorWriter(&bytes.Buffer{})}, tt.opts...)
+				Bootstrap([]string{}, registry, &buf, func(code int) { exitCode = code }, opts...)
+
+				if exitCode != tt.wantExitCode {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, tt.wantExitCode)
+				}
+				if !strings.Contains(buf.String(), tt.wantOutput) {
+					t.Errorf("Help output should contain %q, got:\n%s", tt.wantOutput, buf.String())
+				}
+				if tt.wantOutput == "" && buf.Len() != 0 {
+					t.Errorf("Help output should be empty, got:\n%s", buf.String())
+				}
+			},
+		)
+	}
+}
+
+func TestItFitsHelpIntoTheGivenWidth(t *testing.T) {
+	description := strings.Repeat("A fairly long command description ", 8)
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "narrow width", args: []string{"--width", "40"}},
+		{name: "default width", args: []string{"--width", "80"}},
+		{name: "wide width", args: []string{"--width", "160"}},
+		{name: "negative width", args: []string{"--width", "-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				helpCmd := NewHelpCommand(
+					[]Command{&MockCommandWithFlags{id: "test-cmd", description: description}},
+				)
+
+				var buf bytes.Buffer
+				err := runCommand(context.Background(), helpCmd, tt.args, &buf, io.Discard)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("HelpCommand error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				width := helpCmd.width
+				longestLine := 0
+				for _, line := range strings.Split(buf.String(), "\n") {
+					longestLine = max(longestLine, len(strings.TrimRight(line, " ")))
+				}
+				if longestLine > width {
+					t.Errorf("Help output is %d columns wide, want at most %d:\n%s", longestLine, width, buf.String())
+				}
+				if longestLine < width-20 {
+					t.Errorf("Help output is %d columns wide, want close to %d:\n%s", longestLine, width, buf.String())
+				}
+			},
+		)
+	}
+}
+
+// MockHiddenCommand is a HideableCommand implementation for testing
+type MockHiddenCommand struct {
+	MockCommand
+	hidden bool
+}
+
+func (m *MockHiddenCommand) Hidden() bool {
+	return m.hidden
+}
+
+func TestItCanHideCommandsFromHelp(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantVisible []string
+		wantHidden  []string
+	}{
+		{
+			name:        "hidden commands are excluded by default",
+			args:        []string{},
+			wantVisible: []string{"deploy", "status"},
+			wantHidden:  []string{"reindex"},
+		},
+		{
+			name:        "hidden commands are listed with --all",
+			args:        []string{"--all"},
+			wantVisible: []string{"deploy", "status", "reindex"},
+		},
+		{
+			name:        "hidden commands are excluded from the id list",
+			args:        []string{"--list"},
+			wantVisible: []string{"deploy", "status"},
+			wantHidden:  []string{"reindex"},
+		},
+		{
+			name:        "hidden commands are excluded from json",
+			args:        []string{"--format", "json"},
+			wantVisible: []string{"deploy", "status"},
+			wantHidden:  []string{"reindex"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				helpCmd := NewHelpCommand(
+					[]Command{
+						&MockCommand{id: "deploy"},
+						&MockHiddenCommand{MockCommand{id: "reindex"}, true},
+						&MockHiddenCommand{MockCommand{id: "status"}, false},
+					},
+				)
+
+				var buf bytes.Buffer
+				if err := runCommand(context.Background(), helpCmd, tt.args, &buf, &buf); err != nil {
+					t.Fatalf("HelpCommand error = %v, want nil", err)
+				}
+
+				for _, id := range tt.wantVisible {
+					if !strings.Contains(buf.String(), id) {
+						t.Errorf("Help output should contain %q:\n%s", id, buf.String())
+					}
+				}
+				for _, id := range tt.wantHidden {
+					if strings.Contains(buf.String(), id) {
+						t.Errorf("Help output should not contain %q:\n%s", id, buf.String())
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItCanRunHiddenCommandsById(t *testing.T) {
+	executed := false
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockHiddenCommand{
+			MockCommand{
+				id: "reindex",
+				execFunc: func(writer io.Writer) error {
+					executed = true
+					return nil
+				},
+			},
+			true,
+		},
+	)
+
+	exitCode, err := Run([]string{"reindex"}, registry, io.Discard)
+
+	if exitCode != StatusOk || err != nil || !executed {
+		t.Errorf("Run() = (%d, %v), executed %v, want the hidden command executed", exitCode, err, executed)
+	}
+}
+
+func TestItCanRenderTheHelpOfASingleCommand(t *testing.T) {
+	executed := ""
+	availableCommands := []Command{
+		&MockExampleCommand{
+			MockCommandWithFlags{id: "greet", description: "Says hello"},
+			[]string{"app greet --test-flag john"},
+		},
+		&MockExitCodeCommand{
+			MockCommand{id: "report", description: "Sends the report"},
+			map[int]string{3: "the input is invalid", 0: "the report was sent"},
+		},
+		&MockHiddenCommand{MockCommand{id: "reindex", description: "Rebuilds the index"}, true},
+		newTestDbGroup(&executed),
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "overview list without a command id",
+			args:     nil,
+			want:     []string{"greet", "report", "db", "Sends the report"},
+			unwanted: []string{"Usage:"},
+		},
+		{
+			name: "command with flags and examples",
+			args: []string{"greet"},
+			want: []string{
+				"Usage: greet [flags]\n\nSays hello\n",
+				"Flags:\n  --test-flag string (default )\n    A test flag\n",
+				"Examples:\n  app greet --test-flag john\n",
+			},
+			unwanted: []string{"report", "db"},
+		},
+		{
+			name: "command with exit codes",
+			args: []string{"report"},
+			want: []string{
+				"Usage: report\n",
+				"Flags: none",
+				"Exit codes:\n  0: the report was sent\n  3: the input is invalid\n",
+			},
+			unwanted: []string{"greet"},
+		},
+		{
+			name: "hidden command",
+			args: []string{"reindex"},
+			want: []string{"Usage: reindex\n\nRebuilds the index\n"},
+		},
+		{
+			name: "nested command group",
+			args: []string{"db", "migrate"},
+			want: []string{
+				"Usage: db migrate <subcommand>\n\nDatabase migrations\n",
+				"Subcommands:\n",
+				"down",
+				"Revert migrations",
+			},
+			unwanted: []string{"Flags:", "greet"},
+		},
+		{
+			name:     "json output",
+			args:     []string{"--format", "json", "db", "migrate", "up"},
+			want:     []string{`"id": "up"`, `"name": "test-flag"`},
+			unwanted: []string{`"id": "down"`, "Usage:"},
+		},
+		{
+			name:     "unknown subcommand",
+			args:     []string{"db", "migrate", "dwn"},
+			want:     []string{`No command "db migrate dwn" found`, "Did you mean 'down'?"},
+			unwanted: []string{"Usage:"},
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"gret"},
+			want:     []string{`No commands match "gret"`, "Did you mean 'greet'?"},
+			unwanted: []string{"Usage:", "report"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				err := runCommand(
+					context.Background(),
+					NewHelpCommand(availableCommands),
+					append([]string{"--width", "80"}, tt.args...),
+					&buf,
+					&buf,
+				)
+				if err != nil {
+					t.Fatalf("HelpCommand error = %v, want nil", err)
+				}
+
+				output := buf.String()
+				for _, want := range tt.want {
+					if !strings.Contains(output, want) {
+						t.Errorf("Help output should contain %q:\n%s", want, output)
+					}
+				}
+				for _, unwanted := range tt.unwanted {
+					if strings.Contains(output, unwanted) {
+						t.Errorf("Help output should not contain %q:\n%s", unwanted, output)
+					}
+				}
+			},
+		)
+	}
+}
+
+// MockFlagGroupsCommand is a FlagGroupsProvider implementation for testing
+type MockFlagGroupsCommand struct {
+	MockCommand
+}
+
+func (m *MockFlagGroupsCommand) DefineFlags(flagSet *flag.FlagSet) {
+	flagSet.String("host", "localhost", "The database host")
+	flagSet.Int("port", 5432, "The database port")
+	flagSet.String("format", "text", "The output format")
+	flagSet.Bool("verbose", false, "Log every query")
+}
+
+func (m *MockFlagGroupsCommand) FlagGroups() map[string][]string {
+	return map[string][]string{
+		"Output":     {"format"},
+		"Connection": {"port", "host"},
+	}
+}
+
+func TestItRendersFlagGroupsInHelp(t *testing.T) {
+	cmd := &MockFlagGroupsCommand{MockCommand{id: "query", description: "Runs a query"}}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "overview",
+			args: nil,
+			want: []string{
+				"Connection flags:", "--host (default localhost)", "--port (default 5432)",
+				"Output flags:", "--format (default text)",
+				"Other flags:", "--verbose (default false)",
+			},
+		},
+		{
+			name: "single command",
+			args: []string{"query"},
+			want: []string{
+				"\nConnection flags:\n", "  --host string (default localhost)", "  --port int (default 5432)",
+				"\nOutput flags:\n", "  --format string (default text)",
+				"\nOther flags:\n", "  --verbose bool (default false)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				err := runCommand(context.Background(), NewHelpCommand([]Command{cmd}), tt.args, &buf, &buf)
+				if err != nil {
+					t.Fatalf("HelpCommand error = %v, want nil", err)
+				}
+
+				// The groups are sorted by name, the ungrouped flags last
+				output, previousIndex := buf.String(), -1
+				for _, want := range tt.want {
+					index := strings.Index(output, want)
+					if index <= previousIndex {
+						t.Errorf("Help output should contain %q, in order:\n%s", want, output)
+					}
+					previousIndex = index
+				}
+				if strings.Contains(output, "\tFlags:") || strings.Contains(output, "\nFlags:") {
+					t.Errorf("Help output should only list the flag groups:\n%s", output)
+				}
+			},
+		)
+	}
+
+	groups := make(map[string]string)
+	for _, flagInfo := range Describe(cmd).Flags {
+		groups[flagInfo.Name] = flagInfo.Group
+	}
+	wantGroups := map[string]string{"host": "Connection", "port": "Connection", "format": "Output", "verbose": ""}
+	if !maps.Equal(groups, wantGroups) {
+		t.Errorf("Describe() flag groups = %v, want %v", groups, wantGroups)
+	}
+}
+
+func TestItCanRenderHelpWithATemplate(t *testing.T) {
+	availableCommands := []Command{
+		&MockCommandWithFlags{id: "greet", description: "Says hello"},
+		&MockCategorizedCommand{MockCommand{id: "migrate", description: "Runs the migrations"}, "Database"},
+		&MockHiddenCommand{MockCommand{id: "reindex"}, true},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		args     []string
+		want     string
+	}{
+		{
+			name:     "custom template",
+			template: "{{range .}}{{.Category}}/{{.Id}}: {{len .Flags}} flag(s)\n{{end}}",
+			want:     "Database/migrate: 0 flag(s)\nGeneral/greet: 1 flag(s)\n",
+		},
+		{
+			name:     "custom template with aligned columns",
+			template: "{{range .}}{{.Id}}\t{{.Description}}\n{{end}}",
+			want:     "migrate    Runs the migrations\ngreet      Says hello\n",
+		},
+		{
+			name:     "filtered commands",
+			template: "{{range .}}{{.Id}}\n{{end}}",
+			args:     []string{"hello"},
+			want:     "greet\n",
+		},
+		{
+			name:     "default template",
+			template: DefaultHelpTemplate,
+			want: "Database:\n" +
+				"migrate      Runs the migrations\n" +
+				"             Flags: none\n" +
+				"\n" +
+				"General:\n" +
+				"greet        Says hello\n" +
+				"             Flags:\n" +
+				"             --test-flag (default )\n" +
+				"             A test flag\n" +
+				"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tmpl := template.Must(template.New("help").Funcs(HelpTemplateFuncs()).Parse(tt.template))
+				helpCmd, err := NewHelpCommandWithTemplate(availableCommands, tmpl)
+				if err != nil {
+					t.Fatalf("NewHelpCommandWithTemplate() error = %v, want nil", err)
+				}
+
+				var buf bytes.Buffer
+				if err = runCommand(context.Background(), helpCmd, tt.args, &buf, &buf); err != nil {
+					t.Fatalf("HelpCommand error = %v, want nil", err)
+				}
+				if buf.String() != tt.want {
+					t.Errorf("Help output =\n%q\nwant\n%q", buf.String(), tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestItRejectsInvalidHelpTemplates(t *testing.T) {
+	availableCommands := []Command{&MockCommand{id: "greet"}}
+
+	if _, err := NewHelpCommandWithTemplate(availableCommands, nil); err == nil {
+		t.Errorf("NewHelpCommandWithTemplate() with a nil template error = nil, want an error")
+	}
+
+	tmpl := template.Must(template.New("help").Parse("{{range .}}{{.Name}}{{end}}"))
+	_, err := NewHelpCommandWithTemplate(availableCommands, tmpl)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid help template:") {
+		t.Errorf("NewHelpCommandWithTemplate() error = %v, want an invalid help template error", err)
+	}
+}
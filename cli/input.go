@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// InputCommand is an optional interface for commands reading piped input, like a command
+// reformatting data. When a command implements it (and not ContextualCommand), ExecIO is
+// called instead of Exec, receiving the input reader, which defaults to os.Stdin.
+type InputCommand interface {
+	Command
+	ExecIO(in io.Reader, stdWriter io.Writer) error
+}
+
+// inputKey is the context key of the command input reader
+type inputKey struct{}
+
+// withInput returns a context holding the command input reader
+func withInput(ctx context.Context, in io.Reader) context.Context {
+	return context.WithValue(ctx, inputKey{}, in)
+}
+
+// Input returns the input reader of the command from the context passed to commands
+// implementing ContextualCommand, defaulting to os.Stdin.
+func Input(ctx context.Context) io.Reader {
+	if in, ok := ctx.Value(inputKey{}).(io.Reader); ok && in != nil {
+		return in
+	}
+	return os.Stdin
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+// MockInputCommand is an InputCommand implementation echoing its input
+type MockInputCommand struct {
+	MockCommand
+}
+
+func (m *MockInputCommand) ExecIO(in io.Reader, writer io.Writer) error {
+	_, err := io.Copy(writer, in)
+	return err
+}
+
+func TestItCanPassInputToInputCommands(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockInputCommand{MockCommand{id: "echo"}})
+
+	var buf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"echo"},
+		registry,
+		&buf,
+		func(code int) { exitCode = code },
+		WithInput(bytes.NewReader([]byte("piped data"))),
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+	if buf.String() != "piped data" {
+		t.Errorf("Bootstrap() output = %v, want piped data", buf.String())
+	}
+}
+
+func TestItCanPassInputToWrappedInputCommands(t *testing.T) {
+	lockableCmd := NewLockableCommand(&MockInputCommand{MockCommand{id: "echo"}}, t.TempDir())
+
+	var buf bytes.Buffer
+	ctx := withInput(context.Background(), bytes.NewReader([]byte("piped data")))
+	if err := runCommand(ctx, lockableCmd, nil, &buf, io.Discard); err != nil {
+		t.Fatalf("runCommand() error = %v", err)
+	}
+
+	if buf.String() != "piped data" {
+		t.Errorf("runCommand() output = %v, want piped data", buf.String())
+	}
+}
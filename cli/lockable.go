@@ -1,22 +1,126 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/rsgcata/go-fs"
 	"github.com/rsgcata/go-fs/filelock"
 	"io"
+	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
 )
 
 var CommandLocked = errors.New("command is locked, skipping execution")
 
-func normalizeCommandId(id string) string {
-	var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+// DefaultLockPollInterval is the interval between lock acquisition attempts, used when
+// LockOptions.LockWaitTimeout is set without a LockPollInterval
+const DefaultLockPollInterval = 100 * time.Millisecond
+
+// LockOptions configures how a FsLockableCommand acquires its lock
+type LockOptions struct {
+	// The name used for the lock file. Defaults to the Command.Id() when empty.
+	LockName string
+
+	// How long to wait for a held lock to be released. Zero fails fast, like
+	// NewLockableCommand does.
+	LockWaitTimeout time.Duration
+
+	// The interval between lock acquisition attempts while waiting
+	LockPollInterval time.Duration
+
+	// Maps the lock name to the lock file name. Defaults to DefaultLockFileName. The
+	// resulting name must be filesystem-safe, see NewLockableCommandWithOptions.
+	FileNameStrategy LockFileNameStrategy
+
+	// When greater than zero, a held lock is reclaimed if its holder process is dead
+	// (including when its PID was reused by another process) or if it was acquired
+	// longer than StaleLockMaxAge ago.
+	StaleLockMaxAge time.Duration
+
+	// When greater than zero, the context of the wrapped command is cancelled once it held
+	// the lock for MaxHoldDuration, and a TimeoutError returned, so that a stuck run does
+	// not block all the following ones. The lock is only released once the command
+	// returned, within a 5 seconds grace period. Commands ignoring their context (see
+	// ContextualCommand) keep running in the background past the grace period, the lock
+	// then staying held until the process exits.
+	MaxHoldDuration time.Duration
+
+	// Makes NewLockableCommandChecked create the lock file directory, and its parents,
+	// when it does not exist, instead of failing. Other constructors ignore it.
+	CreateLockDir bool
+}
+
+// LockAwareCommand is an optional interface for commands wrapped by a FsLockableCommand
+// which want to know they run under the lock, for example to log it. SetLockAcquired is
+// called with true once the lock is acquired, right before the command executes, and with
+// false once the lock is released. It is purely informational, a command which is not run
+// because the lock is held elsewhere is not notified.
+type LockAwareCommand interface {
+	Command
+	SetLockAcquired(acquired bool)
+}
+
+// LockFileNameStrategy maps a lock name to the name of the lock file
+type LockFileNameStrategy func(lockName string) string
+
+// DefaultLockFileName names the lock file go-cli-command-<name>-<md5 of name>.lock, the
+// hash avoiding collisions between lock names normalized to the same value
+func DefaultLockFileName(lockName string) string {
+	idHash := md5.Sum([]byte(lockName))
+	return fmt.Sprintf(
+		"go-cli-command-%s-%s.lock",
+		NormalizeId(lockName),
+		hex.EncodeToString(idHash[:]),
+	)
+}
+
+// PlainLockFileName names the lock file go-cli-command-<name>.lock, a predictable and
+// human-readable name. Lock names differing only in non-alphanumeric characters share
+// the same lock file.
+func PlainLockFileName(lockName string) string {
+	return fmt.Sprintf("go-cli-command-%s.lock", NormalizeId(lockName))
+}
+
+// validateLockFileName checks that the lock file name is a single, filesystem-safe path
+// element
+func validateLockFileName(fileName string) error {
+	if fileName == "" || fileName == "." || fileName == ".." {
+		return fmt.Errorf("invalid lock file name %q", fileName)
+	}
+	if strings.ContainsAny(fileName, "/\\<>:\"|?*") {
+		return fmt.Errorf("lock file name %q contains unsafe characters", fileName)
+	}
+	for _, char := range fileName {
+		if char < 0x20 || char == 0x7f {
+			return fmt.Errorf("lock file name %q contains control characters", fileName)
+		}
+	}
+	return nil
+}
+
+// lockInfo describes the holder of a lock, it is written into the lock file
+type lockInfo struct {
+	Pid          int       `json:"pid"`
+	AcquiredAt   time.Time `json:"acquiredAt"`
+	ProcessStart string    `json:"processStart,omitempty"`
+}
+
+// nonAlphanumericRegex matches the runs of characters replaced by NormalizeId
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// NormalizeId replaces each run of non-alphanumeric characters of the id with a single
+// dash, like "db:backup now" to "db-backup-now". It is the normalization used to name
+// lock files, which can be reused to build lock names or completion scripts.
+func NormalizeId(id string) string {
 	return nonAlphanumericRegex.ReplaceAllString(id, "-")
 }
 
@@ -28,6 +132,16 @@ type FsLockableCommand struct {
 
 	// The lock file
 	fileLock filelock.FileLock
+
+	// How long to wait for the lock and how often to retry acquiring it
+	lockWaitTimeout  time.Duration
+	lockPollInterval time.Duration
+
+	// Max age after which a held lock is considered stale, zero disables reclaiming
+	staleLockMaxAge time.Duration
+
+	// How long the wrapped command may hold the lock, zero for no limit
+	maxHoldDuration time.Duration
 }
 
 // NewLockableCommand creates a new FsLockableCommand for the given command.
@@ -46,19 +160,120 @@ func NewLockableCommandWithLockName(
 	lockFileDirPath string,
 	lockName string,
 ) *FsLockableCommand {
-	idHash := md5.Sum([]byte(lockName))
-	lockFilePath := filepath.Join(
-		lockFileDirPath,
-		fmt.Sprintf(
-			"go-cli-command-%s-%s.lock",
-			normalizeCommandId(lockName),
-			hex.EncodeToString(idHash[:]),
-		),
-	)
-	return &FsLockableCommand{
-		Command:  cmd,
-		fileLock: fs.New(lockFilePath),
+	return NewLockableCommandWithOptions(cmd, lockFileDirPath, LockOptions{LockName: lockName})
+}
+
+// NewLockGroup wraps the given commands into lockable commands sharing a single lock,
+// named lockName, making them mutually exclusive: while any of them runs, running another
+// one of the group (in this or another process) fails with CommandLocked, like running
+// the same lockable command twice does.
+func NewLockGroup(lockFileDirPath string, lockName string, cmds ...Command) []Command {
+	lockableCmds := make([]Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		lockableCmds = append(
+			lockableCmds,
+			NewLockableCommandWithLockName(cmd, lockFileDirPath, lockName),
+		)
+	}
+	return lockableCmds
+}
+
+// NewLockableCommandWithOptions creates a new FsLockableCommand for the given command,
+// configured by the given options. With a LockWaitTimeout, acquiring a held lock is
+// retried every LockPollInterval until it is released or the timeout elapses.
+// It panics if the FileNameStrategy produces a name which is empty, contains path
+// separators or characters not allowed in file names on common filesystems.
+func NewLockableCommandWithOptions(
+	cmd Command,
+	lockFileDirPath string,
+	options LockOptions,
+) *FsLockableCommand {
+	lockableCmd, err := newLockableCommand(cmd, lockFileDirPath, options)
+	if err != nil {
+		panic(err.Error())
+	}
+	return lockableCmd
+}
+
+// NewLockableCommandChecked creates a new FsLockableCommand like
+// NewLockableCommandWithOptions does, but checks up front that the lock file directory
+// exists and is writable, so that a misconfiguration is caught at startup rather than at
+// the first execution. The directory is not created, unless options.CreateLockDir is set.
+// It returns an error, instead of panicking, if the FileNameStrategy produces an invalid
+// file name.
+func NewLockableCommandChecked(
+	cmd Command,
+	lockFileDirPath string,
+	options LockOptions,
+) (*FsLockableCommand, error) {
+	if err := checkLockDir(lockFileDirPath, options.CreateLockDir); err != nil {
+		return nil, err
+	}
+	return newLockableCommand(cmd, lockFileDirPath, options)
+}
+
+// checkLockDir checks that the lock file directory exists, creating it if requested, and
+// that files can be created in it
+func checkLockDir(lockFileDirPath string, create bool) error {
+	if create {
+		if err := os.MkdirAll(lockFileDirPath, 0o755); err != nil {
+			return fmt.Errorf("failed to create lock dir %s: %w", lockFileDirPath, err)
+		}
+	}
+
+	info, err := os.Stat(lockFileDirPath)
+	if err != nil {
+		return fmt.Errorf("invalid lock dir %s: %w", lockFileDirPath, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid lock dir %s: not a directory", lockFileDirPath)
+	}
+
+	probe, err := os.CreateTemp(lockFileDirPath, ".go-cli-command-probe-*")
+	if err != nil {
+		return fmt.Errorf("lock dir %s is not writable: %w", lockFileDirPath, err)
 	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+	return nil
+}
+
+// newLockableCommand creates a new FsLockableCommand, failing if the FileNameStrategy
+// produces an invalid file name
+func newLockableCommand(
+	cmd Command,
+	lockFileDirPath string,
+	options LockOptions,
+) (*FsLockableCommand, error) {
+	lockName := options.LockName
+	if lockName == "" {
+		lockName = cmd.Id()
+	}
+
+	pollInterval := options.LockPollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultLockPollInterval
+	}
+
+	fileNameStrategy := options.FileNameStrategy
+	if fileNameStrategy == nil {
+		fileNameStrategy = DefaultLockFileName
+	}
+
+	fileName := fileNameStrategy(lockName)
+	if err := validateLockFileName(fileName); err != nil {
+		return nil, err
+	}
+
+	lockFilePath := filepath.Join(lockFileDirPath, fileName)
+	return &FsLockableCommand{
+		Command:          cmd,
+		fileLock:         fs.New(lockFilePath),
+		lockWaitTimeout:  options.LockWaitTimeout,
+		lockPollInterval: pollInterval,
+		staleLockMaxAge:  options.StaleLockMaxAge,
+		maxHoldDuration:  options.MaxHoldDuration,
+	}, nil
 }
 
 // Id returns the ID of the wrapped command.
@@ -71,6 +286,11 @@ func (l *FsLockableCommand) Description() string {
 	return l.Command.Description()
 }
 
+// Unwrap returns the wrapped command.
+func (l *FsLockableCommand) Unwrap() Command {
+	return l.Command
+}
+
 // DefineFlags delegates to the wrapped command.
 func (l *FsLockableCommand) DefineFlags(flagSet *flag.FlagSet) {
 	l.Command.DefineFlags(flagSet)
@@ -83,44 +303,272 @@ func (l *FsLockableCommand) ValidateFlags() error {
 
 // Exec acquires the lock, executes the wrapped command, and then releases the lock.
 func (l *FsLockableCommand) Exec(stdWriter io.Writer) error {
-	locked, err := l.Lock()
+	return l.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext acquires the lock, executes the wrapped command with the given context,
+// and then releases the lock. The context is forwarded to the wrapped command when it
+// implements ContextualCommand, and the lock state when it implements LockAwareCommand.
+// A failure to release the lock is joined to the error of the wrapped command, which comes
+// first, so that it still decides the exit code.
+func (l *FsLockableCommand) ExecContext(ctx context.Context, stdWriter io.Writer) (cmdErr error) {
+	locked, err := l.LockContext(ctx)
 	if err != nil {
 		return err
 	}
 
 	if locked {
-		// Ensure the lock is released when the function returns
+		lockAware, isLockAware := findOptional[LockAwareCommand](l.Command)
+		returned := true
+
+		// Deferred functions do not run when the process is force-exited after a shutdown
+		// signal, so the lock is released right before it then
+		unregisterRelease := releaseOnForceExit(ctx, func() { _ = l.Unlock() })
+
+		// Ensure the lock is released when the function returns, unless the command is
+		// still running, which would no longer be mutually exclusive
 		defer func(l *FsLockableCommand) {
-			_ = l.Unlock()
+			if !returned {
+				return
+			}
+			unregisterRelease()
+			if unlockErr := l.Unlock(); unlockErr != nil {
+				cmdErr = errors.Join(
+					cmdErr,
+					fmt.Errorf("failed to release the lock of command %s: %w", l.Id(), unlockErr),
+				)
+			}
+			if isLockAware {
+				lockAware.SetLockAcquired(false)
+			}
 		}(l)
 
-		// Execute the wrapped command
-		return l.Command.Exec(stdWriter)
+		if isLockAware {
+			lockAware.SetLockAcquired(true)
+		}
+
+		// Execute the wrapped command, within the max hold duration if there is one
+		if l.maxHoldDuration > 0 {
+			returned, cmdErr = execCommandWithinTimeout(ctx, l.Command, stdWriter, l.maxHoldDuration)
+			return cmdErr
+		}
+		return execCommand(ctx, l.Command, stdWriter)
 	} else {
-		return CommandLocked
+		return l.lockedError()
+	}
+}
+
+// lockedError wraps CommandLocked with the command id, the lock file path and, when it
+// can be read from the lock file, the PID of the lock holder
+func (l *FsLockableCommand) lockedError() error {
+	if pid, _, err := l.LockInfo(); err == nil && pid > 0 {
+		return fmt.Errorf(
+			"%w: command %s, lock file %s held by pid %d",
+			CommandLocked,
+			l.Command.Id(),
+			l.fileLock.Path(),
+			pid,
+		)
 	}
+	return fmt.Errorf("%w: command %s, lock file %s", CommandLocked, l.Command.Id(), l.fileLock.Path())
 }
 
 // Lock acquires both the in-memory mutex and the file lock.
 // If the lock cannot be acquired, it returns an error.
 func (l *FsLockableCommand) Lock() (bool, error) {
-	err := l.fileLock.Lock()
-	if err != nil {
+	return l.LockContext(context.Background())
+}
+
+// LockContext acquires the file lock, waiting up to the configured lock wait timeout
+// for it to be released if it is held. It returns false, without an error, if the lock
+// is still held after the timeout. The wait is aborted when the context is cancelled.
+func (l *FsLockableCommand) LockContext(ctx context.Context) (bool, error) {
+	var deadline <-chan time.Time
+	if l.lockWaitTimeout > 0 {
+		timer := time.NewTimer(l.lockWaitTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		err := l.fileLock.Lock()
+		if err == nil {
+			l.writeLockInfo()
+			return true, nil
+		}
+
 		if errors.Is(err, filelock.ErrLockHeld) {
-			return false, nil
-		} else {
+			if staleContent, stale := l.heldLockStaleContent(); stale {
+				if err = l.reclaimStaleLock(staleContent); err == nil {
+					return true, nil
+				}
+			}
+		}
+
+		if !errors.Is(err, filelock.ErrLockHeld) {
 			return false, fmt.Errorf(
 				"failed to acquire lock for command %s: %w",
 				l.Id(),
 				err,
 			)
 		}
-	}
 
-	return true, nil
+		if deadline == nil {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-deadline:
+			return false, nil
+		case <-time.After(l.lockPollInterval):
+		}
+	}
 }
 
 // Unlock releases both the in-memory mutex and the file lock.
 func (l *FsLockableCommand) Unlock() error {
+	if l.fileLock.IsLocked() {
+		_ = os.Truncate(l.fileLock.Path(), 0)
+	}
 	return l.fileLock.Unlock()
 }
+
+// LockInfo returns the PID of the process holding (or which last held) the lock and
+// when it acquired it, as recorded in the lock file.
+func (l *FsLockableCommand) LockInfo() (pid int, acquiredAt time.Time, err error) {
+	info, err := readLockInfo(l.fileLock.Path())
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return info.Pid, info.AcquiredAt, nil
+}
+
+// writeLockInfo records the current process as the lock holder. It is best effort,
+// locking does not fail if the info cannot be written.
+func (l *FsLockableCommand) writeLockInfo() {
+	pid := os.Getpid()
+	content, err := json.Marshal(
+		lockInfo{
+			Pid:          pid,
+			AcquiredAt:   time.Now(),
+			ProcessStart: processStartId(pid),
+		},
+	)
+	if err == nil {
+		_ = os.WriteFile(l.fileLock.Path(), content, 0666)
+	}
+}
+
+// heldLockStaleContent reports whether the held lock should be reclaimed, according to
+// the stale lock max age option and to the recorded lock holder info. It returns the
+// content of the stale lock file, for reclaimStaleLock to check that it was not reclaimed
+// in the meantime.
+func (l *FsLockableCommand) heldLockStaleContent() ([]byte, bool) {
+	if l.staleLockMaxAge <= 0 {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(l.fileLock.Path())
+	if err != nil {
+		return nil, false
+	}
+	info, err := parseLockInfo(l.fileLock.Path(), content)
+	if err != nil {
+		// The holder may not have written its info yet
+		return nil, false
+	}
+
+	if time.Since(info.AcquiredAt) > l.staleLockMaxAge || !processAlive(info.Pid) {
+		return content, true
+	}
+
+	// The PID is alive but may have been reused by a different process
+	currentStart := processStartId(info.Pid)
+	return content, info.ProcessStart != "" && currentStart != "" && currentStart != info.ProcessStart
+}
+
+// reclaimStaleLock removes the stale lock file and acquires a lock on a new one. Processes
+// reclaiming the same lock are serialized by a guard file lock, and the lock file must
+// still hold the stale content, otherwise another process already reclaimed it and
+// filelock.ErrLockHeld is returned, so that only one of them ends up holding the lock.
+func (l *FsLockableCommand) reclaimStaleLock(staleContent []byte) (err error) {
+	path := l.fileLock.Path()
+	guard, err := lockGuardFile(path+".reclaim", 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlockGuardFile(guard); unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+
+	if content, readErr := os.ReadFile(path); readErr != nil || !bytes.Equal(content, staleContent) {
+		return filelock.ErrLockHeld
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	l.fileLock = fs.New(path)
+	if err = l.fileLock.Lock(); err != nil {
+		return err
+	}
+	l.writeLockInfo()
+	return nil
+}
+
+// lockGuardFile locks a short-lived guard file, removed by unlockGuardFile so that guards
+// do not pile up next to the lock files. A process may lock a guard file that was removed
+// in the meantime, so the lock is only kept if the file at the path was the same before
+// and after locking it, otherwise it is retried on the file now at the path.
+func lockGuardFile(path string, timeout time.Duration) (filelock.FileLock, error) {
+	for {
+		before, statErr := os.Stat(path)
+		if statErr != nil && !errors.Is(statErr, os.ErrNotExist) {
+			return nil, statErr
+		}
+
+		guard := fs.New(path)
+		if err := guard.LockWithTimeout(timeout); err != nil {
+			return nil, err
+		}
+		after, err := os.Stat(path)
+		if statErr == nil && err == nil && os.SameFile(before, after) {
+			return guard, nil
+		}
+		_ = guard.Unlock()
+	}
+}
+
+// unlockGuardFile removes the guard file locked by lockGuardFile, while still holding it,
+// then unlocks it. Removing it is best effort, it fails on Windows while the file is open.
+func unlockGuardFile(guard filelock.FileLock) error {
+	_ = os.Remove(guard.Path())
+	return guard.Unlock()
+}
+
+// readLockInfo reads the lock holder info from the lock file
+func readLockInfo(lockFilePath string) (lockInfo, error) {
+	content, err := os.ReadFile(lockFilePath)
+	if err != nil {
+		return lockInfo{}, err
+	}
+	return parseLockInfo(lockFilePath, content)
+}
+
+// parseLockInfo parses the lock holder info read from the lock file
+func parseLockInfo(lockFilePath string, content []byte) (lockInfo, error) {
+	var info lockInfo
+	if len(content) == 0 {
+		return info, fmt.Errorf("lock file %s holds no lock info", lockFilePath)
+	}
+
+	if err := json.Unmarshal(content, &info); err != nil {
+		return info, fmt.Errorf("lock file %s holds malformed lock info: %w", lockFilePath, err)
+	}
+
+	return info, nil
+}
@@ -2,8 +2,18 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rsgcata/go-fs"
+	"github.com/rsgcata/go-fs/filelock"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -183,3 +193,812 @@ func TestLockableCommandHelper_ConcurrentExecution(t *testing.T) {
 		t.Fatalf("Expected second execution to fail, but it succeeded")
 	}
 }
+
+func TestLockableCommandHelper_ForwardsContext(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var received any
+	mockCmd := &MockContextCommand{
+		MockCommand: MockCommand{id: "ctx-command"},
+		execContextFunc: func(ctx context.Context, writer io.Writer) error {
+			received = ctx.Value(ctxKey("key"))
+			return nil
+		},
+	}
+
+	helper := NewLockableCommand(mockCmd, tempDir)
+	ctx := context.WithValue(context.Background(), ctxKey("key"), "value")
+	if err := helper.ExecContext(ctx, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	if received != "value" {
+		t.Fatalf("Wrapped command received context value %v, want value", received)
+	}
+}
+
+// MockLockAwareCommand records the lock states it is notified of
+type MockLockAwareCommand struct {
+	MockLockableCommand
+	lockStates []bool
+}
+
+func (m *MockLockAwareCommand) SetLockAcquired(acquired bool) {
+	m.lockStates = append(m.lockStates, acquired)
+}
+
+func TestLockableCommandHelper_NotifiesLockAwareCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	mockCmd := &MockLockAwareCommand{MockLockableCommand: MockLockableCommand{id: "lock-aware"}}
+	var acquiredDuringExec bool
+	mockCmd.execFunc = func() error {
+		acquiredDuringExec = len(mockCmd.lockStates) == 1 && mockCmd.lockStates[0]
+		return nil
+	}
+
+	helper := NewLockableCommand(NewRetryableCommand(mockCmd, 1, nil), tempDir)
+	if err := helper.Exec(&bytes.Buffer{}); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	if !acquiredDuringExec {
+		t.Errorf("Command was not notified of the acquired lock before executing")
+	}
+	if len(mockCmd.lockStates) != 2 || mockCmd.lockStates[1] {
+		t.Errorf("Lock states = %v, want [true false]", mockCmd.lockStates)
+	}
+
+	mockCmd.lockStates = nil
+	holder := NewLockableCommand(&MockLockableCommand{id: "lock-aware"}, tempDir)
+	if locked, err := holder.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire the lock: %v", err)
+	}
+	defer func() { _ = holder.Unlock() }()
+
+	if err := helper.Exec(&bytes.Buffer{}); !errors.Is(err, CommandLocked) {
+		t.Fatalf("Exec() error = %v, want CommandLocked", err)
+	}
+	if len(mockCmd.lockStates) != 0 {
+		t.Errorf("Skipped command was notified of lock states %v, want none", mockCmd.lockStates)
+	}
+}
+
+// failingUnlockFileLock is a file lock which releases the lock but reports a failure
+type failingUnlockFileLock struct {
+	filelock.FileLock
+	err error
+}
+
+func (f *failingUnlockFileLock) Unlock() error {
+	_ = f.FileLock.Unlock()
+	return f.err
+}
+
+func TestLockableCommandHelper_ReportsUnlockFailures(t *testing.T) {
+	unlockErr := errors.New("disk unavailable")
+	execErr := NewExitError(3, errors.New("bad input"))
+
+	tests := []struct {
+		name         string
+		execErr      error
+		wantErrs     []error
+		wantExitCode int
+	}{
+		{
+			name:         "successful command",
+			wantErrs:     []error{unlockErr},
+			wantExitCode: StatusErr,
+		},
+		{
+			name:         "failing command",
+			execErr:      execErr,
+			wantErrs:     []error{execErr, unlockErr},
+			wantExitCode: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				mockCmd := &MockLockableCommand{
+					id:       "unlock-failure",
+					execFunc: func() error { return tt.execErr },
+				}
+				helper := NewLockableCommand(mockCmd, t.TempDir())
+				helper.fileLock = &failingUnlockFileLock{FileLock: helper.fileLock, err: unlockErr}
+
+				err := helper.Exec(&bytes.Buffer{})
+
+				for _, wantErr := range tt.wantErrs {
+					if !errors.Is(err, wantErr) {
+						t.Errorf("Exec() error = %v, want it to match %v", err, wantErr)
+					}
+				}
+				if err == nil || !strings.Contains(err.Error(), "failed to release the lock of command unlock-failure") {
+					t.Errorf("Exec() error = %v, want it to report the unlock failure", err)
+				}
+				if exitCode := exitCodeFor(err); exitCode != tt.wantExitCode {
+					t.Errorf("exitCodeFor() = %d, want %d", exitCode, tt.wantExitCode)
+				}
+			},
+		)
+	}
+}
+
+func TestLockableCommandHelper_WaitsForLockUntilTimeout(t *testing.T) {
+	tempDir := t.TempDir()
+	mockCmd := &MockLockableCommand{id: "waiting-command"}
+
+	holder := NewLockableCommand(mockCmd, tempDir)
+	if locked, err := holder.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+
+	// The lock is never released, so waiting times out
+	waiter := NewLockableCommandWithOptions(
+		mockCmd,
+		tempDir,
+		LockOptions{LockWaitTimeout: 50 * time.Millisecond, LockPollInterval: 10 * time.Millisecond},
+	)
+	start := time.Now()
+	err := waiter.Exec(&bytes.Buffer{})
+	if !errors.Is(err, CommandLocked) {
+		t.Fatalf("Exec() error = %v, want CommandLocked", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Errorf("Exec() returned before the lock wait timeout elapsed")
+	}
+	if mockCmd.executed {
+		t.Errorf("Command should not be executed while locked")
+	}
+
+	// The lock is released while waiting, so it is acquired
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = holder.Unlock()
+	}()
+	waiter = NewLockableCommandWithOptions(
+		mockCmd,
+		tempDir,
+		LockOptions{LockWaitTimeout: time.Second, LockPollInterval: 10 * time.Millisecond},
+	)
+	if err = waiter.Exec(&bytes.Buffer{}); err != nil {
+		t.Fatalf("Exec() error = %v, want nil", err)
+	}
+	if !mockCmd.executed {
+		t.Errorf("Command should be executed once the lock is released")
+	}
+}
+
+func TestLockableCommandHelper_CancelsCommandsExceedingTheMaxHoldDuration(t *testing.T) {
+	tempDir := t.TempDir()
+	cancelled := make(chan bool, 1)
+	slowCmd := &MockContextCommand{
+		MockCommand: MockCommand{id: "slow-command"},
+		execContextFunc: func(ctx context.Context, writer io.Writer) error {
+			select {
+			case <-ctx.Done():
+				cancelled <- true
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				cancelled <- false
+				return nil
+			}
+		},
+	}
+
+	helper := NewLockableCommandWithOptions(slowCmd, tempDir, LockOptions{MaxHoldDuration: 50 * time.Millisecond})
+	start := time.Now()
+	err := helper.Exec(&bytes.Buffer{})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("Exec() error = %v, want a TimeoutError after 50ms", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Exec() returned after %v, want it to stop at the max hold duration", elapsed)
+	}
+	if !<-cancelled {
+		t.Errorf("The command context should be cancelled at the max hold duration")
+	}
+
+	// The lock was released, so the next run acquires it
+	next := NewLockableCommand(&MockLockableCommand{id: "slow-command"}, tempDir)
+	if err = next.Exec(&bytes.Buffer{}); err != nil {
+		t.Errorf("Exec() after the max hold duration error = %v, want nil", err)
+	}
+
+	// Commands returning within the max hold duration are not affected
+	fastCmd := &MockLockableCommand{id: "fast-command"}
+	helper = NewLockableCommandWithOptions(fastCmd, tempDir, LockOptions{MaxHoldDuration: time.Second})
+	if err = helper.Exec(&bytes.Buffer{}); err != nil || !fastCmd.executed {
+		t.Errorf("Exec() error = %v, executed = %v, want nil and executed", err, fastCmd.executed)
+	}
+}
+
+func TestLockableCommandHelper_HoldsTheLockUntilTimedOutCommandsReturn(t *testing.T) {
+	tempDir := t.TempDir()
+	lockedDuringCleanup := make(chan bool, 1)
+	slowCmd := &MockContextCommand{
+		MockCommand: MockCommand{id: "slow-command"},
+		execContextFunc: func(ctx context.Context, writer io.Writer) error {
+			<-ctx.Done()
+			// Another run must not get the lock while this one cleans up
+			other := NewLockableCommand(&MockLockableCommand{id: "slow-command"}, tempDir)
+			locked, err := other.Lock()
+			if locked {
+				_ = other.Unlock()
+			}
+			lockedDuringCleanup <- locked || err != nil
+			return ctx.Err()
+		},
+	}
+
+	helper := NewLockableCommandWithOptions(slowCmd, tempDir, LockOptions{MaxHoldDuration: 20 * time.Millisecond})
+	err := helper.Exec(&bytes.Buffer{})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Exec() error = %v, want a TimeoutError", err)
+	}
+	if <-lockedDuringCleanup {
+		t.Errorf("Another run acquired the lock before the timed out command returned")
+	}
+	if locked, err := helper.Lock(); err != nil || !locked {
+		t.Errorf("Lock() after Exec returned = %v, %v, want the lock released", locked, err)
+	}
+	_ = helper.Unlock()
+}
+
+func TestLockableCommandHelper_KeepsTheLockOfCommandsIgnoringTheMaxHoldDuration(t *testing.T) {
+	setTimeoutGracePeriod(t, 20*time.Millisecond)
+	tempDir := t.TempDir()
+	release := make(chan struct{})
+	defer close(release)
+
+	stuckCmd := &MockContextCommand{
+		MockCommand: MockCommand{id: "stuck-command"},
+		execContextFunc: func(ctx context.Context, writer io.Writer) error {
+			<-release
+			return nil
+		},
+	}
+
+	helper := NewLockableCommandWithOptions(stuckCmd, tempDir, LockOptions{MaxHoldDuration: 20 * time.Millisecond})
+	var timeoutErr *TimeoutError
+	if err := helper.Exec(&bytes.Buffer{}); !errors.As(err, &timeoutErr) {
+		t.Fatalf("Exec() error = %v, want a TimeoutError", err)
+	}
+
+	other := NewLockableCommand(&MockLockableCommand{id: "stuck-command"}, tempDir)
+	if locked, _ := other.Lock(); locked {
+		_ = other.Unlock()
+		t.Errorf("Lock() = true, want the lock kept while the command is still running")
+	}
+}
+
+func TestLockableCommandHelper_LockWaitRespectsContext(t *testing.T) {
+	tempDir := t.TempDir()
+	mockCmd := &MockLockableCommand{id: "cancelled-command"}
+
+	holder := NewLockableCommand(mockCmd, tempDir)
+	if locked, err := holder.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+	defer func() { _ = holder.Unlock() }()
+
+	waiter := NewLockableCommandWithOptions(mockCmd, tempDir, LockOptions{LockWaitTimeout: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	locked, err := waiter.LockContext(ctx)
+	if locked || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("LockContext() = %v, %v, want false, context.DeadlineExceeded", locked, err)
+	}
+}
+
+func TestLockableCommandHelper_RecordsLockInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	helper := NewLockableCommand(&MockLockableCommand{id: "info-command"}, tempDir)
+
+	before := time.Now()
+	if locked, err := helper.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+	defer func() { _ = helper.Unlock() }()
+
+	pid, acquiredAt, err := helper.LockInfo()
+	if err != nil {
+		t.Fatalf("LockInfo() error = %v", err)
+	}
+	if pid != os.Getpid() {
+		t.Errorf("LockInfo() pid = %d, want %d", pid, os.Getpid())
+	}
+	if acquiredAt.Before(before.Add(-time.Second)) || acquiredAt.After(time.Now()) {
+		t.Errorf("LockInfo() acquiredAt = %v, want around %v", acquiredAt, before)
+	}
+}
+
+func TestLockableCommandHelper_ReclaimsStaleLocks(t *testing.T) {
+	deadProcess := exec.Command(os.Args[0], "-test.run=^$")
+	if err := deadProcess.Run(); err != nil {
+		t.Fatalf("Failed to run helper process: %v", err)
+	}
+	deadPid := deadProcess.Process.Pid
+	ownStart := processStartId(os.Getpid())
+
+	tests := []struct {
+		name        string
+		info        lockInfo
+		maxAge      time.Duration
+		wantReclaim bool
+	}{
+		{
+			name:        "fresh lock held by a live process",
+			info:        lockInfo{Pid: os.Getpid(), AcquiredAt: time.Now(), ProcessStart: ownStart},
+			maxAge:      time.Hour,
+			wantReclaim: false,
+		},
+		{
+			name:        "reclaiming disabled",
+			info:        lockInfo{Pid: deadPid, AcquiredAt: time.Now()},
+			maxAge:      0,
+			wantReclaim: false,
+		},
+		{
+			name:        "held by a dead process",
+			info:        lockInfo{Pid: deadPid, AcquiredAt: time.Now()},
+			maxAge:      time.Hour,
+			wantReclaim: true,
+		},
+		{
+			name:        "older than max age",
+			info:        lockInfo{Pid: os.Getpid(), AcquiredAt: time.Now().Add(-2 * time.Hour)},
+			maxAge:      time.Hour,
+			wantReclaim: true,
+		},
+		{
+			name:        "pid reused by another process",
+			info:        lockInfo{Pid: os.Getpid(), AcquiredAt: time.Now(), ProcessStart: "reused"},
+			maxAge:      time.Hour,
+			wantReclaim: ownStart != "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tempDir := t.TempDir()
+				mockCmd := &MockLockableCommand{id: "stale-command"}
+
+				holder := NewLockableCommand(mockCmd, tempDir)
+				if locked, err := holder.Lock(); err != nil || !locked {
+					t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+				}
+				defer func() { _ = holder.Unlock() }()
+
+				content, _ := json.Marshal(tt.info)
+				if err := os.WriteFile(holder.fileLock.Path(), content, 0666); err != nil {
+					t.Fatalf("Failed to write lock info: %v", err)
+				}
+
+				helper := NewLockableCommandWithOptions(
+					mockCmd,
+					tempDir,
+					LockOptions{StaleLockMaxAge: tt.maxAge},
+				)
+				locked, err := helper.Lock()
+				if err != nil {
+					t.Fatalf("Lock() error = %v", err)
+				}
+				if locked != tt.wantReclaim {
+					t.Errorf("Lock() locked = %v, want %v", locked, tt.wantReclaim)
+				}
+				if locked {
+					_ = helper.Unlock()
+				}
+			},
+		)
+	}
+}
+
+func TestLockableCommandHelper_ReclaimsStaleLocksOnlyOnce(t *testing.T) {
+	for attempt := 0; attempt < 20; attempt++ {
+		tempDir := t.TempDir()
+		mockCmd := &MockLockableCommand{id: "contended-stale-command"}
+
+		holder := NewLockableCommand(mockCmd, tempDir)
+		if locked, err := holder.Lock(); err != nil || !locked {
+			t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+		}
+		content, _ := json.Marshal(lockInfo{Pid: os.Getpid(), AcquiredAt: time.Now().Add(-2 * time.Hour)})
+		if err := os.WriteFile(holder.fileLock.Path(), content, 0666); err != nil {
+			t.Fatalf("Failed to write lock info: %v", err)
+		}
+
+		const reclaimers = 8
+		start := make(chan struct{})
+		results := make(chan bool, reclaimers)
+		for range reclaimers {
+			helper := NewLockableCommandWithOptions(mockCmd, tempDir, LockOptions{StaleLockMaxAge: time.Hour})
+			go func() {
+				<-start
+				locked, err := helper.Lock()
+				results <- locked && err == nil
+			}()
+		}
+		close(start)
+
+		acquired := 0
+		for range reclaimers {
+			if <-results {
+				acquired++
+			}
+		}
+		if acquired != 1 {
+			t.Fatalf("%d processes reclaimed the stale lock, want exactly 1", acquired)
+		}
+		if _, err := os.Stat(holder.fileLock.Path() + ".reclaim"); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("Expected the reclaim guard file to be removed, got %v", err)
+		}
+		_ = holder.Unlock()
+	}
+}
+
+func TestLockableCommandHelper_DoesNotReclaimALockReclaimedInTheMeantime(t *testing.T) {
+	tempDir := t.TempDir()
+	mockCmd := &MockLockableCommand{id: "reclaimed-command"}
+
+	holder := NewLockableCommand(mockCmd, tempDir)
+	if locked, err := holder.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+	defer func() { _ = holder.Unlock() }()
+	content, _ := json.Marshal(lockInfo{Pid: os.Getpid(), AcquiredAt: time.Now().Add(-2 * time.Hour)})
+	if err := os.WriteFile(holder.fileLock.Path(), content, 0666); err != nil {
+		t.Fatalf("Failed to write lock info: %v", err)
+	}
+
+	// Both processes see the same stale lock before either reclaims it
+	first := NewLockableCommandWithOptions(mockCmd, tempDir, LockOptions{StaleLockMaxAge: time.Hour})
+	second := NewLockableCommandWithOptions(mockCmd, tempDir, LockOptions{StaleLockMaxAge: time.Hour})
+	firstContent, firstStale := first.heldLockStaleContent()
+	secondContent, secondStale := second.heldLockStaleContent()
+	if !firstStale || !secondStale {
+		t.Fatalf("heldLockStaleContent() = %v, %v, want both stale", firstStale, secondStale)
+	}
+
+	if err := first.reclaimStaleLock(firstContent); err != nil {
+		t.Fatalf("first reclaimStaleLock() error = %v, want nil", err)
+	}
+	defer func() { _ = first.Unlock() }()
+
+	if err := second.reclaimStaleLock(secondContent); !errors.Is(err, filelock.ErrLockHeld) {
+		t.Errorf("second reclaimStaleLock() error = %v, want %v", err, filelock.ErrLockHeld)
+	}
+	if pid, _, err := first.LockInfo(); err != nil || pid != os.Getpid() {
+		t.Errorf("LockInfo() = %d, %v, want the lock info of the first reclaimer", pid, err)
+	}
+}
+
+func TestItRemovesGuardFilesOnUnlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.guard")
+
+	holder, err := lockGuardFile(path, 0)
+	if err != nil {
+		t.Fatalf("lockGuardFile() error = %v, want nil", err)
+	}
+	if err = unlockGuardFile(holder); err != nil {
+		t.Fatalf("unlockGuardFile() error = %v, want nil", err)
+	}
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the guard file to be removed, got %v", err)
+	}
+
+	guard, err := lockGuardFile(path, 0)
+	if err != nil {
+		t.Fatalf("lockGuardFile() error = %v, want nil", err)
+	}
+	defer func() { _ = unlockGuardFile(guard) }()
+	if err = fs.New(path).Lock(); !errors.Is(err, filelock.ErrLockHeld) {
+		t.Errorf("Lock() error = %v, want the guard file at the path to be locked", err)
+	}
+}
+
+func TestLockableCommandHelper_ReportsTheLockedCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	mockCmd := &MockLockableCommand{id: "reported-command"}
+
+	holder := NewLockableCommand(mockCmd, tempDir)
+	if locked, err := holder.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+	defer func() { _ = holder.Unlock() }()
+
+	err := NewLockableCommand(mockCmd, tempDir).Exec(&bytes.Buffer{})
+	if !errors.Is(err, CommandLocked) {
+		t.Fatalf("Exec() error = %v, want CommandLocked", err)
+	}
+
+	message := err.Error()
+	if !strings.Contains(message, "reported-command") {
+		t.Errorf("Error should contain the command id, got %v", message)
+	}
+	if !strings.Contains(message, holder.fileLock.Path()) {
+		t.Errorf("Error should contain the lock file path, got %v", message)
+	}
+	if runtime.GOOS != "windows" && !strings.Contains(message, fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("Error should contain the lock holder pid, got %v", message)
+	}
+}
+
+func TestBootstrapExitsWithTheSkippedCodeWhenLocked(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []BootstrapOption
+		wantExitCode int
+	}{
+		{
+			name:         "default skipped exit code",
+			wantExitCode: StatusOk,
+		},
+		{
+			name:         "custom skipped exit code",
+			opts:         []BootstrapOption{WithSkippedExitCode(75)},
+			wantExitCode: 75,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tempDir := t.TempDir()
+				mockCmd := &MockLockableCommand{id: "skipped-command"}
+
+				holder := NewLockableCommand(mockCmd, tempDir)
+				if locked, err := holder.Lock(); err != nil || !locked {
+					t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+				}
+				defer func() { _ = holder.Unlock() }()
+
+				registry := NewCommandsRegistry()
+				_ = registry.Register(NewLockableCommand(mockCmd, tempDir))
+
+				var errBuf bytes.Buffer
+				exitCode := -1
+				opts := append([]BootstrapOption{WithErrorWriter(&errBuf)}, tt.opts...)
+				Bootstrap(
+					[]string{"skipped-command"},
+					registry,
+					&bytes.Buffer{},
+					func(code int) { exitCode = code },
+					opts...,
+				)
+
+				if exitCode != tt.wantExitCode {
+					t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, tt.wantExitCode)
+				}
+				if !strings.HasPrefix(errBuf.String(), "Skipped command skipped-command") {
+					t.Errorf("Error output should report the skipped command, got %v", errBuf.String())
+				}
+				if mockCmd.executed {
+					t.Errorf("Command should not be executed while locked")
+				}
+			},
+		)
+	}
+}
+
+func TestLockGroupCommandsAreMutuallyExclusive(t *testing.T) {
+	tempDir := t.TempDir()
+	vacuumCmd := &MockLockableCommand{id: "db-vacuum"}
+	reindexCmd := &MockLockableCommand{id: "db-reindex"}
+
+	group := NewLockGroup(tempDir, "db-maintenance", vacuumCmd, reindexCmd)
+	if len(group) != 2 || group[0].Id() != "db-vacuum" || group[1].Id() != "db-reindex" {
+		t.Fatalf("NewLockGroup() returned unexpected commands %v", group)
+	}
+
+	vacuum := group[0].(*FsLockableCommand)
+	if locked, err := vacuum.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+
+	if err := group[1].Exec(&bytes.Buffer{}); !errors.Is(err, CommandLocked) {
+		t.Fatalf("Exec() error = %v, want CommandLocked", err)
+	}
+	if reindexCmd.executed {
+		t.Errorf("Command should not be executed while another one of its group runs")
+	}
+
+	if err := vacuum.Unlock(); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if err := group[1].Exec(&bytes.Buffer{}); err != nil {
+		t.Fatalf("Exec() error = %v, want nil", err)
+	}
+	if !reindexCmd.executed {
+		t.Errorf("Command should be executed once the group lock is released")
+	}
+}
+
+func TestLockableCommandHelper_UsesTheLockFileNameStrategy(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     LockFileNameStrategy
+		wantFileName string
+		wantPanic    bool
+	}{
+		{
+			name:         "default strategy",
+			strategy:     nil,
+			wantFileName: "go-cli-command-db-backup-160e33ab8dd23f15b6ccfbc53602a893.lock",
+		},
+		{
+			name:         "plain strategy",
+			strategy:     PlainLockFileName,
+			wantFileName: "go-cli-command-db-backup.lock",
+		},
+		{
+			name: "custom strategy",
+			strategy: func(lockName string) string {
+				return "cron_" + NormalizeId(lockName) + ".pid"
+			},
+			wantFileName: "cron_db-backup.pid",
+		},
+		{
+			name: "unsafe custom strategy",
+			strategy: func(lockName string) string {
+				return "../" + lockName
+			},
+			wantPanic: true,
+		},
+		{
+			name: "empty custom strategy",
+			strategy: func(lockName string) string {
+				return ""
+			},
+			wantPanic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tempDir := t.TempDir()
+				defer func() {
+					if recovered := recover(); (recovered != nil) != tt.wantPanic {
+						t.Errorf("NewLockableCommandWithOptions() panic = %v, wantPanic %v", recovered, tt.wantPanic)
+					}
+				}()
+
+				cmd := NewLockableCommandWithOptions(
+					&MockLockableCommand{id: "db:backup"},
+					tempDir,
+					LockOptions{FileNameStrategy: tt.strategy},
+				)
+
+				wantPath := filepath.Join(tempDir, tt.wantFileName)
+				if cmd.fileLock.Path() != wantPath {
+					t.Errorf("Lock file path = %v, want %v", cmd.fileLock.Path(), wantPath)
+				}
+			},
+		)
+	}
+}
+
+func TestItCanCheckTheLockDirAtConstruction(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "file")
+	if err := os.WriteFile(filePath, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		options  LockOptions
+		readOnly bool
+		wantErr  string
+	}{
+		{
+			name: "existing dir",
+			dir:  tempDir,
+		},
+		{
+			name:    "missing dir",
+			dir:     filepath.Join(tempDir, "missing"),
+			wantErr: "invalid lock dir",
+		},
+		{
+			name:    "created missing dir",
+			dir:     filepath.Join(tempDir, "created", "locks"),
+			options: LockOptions{CreateLockDir: true},
+		},
+		{
+			name:    "file instead of dir",
+			dir:     filePath,
+			wantErr: "not a directory",
+		},
+		{
+			name:     "read-only dir",
+			dir:      filepath.Join(tempDir, "read-only"),
+			readOnly: true,
+			wantErr:  "is not writable",
+		},
+		{
+			name: "invalid lock file name",
+			dir:  tempDir,
+			options: LockOptions{
+				FileNameStrategy: func(lockName string) string { return "../" + lockName },
+			},
+			wantErr: "contains unsafe characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if tt.readOnly {
+					if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+						t.Skip("read-only directories are writable by root and on windows")
+					}
+					if err := os.Mkdir(tt.dir, 0o500); err != nil {
+						t.Fatalf("Mkdir() error = %v", err)
+					}
+				}
+
+				cmd, err := NewLockableCommandChecked(&MockLockableCommand{id: "backup"}, tt.dir, tt.options)
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("NewLockableCommandChecked() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("NewLockableCommandChecked() error = %v, want nil", err)
+				}
+				if locked, err := cmd.Lock(); !locked || err != nil {
+					t.Errorf("Lock() = (%v, %v), want (true, nil)", locked, err)
+				}
+				_ = cmd.Unlock()
+				entries, _ := os.ReadDir(tt.dir)
+				for _, entry := range entries {
+					if strings.Contains(entry.Name(), "probe") {
+						t.Errorf("the writability probe file %s was not removed", entry.Name())
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItCanNormalizeIds(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: "say-hello", want: "say-hello"},
+		{id: "db:backup now", want: "db-backup-now"},
+		{id: "cache//clear", want: "cache-clear"},
+		{id: "Report2", want: "Report2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.id, func(t *testing.T) {
+				if got := NormalizeId(tt.id); got != tt.want {
+					t.Errorf("NormalizeId(%q) = %q, want %q", tt.id, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func BenchmarkNormalizeId(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NormalizeId("db:backup now")
+	}
+}
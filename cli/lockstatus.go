@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rsgcata/go-fs"
+	"github.com/rsgcata/go-fs/filelock"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// lockFileHashSuffix matches the md5 suffix of the lock file names built by
+// DefaultLockFileName
+var lockFileHashSuffix = regexp.MustCompile(`-[0-9a-f]{32}$`)
+
+// LockStatusCommand lists the lock files of a lock directory, reporting for each whether
+// it is currently held and, when known, by which process and for how long
+type LockStatusCommand struct {
+	CommandWithoutFlags
+	lockDir string
+}
+
+// NewLockStatusCommand creates a command reporting the status of the locks created by
+// FsLockableCommand in the given lock directory
+func NewLockStatusCommand(lockDir string) *LockStatusCommand {
+	return &LockStatusCommand{lockDir: lockDir}
+}
+
+func (c *LockStatusCommand) Id() string {
+	return "lock-status"
+}
+
+func (c *LockStatusCommand) Description() string {
+	return "Lists the command locks and whether they are currently held"
+}
+
+func (c *LockStatusCommand) Exec(baseWriter io.Writer) error {
+	lockFiles, err := filepath.Glob(filepath.Join(c.lockDir, "go-cli-command-*.lock"))
+	if err != nil {
+		return fmt.Errorf("failed to list the lock files in %s: %w", c.lockDir, err)
+	}
+	slices.Sort(lockFiles)
+
+	writer := tabwriter.NewWriter(baseWriter, 0, 0, 4, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "LOCK\tSTATUS\tPID\tAGE\tFILE")
+	for _, lockFile := range lockFiles {
+		status, pid, age := lockFileStatus(lockFile)
+		_, _ = fmt.Fprintf(
+			writer,
+			"%s\t%s\t%s\t%s\t%s\n",
+			lockNameFromFileName(filepath.Base(lockFile)),
+			status,
+			pid,
+			age,
+			lockFile,
+		)
+	}
+	if len(lockFiles) == 0 {
+		_, _ = fmt.Fprintf(writer, "No locks found in %s\n", c.lockDir)
+	}
+
+	return writer.Flush()
+}
+
+// lockFileStatus reports whether the lock file is held, by trying to acquire it, and the
+// PID and age of the holder, read from the lock file, or "-" when unknown
+func lockFileStatus(lockFile string) (status string, pid string, age string) {
+	pid, age = "-", "-"
+
+	fileLock := fs.New(lockFile)
+	err := fileLock.Lock()
+	if err == nil {
+		_ = fileLock.Unlock()
+		return "free", pid, age
+	}
+	if !errors.Is(err, filelock.ErrLockHeld) {
+		return "unknown", pid, age
+	}
+
+	if info, err := readLockInfo(lockFile); err == nil && info.Pid > 0 {
+		pid = strconv.Itoa(info.Pid)
+		if !info.AcquiredAt.IsZero() {
+			age = time.Since(info.AcquiredAt).Truncate(time.Second).String()
+		}
+	}
+	return "held", pid, age
+}
+
+// lockNameFromFileName returns the (normalized) lock name from a lock file name built by
+// DefaultLockFileName or PlainLockFileName
+func lockNameFromFileName(fileName string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(fileName, "go-cli-command-"), ".lock")
+	return lockFileHashSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestItCanListTheLockStatus(t *testing.T) {
+	tempDir := t.TempDir()
+
+	held := NewLockableCommand(&MockLockableCommand{id: "db:backup"}, tempDir)
+	if locked, err := held.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+	defer func() { _ = held.Unlock() }()
+
+	released := NewLockableCommandWithOptions(
+		&MockLockableCommand{id: "cache-warmup"},
+		tempDir,
+		LockOptions{FileNameStrategy: PlainLockFileName},
+	)
+	if locked, err := released.Lock(); err != nil || !locked {
+		t.Fatalf("Failed to acquire lock: locked=%v err=%v", locked, err)
+	}
+	_ = released.Unlock()
+
+	var buf bytes.Buffer
+	if err := NewLockStatusCommand(tempDir).Exec(&buf); err != nil {
+		t.Fatalf("LockStatusCommand.Exec() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "LOCK") {
+		t.Fatalf("Unexpected lock status output:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "cache-warmup" || fields[1] != "free" {
+		t.Errorf("Unexpected status of the released lock: %s", lines[1])
+	}
+	fields := strings.Fields(lines[2])
+	if fields[0] != "db-backup" || fields[1] != "held" {
+		t.Errorf("Unexpected status of the held lock: %s", lines[2])
+	}
+	if runtime.GOOS != "windows" && fields[2] != fmt.Sprint(os.Getpid()) {
+		t.Errorf("The held lock should report the holder pid %d: %s", os.Getpid(), lines[2])
+	}
+}
+
+func TestItReportsAnEmptyLockDirectory(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewLockStatusCommand(t.TempDir()).Exec(&buf); err != nil {
+		t.Fatalf("LockStatusCommand.Exec() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "No locks found") {
+		t.Errorf("Lock status output should report no locks, got %s", buf.String())
+	}
+}
@@ -0,0 +1,20 @@
+package cli
+
+import "time"
+
+// MetricsCollector observes the command runs, for example to export invocation and
+// failure counters and a duration histogram to Prometheus, through an adapter written by
+// the caller, since the package does not depend on any metrics library.
+type MetricsCollector interface {
+	// ObserveExecution is called once per run, when it is over, with the id of the command
+	// (the full path of subcommands, like "db migrate"), how long the run took and its
+	// error, nil on success. Panics and timeouts are observed as PanicError and TimeoutError
+	// errors. Runs failing before a command was resolved, because the global flags could
+	// not be parsed, are not observed.
+	ObserveExecution(id string, duration time.Duration, err error)
+}
+
+// noopMetricsCollector is used when no metrics collector is set
+type noopMetricsCollector struct{}
+
+func (noopMetricsCollector) ObserveExecution(string, time.Duration, error) {}
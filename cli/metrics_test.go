@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockMetricsCollector is a MetricsCollector implementation recording the observations
+type MockMetricsCollector struct {
+	mu           sync.Mutex
+	observations []mockObservation
+}
+
+type mockObservation struct {
+	id       string
+	duration time.Duration
+	err      error
+}
+
+func (m *MockMetricsCollector) ObserveExecution(id string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations = append(m.observations, mockObservation{id: id, duration: duration, err: err})
+}
+
+func TestItReportsEachRunToTheMetricsCollector(t *testing.T) {
+	failure := errors.New("bad input")
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "deploy"})
+	_ = registry.Register(
+		&MockCommand{
+			id: "report",
+			execFunc: func(writer io.Writer) error {
+				return failure
+			},
+		},
+	)
+	_ = registry.Register(
+		&MockCommand{
+			id: "crash",
+			execFunc: func(writer io.Writer) error {
+				panic("boom")
+			},
+		},
+	)
+	_ = registry.Register(
+		&MockTimeoutCommand{
+			MockContextCommand{
+				MockCommand: MockCommand{id: "slow"},
+				execContextFunc: func(ctx context.Context, writer io.Writer) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			time.Millisecond,
+		},
+	)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantId  string
+		wantErr func(err error) bool
+	}{
+		{
+			name:    "success",
+			args:    []string{"deploy"},
+			wantId:  "deploy",
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			name:    "failure",
+			args:    []string{"report"},
+			wantId:  "report",
+			wantErr: func(err error) bool { return errors.Is(err, failure) },
+		},
+		{
+			name:   "panic",
+			args:   []string{"crash"},
+			wantId: "crash",
+			wantErr: func(err error) bool {
+				var panicErr *PanicError
+				return errors.As(err, &panicErr)
+			},
+		},
+		{
+			name:   "timeout",
+			args:   []string{"slow"},
+			wantId: "slow",
+			wantErr: func(err error) bool {
+				var timeoutErr *TimeoutError
+				return errors.As(err, &timeoutErr)
+			},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"deplyo"},
+			wantId:  "deplyo",
+			wantErr: func(err error) bool { return errors.Is(err, ErrCommandNotFound) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				collector := &MockMetricsCollector{}
+				result := RunWithResult(
+					tt.args,
+					registry,
+					io.Discard,
+					WithErrorWriter(io.Discard),
+					WithoutSignalHandling(),
+					WithMetricsCollector(collector),
+				)
+
+				if len(collector.observations) != 1 {
+					t.Fatalf("ObserveExecution() calls = %d, want 1", len(collector.observations))
+				}
+				observation := collector.observations[0]
+				if observation.id != tt.wantId {
+					t.Errorf("ObserveExecution() id = %q, want %q", observation.id, tt.wantId)
+				}
+				if !tt.wantErr(observation.err) {
+					t.Errorf("ObserveExecution() error = %v, unexpected", observation.err)
+				}
+				if observation.duration != result.Duration {
+					t.Errorf("ObserveExecution() duration = %v, want %v", observation.duration, result.Duration)
+				}
+			},
+		)
+	}
+}
+
+func TestItDoesNotReportUnresolvedRunsToTheMetricsCollector(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "deploy"})
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "completion",
+			args: []string{CompleteCommandId, "dep"},
+		},
+		{
+			name: "invalid global flag",
+			args: []string{"--output", "xml", "deploy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				collector := &MockMetricsCollector{}
+				_, _ = Run(
+					tt.args,
+					registry,
+					io.Discard,
+					WithErrorWriter(io.Discard),
+					WithoutSignalHandling(),
+					WithMetricsCollector(collector),
+				)
+
+				if len(collector.observations) != 0 {
+					t.Errorf("ObserveExecution() calls = %d, want none", len(collector.observations))
+				}
+			},
+		)
+	}
+}
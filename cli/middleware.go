@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Invocation describes a resolved command invocation, as seen by middlewares
+type Invocation struct {
+	// The full id of the resolved command, including parent groups (e.g. "db migrate up")
+	Id string
+
+	// The resolved command
+	Command Command
+
+	// The args passed to the command, not yet parsed
+	Args []string
+
+	// The writers receiving the command output and the error messages
+	OutputWriter io.Writer
+	ErrWriter    io.Writer
+
+	// The reader providing the command input, os.Stdin by default
+	Input io.Reader
+
+	// The parsed global flags
+	GlobalFlags *flag.FlagSet
+
+	// The result of the command execution, filled in once the command returned, so that
+	// middlewares can read it after calling the next handler. It is nil when the
+	// invocation was not created by Bootstrap.
+	Result *ExecResult
+}
+
+// CommandHandler handles a command invocation
+type CommandHandler func(ctx context.Context, invocation Invocation) error
+
+// Middleware wraps a CommandHandler with cross-cutting logic, like logging, metrics or
+// authorization checks. A middleware can short-circuit the execution by not calling
+// next, or inspect and wrap the error returned by it.
+type Middleware func(next CommandHandler) CommandHandler
+
+// runInvocation is the innermost CommandHandler, it runs the command
+func runInvocation(ctx context.Context, invocation Invocation) error {
+	if invocation.Input != nil {
+		ctx = withInput(ctx, invocation.Input)
+	}
+
+	start := time.Now()
+	err := runCommand(
+		ctx,
+		invocation.Command,
+		invocation.Args,
+		invocation.OutputWriter,
+		invocation.ErrWriter,
+	)
+	if invocation.Result != nil {
+		*invocation.Result = newExecResult(runExitCode(ctx, err), err, time.Since(start))
+		invocation.Result.Value = commandResult(invocation.Command, err)
+	}
+	return err
+}
+
+// chainMiddlewares wraps the handler with the middlewares. The first middleware is the
+// outermost one, so it runs first before the execution and last after it.
+func chainMiddlewares(handler CommandHandler, middlewares []Middleware) CommandHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			handler = middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+// TimingMiddleware writes how long each command invocation took to the given writer
+func TimingMiddleware(writer io.Writer) Middleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, invocation Invocation) error {
+			start := time.Now()
+			err := next(ctx, invocation)
+			_, _ = fmt.Fprintf(writer, "Command %s took %s\n", invocation.Id, time.Since(start))
+			return err
+		}
+	}
+}
+
+// loggingMiddleware logs a start and an end event for each command invocation. Panics
+// are recovered by runCommand, so the end event is logged for panicking commands too.
+func loggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, invocation Invocation) error {
+			logger.InfoContext(
+				ctx,
+				"command started",
+				slog.String("command", invocation.Id),
+				slog.Int("args", len(invocation.Args)),
+			)
+
+			start := time.Now()
+			err := next(ctx, invocation)
+			attrs := []any{
+				slog.String("command", invocation.Id),
+				slog.Int("args", len(invocation.Args)),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int("exitCode", runExitCode(ctx, err)),
+			}
+
+			if err != nil {
+				logger.ErrorContext(ctx, "command failed", append(attrs, slog.Any("error", err))...)
+			} else {
+				logger.InfoContext(ctx, "command finished", attrs...)
+			}
+
+			return err
+		}
+	}
+}
+
+// CommandDecorator wraps a command into another one, which must keep the same id
+type CommandDecorator func(cmd Command) Command
+
+// decorateCommand applies the decorators to the command, unless it is the help command.
+// It fails if a decorator changed the command id, which would no longer match the
+// requested one.
+func decorateCommand(cmd Command, decorators []CommandDecorator) (Command, error) {
+	if _, isHelp := cmd.(*HelpCommand); isHelp {
+		return cmd, nil
+	}
+
+	for _, decorate := range decorators {
+		if decorate == nil {
+			continue
+		}
+		decorated := decorate(cmd)
+		if decorated == nil || decorated.Id() != cmd.Id() {
+			return cmd, fmt.Errorf("command decorator changed the id of command %s", cmd.Id())
+		}
+		cmd = decorated
+	}
+	return cmd, nil
+}
@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestItAppliesMiddlewaresInOrder(t *testing.T) {
+	var calls []string
+	recordingMiddleware := func(name string) Middleware {
+		return func(next CommandHandler) CommandHandler {
+			return func(ctx context.Context, invocation Invocation) error {
+				calls = append(calls, fmt.Sprintf("%s before %s %v", name, invocation.Id, invocation.Args))
+				err := next(ctx, invocation)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommandWithFlags{
+			id: "test-cmd",
+			execFunc: func(writer io.Writer) error {
+				calls = append(calls, "exec")
+				return nil
+			},
+		},
+	)
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"test-cmd", "--test-flag", "value"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithMiddlewares(recordingMiddleware("first"), recordingMiddleware("second")),
+	)
+
+	want := []string{
+		"first before test-cmd [--test-flag value]",
+		"second before test-cmd [--test-flag value]",
+		"exec",
+		"second after",
+		"first after",
+	}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("Middleware calls = %v, want %v", calls, want)
+	}
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+}
+
+func TestMiddlewaresCanShortCircuitAndWrapErrors(t *testing.T) {
+	executed := false
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "test-cmd",
+			execFunc: func(writer io.Writer) error {
+				executed = true
+				return nil
+			},
+		},
+	)
+
+	denied := errors.New("access denied")
+	authMiddleware := func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, invocation Invocation) error {
+			return fmt.Errorf("auth check failed: %w", denied)
+		}
+	}
+
+	var errBuf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"test-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
+		WithMiddlewares(authMiddleware),
+	)
+
+	if executed {
+		t.Errorf("Command should not be executed when a middleware short-circuits")
+	}
+	if exitCode != StatusErr {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusErr)
+	}
+	if !strings.Contains(errBuf.String(), "auth check failed: access denied") {
+		t.Errorf("Bootstrap() should report the middleware error, got %q", errBuf.String())
+	}
+}
+
+func TestTimingMiddlewareReportsTheDuration(t *testing.T) {
+	var timingBuf bytes.Buffer
+	handler := chainMiddlewares(
+		runInvocation,
+		[]Middleware{TimingMiddleware(&timingBuf)},
+	)
+
+	err := handler(
+		context.Background(),
+		Invocation{
+			Id:           "test-cmd",
+			Command:      &MockCommand{id: "test-cmd"},
+			OutputWriter: &bytes.Buffer{},
+			ErrWriter:    &bytes.Buffer{},
+		},
+	)
+	if err != nil {
+		t.Fatalf("handler() error = %v", err)
+	}
+	if !strings.HasPrefix(timingBuf.String(), "Command test-cmd took ") {
+		t.Errorf("TimingMiddleware output = %q", timingBuf.String())
+	}
+}
+
+func TestItLogsCommandExecutionThroughTheLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		execFunc  func(writer io.Writer) error
+		wantLevel string
+		wantMsg   string
+		wantCode  float64
+	}{
+		{
+			name:      "success",
+			execFunc:  func(writer io.Writer) error { return nil },
+			wantLevel: "INFO",
+			wantMsg:   "command finished",
+			wantCode:  StatusOk,
+		},
+		{
+			name:      "panic",
+			execFunc:  func(writer io.Writer) error { panic("boom") },
+			wantLevel: "ERROR",
+			wantMsg:   "command failed",
+			wantCode:  StatusErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(&MockCommand{id: "logged-cmd", execFunc: tt.execFunc})
+
+				var logBuf bytes.Buffer
+				Bootstrap(
+					[]string{"logged-cmd", "arg"},
+					registry,
+					&bytes.Buffer{},
+					func(code int) {},
+					WithErrorWriter(&bytes.Buffer{}),
+					WithLogger(slog.New(slog.NewJSONHandler(&logBuf, nil))),
+				)
+
+				lines := strings.Split(strings.TrimSpace(logBuf.String()), "\n")
+				if len(lines) != 2 {
+					t.Fatalf("Expected a start and an end log event, got %q", logBuf.String())
+				}
+
+				var start, end map[string]any
+				_ = json.Unmarshal([]byte(lines[0]), &start)
+				_ = json.Unmarshal([]byte(lines[1]), &end)
+
+				if start["msg"] != "command started" || start["command"] != "logged-cmd" || start["args"] != 1.0 {
+					t.Errorf("Unexpected start event %v", start)
+				}
+				if end["msg"] != tt.wantMsg || end["level"] != tt.wantLevel || end["exitCode"] != tt.wantCode {
+					t.Errorf("Unexpected end event %v", end)
+				}
+				if _, hasDuration := end["duration"]; !hasDuration {
+					t.Errorf("End event should contain the duration, got %v", end)
+				}
+			},
+		)
+	}
+}
+
+func TestItLogsTheExitCodeOfTheRun(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id:       "locked-cmd",
+			execFunc: func(writer io.Writer) error { return fmt.Errorf("%w: held", CommandLocked) },
+		},
+	)
+
+	var invocationCode int
+	resultMiddleware := func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, invocation Invocation) error {
+			err := next(ctx, invocation)
+			invocationCode = invocation.Result.ExitCode
+			return err
+		}
+	}
+
+	var logBuf bytes.Buffer
+	exitCode, _ := Run(
+		[]string{"locked-cmd"},
+		registry,
+		io.Discard,
+		WithErrorWriter(io.Discard),
+		WithoutSignalHandling(),
+		WithSkippedExitCode(3),
+		WithLogger(slog.New(slog.NewJSONHandler(&logBuf, nil))),
+		WithMiddlewares(resultMiddleware),
+	)
+
+	lines := strings.Split(strings.TrimSpace(logBuf.String()), "\n")
+	var end map[string]any
+	_ = json.Unmarshal([]byte(lines[len(lines)-1]), &end)
+
+	if exitCode != 3 {
+		t.Errorf("Run() exitCode = %d, want 3", exitCode)
+	}
+	if end["exitCode"] != 3.0 {
+		t.Errorf("Logged exitCode = %v, want the run exit code 3", end["exitCode"])
+	}
+	if invocationCode != 3 {
+		t.Errorf("Invocation result exit code = %d, want the run exit code 3", invocationCode)
+	}
+}
+
+func TestItCanDecorateTheExecutedCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	var decorated []string
+	recordingDecorator := func(cmd Command) Command {
+		decorated = append(decorated, cmd.Id())
+		return cmd
+	}
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "test-cmd"})
+
+	exitCode := -1
+	Bootstrap(
+		[]string{"test-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithCommandDecorator(recordingDecorator),
+		WithCommandDecorator(
+			func(cmd Command) Command {
+				return NewLockableCommand(cmd, tempDir)
+			},
+		),
+	)
+
+	if exitCode != StatusOk {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusOk)
+	}
+	if strings.Join(decorated, " ") != "test-cmd" {
+		t.Errorf("Decorated commands = %v, want [test-cmd]", decorated)
+	}
+	lockFiles, _ := filepath.Glob(filepath.Join(tempDir, "go-cli-command-test-cmd-*.lock"))
+	if len(lockFiles) != 1 {
+		t.Errorf("The lockable decorator should have created a lock file, got %v", lockFiles)
+	}
+
+	// The help command is not decorated
+	decorated = nil
+	Bootstrap([]string{"help"}, registry, &bytes.Buffer{}, func(code int) {}, WithCommandDecorator(recordingDecorator))
+	if len(decorated) != 0 {
+		t.Errorf("The help command should not be decorated, got %v", decorated)
+	}
+}
+
+func TestItRejectsDecoratorsChangingTheCommandId(t *testing.T) {
+	executed := false
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "test-cmd",
+			execFunc: func(writer io.Writer) error {
+				executed = true
+				return nil
+			},
+		},
+	)
+
+	var errBuf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"test-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
+		WithCommandDecorator(
+			func(cmd Command) Command {
+				return &MockCommand{id: "other-cmd"}
+			},
+		),
+	)
+
+	if exitCode != StatusErr || executed {
+		t.Errorf("Bootstrap() exitCode = %v, executed = %v, want %v and not executed", exitCode, executed, StatusErr)
+	}
+	if !strings.Contains(errBuf.String(), "changed the id of command test-cmd") {
+		t.Errorf("Error output should report the id change, got %v", errBuf.String())
+	}
+}
@@ -0,0 +1,422 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// DefaultShutdownGracePeriod is how long Bootstrap waits for an interrupted command to
+// return before force-exiting the process
+const DefaultShutdownGracePeriod = 10 * time.Second
+
+// bootstrapOptions holds the settings which can be customized via BootstrapOption
+type bootstrapOptions struct {
+	ctx                 context.Context
+	outputWriter        io.Writer
+	processExit         func(code int)
+	handleSignals       bool
+	shutdownGracePeriod time.Duration
+	signalExitCode      int
+	errWriter           io.Writer
+	input               io.Reader
+	middlewares         []Middleware
+	logger              *slog.Logger
+	verbose             bool
+	quiet               bool
+	globalFlags         []func(flagSet *flag.FlagSet)
+	withoutDefaultHelp  bool
+	skippedExitCode     int
+	progressReporting   bool
+	defaultCommand      string
+	errorFormat         string
+	decorators          []CommandDecorator
+	outputCapture       io.Writer
+	errorCapture        io.Writer
+	eventWriter         io.Writer
+	pager               bool
+	envPrefix           *string
+	flagErrorHandling   flag.ErrorHandling
+	silentFlagErrors    bool
+	commandNotFoundHook CommandNotFoundHook
+	auditLogPath        string
+	metricsCollector    MetricsCollector
+}
+
+// BootstrapOption configures optional Bootstrap behaviour
+type BootstrapOption func(options *bootstrapOptions)
+
+// BootstrapOptions groups the most common settings in a struct, as an alternative to
+// passing the matching BootstrapOption functions one by one. Zero fields keep the default
+// behaviour. Pass it with WithOptions, along with other options if needed.
+type BootstrapOptions struct {
+	// The writer receiving the command output, see WithOutputWriter
+	OutputWriter io.Writer
+
+	// The writer receiving the error messages, see WithErrorWriter
+	ErrorWriter io.Writer
+
+	// The function the process exits with, see WithProcessExit
+	ProcessExit func(code int)
+
+	// The reader providing the command input, see WithInput
+	Input io.Reader
+
+	// The logger of the command executions, see WithLogger
+	Logger *slog.Logger
+
+	// The command run when no command id is given, see WithDefaultCommand
+	DefaultCommand string
+
+	// Enables verbose error reporting, see WithVerbose
+	Verbose bool
+
+	// Enables the quiet mode, see WithQuiet
+	Quiet bool
+}
+
+// WithOptions applies the non-zero settings of the BootstrapOptions struct
+func WithOptions(settings BootstrapOptions) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		if settings.OutputWriter != nil {
+			options.outputWriter = settings.OutputWriter
+		}
+		if settings.ErrorWriter != nil {
+			options.errWriter = settings.ErrorWriter
+		}
+		if settings.ProcessExit != nil {
+			options.processExit = settings.ProcessExit
+		}
+		if settings.Input != nil {
+			options.input = settings.Input
+		}
+		if settings.Logger != nil {
+			options.logger = settings.Logger
+		}
+		if settings.DefaultCommand != "" {
+			options.defaultCommand = settings.DefaultCommand
+		}
+		options.verbose = options.verbose || settings.Verbose
+		options.quiet = options.quiet || settings.Quiet
+	}
+}
+
+func newBootstrapOptions(opts ...BootstrapOption) *bootstrapOptions {
+	options := &bootstrapOptions{
+		ctx:                 context.Background(),
+		handleSignals:       true,
+		shutdownGracePeriod: DefaultShutdownGracePeriod,
+		signalExitCode:      StatusInterrupted,
+		skippedExitCode:     StatusOk,
+		metricsCollector:    noopMetricsCollector{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+
+	return options
+}
+
+// WithContext sets the root context passed down to the executed command. Commands
+// implementing ContextualCommand will receive it (or a context derived from it), which
+// allows callers to wire in their own cancellation. Defaults to context.Background().
+func WithContext(ctx context.Context) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		if ctx != nil {
+			options.ctx = ctx
+		}
+	}
+}
+
+// WithShutdownGracePeriod sets how long Bootstrap waits, after receiving SIGINT or
+// SIGTERM, for the command to return before force-exiting the process. A value <= 0
+// waits indefinitely. A second signal always force-exits immediately.
+func WithShutdownGracePeriod(gracePeriod time.Duration) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.shutdownGracePeriod = gracePeriod
+	}
+}
+
+// WithSignalExitCode sets the exit code used when the process is stopped by SIGINT or
+// SIGTERM. Defaults to StatusInterrupted.
+func WithSignalExitCode(code int) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.signalExitCode = code
+	}
+}
+
+// WithoutSignalHandling disables the SIGINT/SIGTERM handler installed by Bootstrap,
+// leaving signal handling entirely to the caller
+func WithoutSignalHandling() BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.handleSignals = false
+	}
+}
+
+// WithOutputWriter sets the writer receiving the command output. Defaults to os.Stdout.
+// The output writer given to Run or RunWithResult, when not nil, takes precedence.
+func WithOutputWriter(outputWriter io.Writer) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.outputWriter = outputWriter
+	}
+}
+
+// WithProcessExit sets the function BootstrapWith exits the process with, like a fake one
+// recording the exit code in tests. Defaults to os.Exit.
+func WithProcessExit(processExit func(code int)) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.processExit = processExit
+	}
+}
+
+// WithErrorWriter sets the writer receiving error messages, like command failures and
+// flag parse errors, separately from the command output. Defaults to os.Stderr.
+func WithErrorWriter(errWriter io.Writer) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.errWriter = errWriter
+	}
+}
+
+// WithMiddlewares appends middlewares wrapping the execution of the resolved command.
+// They are applied in order, the first one being the outermost.
+func WithMiddlewares(middlewares ...Middleware) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.middlewares = append(options.middlewares, middlewares...)
+	}
+}
+
+// WithLogger logs a start and an end event for each command invocation through the
+// given logger, with the command id, argument count, duration, exit code and error.
+// Nothing is logged when no logger is provided.
+func WithLogger(logger *slog.Logger) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.logger = logger
+	}
+}
+
+// WithVerbose enables verbose error reporting, like the --verbose global flag. When a
+// command panics, the stack trace of the panic is written to the error writer after the
+// failure message.
+func WithVerbose(verbose bool) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.verbose = verbose
+	}
+}
+
+// WithQuiet enables the quiet mode, like the --quiet global flag: warnings, like the
+// deprecation ones, and failure messages are not written to the error writer, failures
+// only being reported by the exit code. Flag parse errors and usage are still written.
+// Verbose error reporting, via WithVerbose or the --verbose global flag, disables it.
+func WithQuiet(quiet bool) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.quiet = quiet
+	}
+}
+
+// WithInput sets the reader passed as input to commands implementing InputCommand, or
+// returned by Input(ctx) for commands implementing ContextualCommand. Defaults to os.Stdin.
+func WithInput(in io.Reader) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.input = in
+	}
+}
+
+// WithGlobalFlags adds a callback defining global flags, like --log-level, which apply to
+// every command. Global flags are parsed from the args given before the command id, the
+// remaining args being passed to the command. The parsed flag set is available to
+// middlewares through Invocation.GlobalFlags and to commands through GlobalFlags(ctx).
+// A command may define a flag with the same name as a global one: they do not clash,
+// as flags given before the command id are global and the ones after it are the command's.
+// A global flag named like a built-in one, like --timeout, replaces it, the built-in
+// behaviour only applying when the flag has the same type.
+func WithGlobalFlags(defineFlags func(flagSet *flag.FlagSet)) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.globalFlags = append(options.globalFlags, defineFlags)
+	}
+}
+
+// WithoutDefaultHelp disables the registration of the built-in HelpCommand. Running the
+// app without a command id then fails, unless a command with the "help" id is registered.
+func WithoutDefaultHelp() BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.withoutDefaultHelp = true
+	}
+}
+
+// WithSkippedExitCode sets the exit code used when a lockable command is skipped because
+// its lock is held by another process (the command returned CommandLocked). Defaults to
+// StatusOk, so that overlapping scheduled runs are not reported as failures.
+func WithSkippedExitCode(code int) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.skippedExitCode = code
+	}
+}
+
+// WithProgressReporting enables the progress reporter returned by Progress(ctx), rendering
+// the progress of commands to the output writer
+func WithProgressReporting() BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.progressReporting = true
+	}
+}
+
+// WithDefaultCommand sets the command run when no command id is given, instead of the
+// help command. When the args following the global flags start with a flag, like in
+// "app --name x file.txt", they are the default command args. A "--" terminator ends the
+// global flags too, like in "app -- --dry-run" giving --dry-run to the default command.
+// Unknown command ids are still reported as not found. If the default command is not
+// registered, an error is written to the error writer and the help command is run
+// instead.
+func WithDefaultCommand(id string) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.defaultCommand = id
+	}
+}
+
+// WithErrorFormat sets the format of the failures written to the error writer: the human
+// readable ErrorFormatText (default), or ErrorFormatJson, writing one json object per
+// failure, like {"command":"x","error":"...","exitCode":1}. The reported exit code is the
+// one the process exits with. With the json format, the flag package text, like parse
+// errors and usages, is discarded, so that the error writer only gets json lines.
+func WithErrorFormat(format string) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.errorFormat = format
+	}
+}
+
+// WithCommandDecorator adds a decorator wrapping the executed command, like
+// NewLockableCommand or a custom timing or logging wrapper, so that wrappers can be applied
+// uniformly instead of to each command at registration. Decorators are applied in order,
+// the last one being the outermost, to the command resolved from the args, including
+// subcommands of groups, but not to the built-in help command.
+func WithCommandDecorator(decorator CommandDecorator) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.decorators = append(options.decorators, decorator)
+	}
+}
+
+// WithOutputCapture mirrors everything written to the output writer, like the command and
+// help output, into the given writer, for example a buffer inspected once Run returns.
+// The output is still written to the output writer as it is produced.
+func WithOutputCapture(capture io.Writer) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.outputCapture = capture
+	}
+}
+
+// WithErrorCapture mirrors everything written to the error writer, like failure messages,
+// flag parse errors and usage, into the given writer. The messages are still written to
+// the error writer.
+func WithErrorCapture(capture io.Writer) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.errorCapture = capture
+	}
+}
+
+// WithEventWriter enables the lifecycle events of the command execution, written to the
+// given writer as json lines, for log pipelines consuming files or sockets. Each event holds
+// its type ("event"), a timestamp ("time") and the resolved command id ("command"), empty
+// when the global flags cannot be parsed:
+// command_start, flag_parsed (with the "flags" set in the args), validation_failed (with
+// the "error") and command_end (with the "exitCode", the "error" if any and the
+// "durationMs"). A run emits one command_start and one command_end event.
+func WithEventWriter(writer io.Writer) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.eventWriter = writer
+	}
+}
+
+// WithPager pipes the command output, like a long help, through the pager set in the
+// PAGER environment variable (e.g. "less -R"), when the output writer is a terminal. It
+// adds the --no-pager global flag to disable it. Nothing changes when the output is not a
+// terminal or PAGER is not set. If the pager cannot be started, the output is written
+// directly, and a pager failure is reported as a warning once it exits.
+func WithPager() BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.pager = true
+		options.globalFlags = append(
+			options.globalFlags,
+			func(flagSet *flag.FlagSet) {
+				if flagSet.Lookup(NoPagerFlagName) == nil {
+					flagSet.Bool(NoPagerFlagName, false, "Write the output directly, without the pager")
+				}
+			},
+		)
+	}
+}
+
+// WithEnvPrefix makes every command read the flags not set in the args from environment
+// variables named after them, with the given prefix: the --count-to flag is read from
+// MYAPP_COUNT_TO with the "MYAPP" prefix, see FlagEnvName. Args take precedence over the
+// environment, which takes precedence over the config file (see ConfigurableCommand) and
+// the flag defaults.
+func WithEnvPrefix(prefix string) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.envPrefix = &prefix
+	}
+}
+
+// WithFlagErrorHandling sets how the command flag sets handle parse errors. The default,
+// flag.ContinueOnError, makes the failing command return a FlagParseError. With
+// flag.ExitOnError the flag package exits the process itself, with code 2 (or 0 on a help
+// request), and with flag.PanicOnError it panics, the panic being returned as PanicError.
+func WithFlagErrorHandling(handling flag.ErrorHandling) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.flagErrorHandling = handling
+	}
+}
+
+// WithSilentFlagErrors stops the flag package from printing the parse errors and the
+// usage, of the global and command flags, and the usage shown along flag validation errors.
+// The errors are still returned, in the ExecResult of Run, and Bootstrap reports them like
+// other command failures, once, unless the quiet mode is enabled. It lets programmatic
+// callers fully control how invalid invocations are presented.
+func WithSilentFlagErrors() BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.silentFlagErrors = true
+	}
+}
+
+// CommandNotFoundHook is called with the requested command id, like "deploy" or "db nope"
+// for an unknown subcommand, and the args following it, when no registered command matches
+// them. It returns whether it handled the invocation, for example by loading the command
+// lazily or by forwarding it to an external tool, and the resulting error.
+type CommandNotFoundHook func(id string, args []string) (handled bool, err error)
+
+// WithCommandNotFoundHook sets the hook called before reporting that a command does not
+// exist. When it returns handled, its error is used as the command result, nil meaning
+// success, instead of the CommandNotFoundError.
+func WithCommandNotFoundHook(hook CommandNotFoundHook) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.commandNotFoundHook = hook
+	}
+}
+
+// WithAuditLog appends a json line to the audit log file at the given path, created if
+// needed, once each command run is over: its start time, the user (from the USER
+// environment variable), the command id, the args, the exit code, the duration in
+// milliseconds and the error, if any. Each line is appended with a single write, so that
+// concurrent processes can share the file. Failing to write it does not fail the command,
+// a warning being written to the error writer instead. Note that the args are recorded as
+// given, secrets passed as flag values included.
+func WithAuditLog(path string) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		options.auditLogPath = path
+	}
+}
+
+// WithMetricsCollector sets the collector observing each command run, once it is over,
+// whatever its outcome, shell completions excepted. A nil collector keeps the default,
+// which does nothing.
+func WithMetricsCollector(collector MetricsCollector) BootstrapOption {
+	return func(options *bootstrapOptions) {
+		if collector != nil {
+			options.metricsCollector = collector
+		}
+	}
+}
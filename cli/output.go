@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OutputFlagName is the name of the built-in global flag choosing the output format of
+// the commands supporting it, see OutputFormat
+const OutputFlagName = "output"
+
+// The output formats of the --output global flag, see Encode
+const (
+	OutputFormatText = "text"
+	OutputFormatJson = "json"
+	OutputFormatYaml = "yaml"
+)
+
+// outputFormatKey is the context key of the output format given via the global flag
+type outputFormatKey struct{}
+
+// OutputFormat returns the output format given via the --output global flag, from the
+// context passed to commands (implementing ContextualCommand) and middlewares:
+// OutputFormatText (the default), OutputFormatJson or OutputFormatYaml. Commands render
+// their result with Encode, to get consistent machine-readable output.
+func OutputFormat(ctx context.Context) string {
+	if format, _ := ctx.Value(outputFormatKey{}).(string); format != "" {
+		return format
+	}
+	return OutputFormatText
+}
+
+// Encode writes v to the writer in the given output format. The json format is indented
+// and the yaml format is derived from it, with gopkg.in/yaml.v3, so both honour the json
+// struct tags and json.Marshaler implementations. The text format writes v with fmt.Println, commands
+// wanting a richer text layout rendering it themselves when OutputFormat is
+// OutputFormatText. It returns an error for unknown formats and values which cannot be
+// encoded.
+func Encode(w io.Writer, format string, v any) error {
+	switch format {
+	case OutputFormatText:
+		_, err := fmt.Fprintln(w, v)
+		return err
+	case OutputFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case OutputFormatYaml:
+		return encodeYaml(w, v)
+	default:
+		return fmt.Errorf(
+			"unknown output format %s, expected %s, %s or %s",
+			format,
+			OutputFormatText,
+			OutputFormatJson,
+			OutputFormatYaml,
+		)
+	}
+}
+
+// encodeYaml writes v as a yaml block document, by converting its json representation
+// into a yaml node tree, keeping the order of the json object keys
+func encodeYaml(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := readJsonNode(decoder)
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// readJsonNode reads the next json value of the decoder as a yaml node, objects being
+// read as mappings and arrays as sequences
+func readJsonNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := readJsonNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, yamlString(key.(string)), value)
+			}
+			if len(node.Content) == 0 {
+				node.Style = yaml.FlowStyle
+			}
+			_, err = decoder.Token()
+			return node, err
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for decoder.More() {
+				item, err := readJsonNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, item)
+			}
+			if len(node.Content) == 0 {
+				node.Style = yaml.FlowStyle
+			}
+			_, err = decoder.Token()
+			return node, err
+		default:
+			return nil, errors.New("unexpected end of json value")
+		}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(token)}, nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := token.Int64(); err == nil {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: token.String()}, nil
+	case string:
+		return yamlString(token), nil
+	default:
+		return yamlString(fmt.Sprint(token)), nil
+	}
+}
+
+// yamlString returns the string scalar node of the value, which the encoder quotes when
+// it would otherwise be read as another type or break the yaml syntax. The YAML 1.1
+// booleans, like "yes" or "off", are strings in YAML 1.2, so they are quoted explicitly
+// for the parsers still reading them as booleans.
+func yamlString(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if slices.Contains(yaml11Booleans, strings.ToLower(value)) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return node
+}
+
+// yaml11Booleans are the lower-cased plain scalars read as booleans by YAML 1.1 parsers
+var yaml11Booleans = []string{"y", "n", "yes", "no", "on", "off", "true", "false"}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestItResolvesTheOutputFormatFromTheGlobalFlag(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantFormat   string
+		wantExitCode int
+	}{
+		{name: "default", args: []string{"report"}, wantFormat: OutputFormatText},
+		{name: "json", args: []string{"--output", "json", "report"}, wantFormat: OutputFormatJson},
+		{name: "yaml", args: []string{"--output=yaml", "report"}, wantFormat: OutputFormatYaml},
+		{name: "unknown format", args: []string{"--output", "xml", "report"}, wantExitCode: StatusUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				format := ""
+				registry := NewCommandsRegistry()
+				_ = registry.Register(
+					&MockContextCommand{
+						MockCommand: MockCommand{id: "report"},
+						execContextFunc: func(ctx context.Context, writer io.Writer) error {
+							format = OutputFormat(ctx)
+							return nil
+						},
+					},
+				)
+
+				exitCode, _ := Run(tt.args, registry, io.Discard, WithErrorWriter(io.Discard), WithoutSignalHandling())
+
+				if exitCode != tt.wantExitCode || format != tt.wantFormat {
+					t.Errorf(
+						"Run() = (%d, format %q), want (%d, format %q)",
+						exitCode, format, tt.wantExitCode, tt.wantFormat,
+					)
+				}
+			},
+		)
+	}
+
+	if format := OutputFormat(context.Background()); format != OutputFormatText {
+		t.Errorf("OutputFormat() without the global flag = %q, want %q", format, OutputFormatText)
+	}
+}
+
+type sampleReport struct {
+	Name    string            `json:"name"`
+	Count   int               `json:"count"`
+	Ratio   float64           `json:"ratio"`
+	Enabled bool              `json:"enabled"`
+	Note    *string           `json:"note"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+	Items   []sampleItem      `json:"items"`
+	Empty   []string          `json:"empty"`
+	Skipped string            `json:"-"`
+}
+
+type sampleItem struct {
+	Id    string `json:"id"`
+	Value string `json:"value"`
+}
+
+func TestItCanEncodeOutput(t *testing.T) {
+	report := sampleReport{
+		Name:    "daily: sales",
+		Count:   3,
+		Ratio:   0.5,
+		Enabled: true,
+		Tags:    []string{"plain", "true", "42", "", "- dash", "multi\nline"},
+		Labels:  map[string]string{"zone": "eu", "env": "prod"},
+		Items:   []sampleItem{{Id: "a", Value: "first"}, {Id: "b", Value: "#second"}},
+		Empty:   []string{},
+		Skipped: "hidden",
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		value   any
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "json",
+			format: OutputFormatJson,
+			value:  sampleItem{Id: "a", Value: "first"},
+			want:   "{\n  \"id\": \"a\",\n  \"value\": \"first\"\n}\n",
+		},
+		{
+			name:   "yaml",
+			format: OutputFormatYaml,
+			value:  report,
+			want: `name: 'daily: sales'
+count: 3
+ratio: 0.5
+enabled: true
+note: null
+tags:
+  - plain
+  - "true"
+  - "42"
+  - ""
+  - '- dash'
+  - |-
+    multi
+    line
+labels:
+  env: prod
+  zone: eu
+items:
+  - id: a
+    value: first
+  - id: b
+    value: '#second'
+empty: []
+`,
+		},
+		{
+			name:   "yaml scalar",
+			format: OutputFormatYaml,
+			value:  "done",
+			want:   "done\n",
+		},
+		{
+			name:   "text",
+			format: OutputFormatText,
+			value:  sampleItem{Id: "a", Value: "first"},
+			want:   "{a first}\n",
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			value:   report,
+			wantErr: "unknown output format xml",
+		},
+		{
+			name:    "unsupported value",
+			format:  OutputFormatYaml,
+			value:   make(chan int),
+			wantErr: "unsupported type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				err := Encode(&buf, tt.format, tt.value)
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("Encode() error = %v, want it to contain %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Encode() error = %v, want nil", err)
+				}
+				if buf.String() != tt.want {
+					t.Errorf("Encode() output =\n%s\nwant\n%s", buf.String(), tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestItEncodesYamlStringsReadBackAsStrings(t *testing.T) {
+	values := map[string]string{
+		"yes":     "yes",
+		"no":      "No",
+		"on":      "on",
+		"off":     "OFF",
+		"null":    "~",
+		"alias":   "*ref",
+		"anchor":  "&ref",
+		"tag":     "!important",
+		"dir":     "%YAML",
+		"number":  "1e3",
+		"octal":   "0o17",
+		"key: id": "value: with colon",
+		"comment": "text # not a comment",
+		"quotes":  `it's "quoted"`,
+		"lines":   "multi\nline\n",
+		"spaces":  " padded ",
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, OutputFormatYaml, values); err != nil {
+		t.Fatalf("Encode() error = %v, want nil", err)
+	}
+
+	var decoded map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v, output:\n%s", err, buf.String())
+	}
+	for key, want := range values {
+		if got, isString := decoded[key].(string); !isString || got != want {
+			t.Errorf("Decoded %q = %#v, want the string %q, output:\n%s", key, decoded[key], want, buf.String())
+		}
+	}
+
+	// YAML 1.2 parsers read the YAML 1.1 booleans as strings, so check they are quoted
+	for _, key := range []string{"yes", "no", "on", "off"} {
+		if line := `"` + key + `": "` + values[key] + `"`; !strings.Contains(buf.String(), line) {
+			t.Errorf("Encode() output should contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// NoPagerFlagName is the name of the global flag disabling the pager enabled by WithPager
+const NoPagerFlagName = "no-pager"
+
+// PagerEnvVar is the environment variable holding the pager command, like "less -R"
+const PagerEnvVar = "PAGER"
+
+// pagerWriter writes the command output to the stdin of a pager process, like less, which
+// writes it to the terminal
+type pagerWriter struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// startPager starts the pager command, split on whitespace into the binary and its args,
+// writing to the terminal file
+func startPager(command string, terminal *os.File, errWriter io.Writer) (*pagerWriter, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty pager command")
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdout = terminal
+	cmd.Stderr = errWriter
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the pager %s: %w", command, err)
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start the pager %s: %w", command, err)
+	}
+
+	return &pagerWriter{cmd: cmd, stdin: stdin}, nil
+}
+
+// Write writes to the pager. Once the user quit the pager, the output is discarded
+// instead of failing the command.
+func (p *pagerWriter) Write(b []byte) (int, error) {
+	n, err := p.stdin.Write(b)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		return len(b), nil
+	}
+	return n, err
+}
+
+// close closes the pager input and waits for the user to quit the pager
+func (p *pagerWriter) close() error {
+	closeErr := p.stdin.Close()
+	if errors.Is(closeErr, os.ErrClosed) || errors.Is(closeErr, syscall.EPIPE) {
+		closeErr = nil
+	}
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("the pager %s failed: %w", p.cmd.Path, err)
+	}
+	return closeErr
+}
+
+// pagerCommand returns the pager command to pipe the output through: the PAGER
+// environment variable, when the pager is enabled, not disabled by the --no-pager global
+// flag and the output is a terminal
+func pagerCommand(enabled bool, noPager bool, output io.Writer) (string, *os.File, bool) {
+	if !enabled || noPager {
+		return "", nil, false
+	}
+	terminal, isFile := output.(*os.File)
+	if !isFile || !isTerminal(terminal) {
+		return "", nil, false
+	}
+	command := strings.TrimSpace(os.Getenv(PagerEnvVar))
+	return command, terminal, command != ""
+}
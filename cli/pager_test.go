@@ -0,0 +1,131 @@
+//go:build unix
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestItCanWriteThroughAPager(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		wantOutput string
+		wantErr    string
+	}{
+		{
+			name:       "pager with args",
+			command:    "cat -",
+			wantOutput: "a long help\n",
+		},
+		{
+			name:    "failing pager",
+			command: "false",
+			wantErr: "the pager",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				terminal, err := os.Create(filepath.Join(t.TempDir(), "terminal"))
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				defer terminal.Close()
+
+				pager, err := startPager(tt.command, terminal, io.Discard)
+				if err != nil {
+					t.Fatalf("startPager() error = %v, want nil", err)
+				}
+				if _, err = io.WriteString(pager, "a long help\n"); err != nil {
+					t.Errorf("Write() error = %v, want nil", err)
+				}
+				err = pager.close()
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("close() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Errorf("close() error = %v, want nil", err)
+				}
+				if content, _ := os.ReadFile(terminal.Name()); string(content) != tt.wantOutput {
+					t.Errorf("terminal output = %q, want %q", content, tt.wantOutput)
+				}
+			},
+		)
+	}
+}
+
+func TestItFailsToStartAMissingPager(t *testing.T) {
+	for _, command := range []string{"", "  ", "/nonexistent/pager"} {
+		if _, err := startPager(command, os.Stdout, io.Discard); err == nil {
+			t.Errorf("startPager(%q) error = nil, want an error", command)
+		}
+	}
+}
+
+func TestItOnlyPagesTerminalOutput(t *testing.T) {
+	t.Setenv(PagerEnvVar, "cat")
+	file, err := os.Create(filepath.Join(t.TempDir(), "output"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer file.Close()
+
+	tests := []struct {
+		name    string
+		enabled bool
+		noPager bool
+		output  io.Writer
+	}{
+		{name: "disabled", enabled: false, output: os.Stdout},
+		{name: "disabled by flag", enabled: true, noPager: true, output: os.Stdout},
+		{name: "buffer output", enabled: true, output: &bytes.Buffer{}},
+		{name: "regular file output", enabled: true, output: file},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if _, _, page := pagerCommand(tt.enabled, tt.noPager, tt.output); page {
+					t.Errorf("pagerCommand() page = true, want false")
+				}
+			},
+		)
+	}
+}
+
+func TestItWritesNonTerminalOutputDirectlyWithThePagerEnabled(t *testing.T) {
+	t.Setenv(PagerEnvVar, "false")
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockCommand{
+			id: "report",
+			execFunc: func(writer io.Writer) error {
+				_, err := io.WriteString(writer, "report\n")
+				return err
+			},
+		},
+	)
+
+	for _, args := range [][]string{{"report"}, {"--no-pager", "report"}} {
+		var output, errOutput bytes.Buffer
+		exitCode, err := Run(args, registry, &output, WithPager(), WithErrorWriter(&errOutput))
+
+		if exitCode != StatusOk || err != nil {
+			t.Errorf("Run(%v) = (%d, %v), want (%d, nil)", args, exitCode, err, StatusOk)
+		}
+		if output.String() != "report\n" || errOutput.Len() != 0 {
+			t.Errorf("Run(%v) output = %q, errors = %q, want the report only", args, output.String(), errOutput.String())
+		}
+	}
+}
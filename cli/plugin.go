@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PluginMetaFlag is the flag a plugin binary is called with to query its metadata. The
+// binary must print its command id on the first line, followed by its description.
+const PluginMetaFlag = "--cli-meta"
+
+// PluginMetaTimeout is how long a plugin binary is given to print its metadata
+const PluginMetaTimeout = 5 * time.Second
+
+// PluginCommand is a command running an external executable, the plugin binary. It is
+// created by RegisterPluginDir from the metadata printed by the binary.
+type PluginCommand struct {
+	CommandWithoutFlags
+	path        string
+	id          string
+	description string
+	args        []string
+}
+
+// NewPluginCommand queries the plugin binary at the given path for its metadata and
+// returns a command running it
+func NewPluginCommand(path string) (*PluginCommand, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), PluginMetaTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, PluginMetaFlag).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the metadata of plugin %s: %w", path, err)
+	}
+
+	id, description, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, fmt.Errorf("plugin %s printed no command id", path)
+	}
+
+	return &PluginCommand{
+		path:        path,
+		id:          id,
+		description: strings.TrimSpace(description),
+	}, nil
+}
+
+func (p *PluginCommand) Id() string {
+	return p.id
+}
+
+func (p *PluginCommand) Description() string {
+	return p.description
+}
+
+// Path returns the path of the plugin binary
+func (p *PluginCommand) Path() string {
+	return p.path
+}
+
+// DefineFlags defines no flags, the args being forwarded to the plugin binary as is
+func (p *PluginCommand) DefineFlags(flagSet *flag.FlagSet) {
+}
+
+// RawArgs makes runCommand forward all the args, flags included, to the plugin binary
+func (p *PluginCommand) RawArgs() {
+}
+
+// SetArgs receives the args forwarded to the plugin binary
+func (p *PluginCommand) SetArgs(args []string) {
+	p.args = args
+}
+
+func (p *PluginCommand) Exec(stdWriter io.Writer) error {
+	return p.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext runs the plugin binary with the forwarded args, the command input as its
+// stdin, the output writer as its stdout and the error writer as its stderr. The
+// binary is killed when the context is cancelled. When it exits with a non-zero code,
+// the returned error makes Bootstrap exit with the same code, without reporting another
+// failure message, the binary being expected to report its own failures.
+func (p *PluginCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	cmd := exec.CommandContext(ctx, p.path, p.args...)
+	cmd.Stdin = Input(ctx)
+	cmd.Stdout = stdWriter
+	cmd.Stderr = ErrWriter(ctx)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return NewExitError(exitErr.ExitCode(), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run plugin %s: %w", p.path, err)
+	}
+	return nil
+}
+
+// RegisterPluginDir registers a PluginCommand for each executable file of the directory,
+// symlinks to executable files included. Binaries whose metadata cannot be queried, or
+// whose command cannot be registered, are skipped with a warning written to os.Stderr. It
+// only fails if the directory cannot be read.
+func RegisterPluginDir(registry *CommandsRegistry, dir string) error {
+	return registerPluginDir(registry, dir, os.Stderr)
+}
+
+func registerPluginDir(registry *CommandsRegistry, dir string, warnWriter io.Writer) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin dir %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		// Symlinks, the usual way plugins are installed, are followed to their binary
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || !isExecutableFile(info) {
+			continue
+		}
+
+		plugin, err := NewPluginCommand(path)
+		if err == nil {
+			err = registry.Register(plugin)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(warnWriter, "Warning: skipping plugin %s: %s\n", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package cli
+
+import (
+	"os"
+)
+
+// isExecutableFile reports whether the file has an execute permission bit set
+func isExecutableFile(info os.FileInfo) bool {
+	return info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,129 @@
+//go:build unix
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes an executable shell script plugin into the directory
+func writePlugin(t *testing.T, dir string, name string, script string) {
+	t.Helper()
+	err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755)
+	if err != nil {
+		t.Fatalf("failed to write plugin %s: %v", name, err)
+	}
+}
+
+func TestItCanRegisterPluginsFromADirectory(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(
+		t, dir, "greeter", `if [ "$1" = "--cli-meta" ]; then
+  printf 'greet\nGreets from a plugin\n'
+  exit 0
+fi
+echo "hello $*"
+`,
+	)
+	writePlugin(
+		t, dir, "failing", `if [ "$1" = "--cli-meta" ]; then
+  printf 'fail\nAlways fails\n'
+  exit 0
+fi
+echo "fail: bad input" >&2
+exit 3
+`,
+	)
+	writePlugin(t, dir, "broken", "exit 1\n")
+	writePlugin(t, dir, "invalid", "echo 'not a valid id'\n")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a plugin"), 0644); err != nil {
+		t.Fatalf("failed to write notes.txt: %v", err)
+	}
+
+	registry := NewCommandsRegistry()
+	var warnings bytes.Buffer
+	if err := registerPluginDir(registry, dir, &warnings); err != nil {
+		t.Fatalf("registerPluginDir() error = %v, want nil", err)
+	}
+
+	if len(registry.Commands()) != 2 {
+		t.Fatalf("registerPluginDir() registered %v, want greet and fail", registry.Commands())
+	}
+	for _, name := range []string{"broken", "invalid"} {
+		if !strings.Contains(warnings.String(), "skipping plugin "+filepath.Join(dir, name)) {
+			t.Errorf("registerPluginDir() warnings = %q, want one for %s", warnings.String(), name)
+		}
+	}
+
+	greet, _ := registry.Command("greet")
+	if greet.Description() != "Greets from a plugin" {
+		t.Errorf("Description() = %q, want %q", greet.Description(), "Greets from a plugin")
+	}
+
+	var out bytes.Buffer
+	err := runCommand(context.Background(), greet, []string{"--name", "john"}, &out, &out)
+	if err != nil || out.String() != "hello --name john\n" {
+		t.Errorf("runCommand() = %q, %v, want the plugin output", out.String(), err)
+	}
+
+	fail, _ := registry.Command("fail")
+	var errOut bytes.Buffer
+	err = runCommand(context.Background(), fail, nil, &out, &errOut)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || !isSilentExit(err) {
+		t.Errorf("runCommand() error = %v, want a silent exit with code 3", err)
+	}
+	if errOut.String() != "fail: bad input\n" {
+		t.Errorf("runCommand() error output = %q, want the plugin stderr", errOut.String())
+	}
+}
+
+func TestItCanRegisterSymlinkedPlugins(t *testing.T) {
+	binDir := t.TempDir()
+	writePlugin(
+		t, binDir, "greeter", `if [ "$1" = "--cli-meta" ]; then
+  printf 'greet\nGreets from a plugin\n'
+  exit 0
+fi
+echo "hello"
+`,
+	)
+
+	pluginDir := t.TempDir()
+	if err := os.Symlink(filepath.Join(binDir, "greeter"), filepath.Join(pluginDir, "greeter")); err != nil {
+		t.Fatalf("failed to symlink the plugin: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(binDir, "missing"), filepath.Join(pluginDir, "dangling")); err != nil {
+		t.Fatalf("failed to symlink the missing plugin: %v", err)
+	}
+
+	registry := NewCommandsRegistry()
+	var warnings bytes.Buffer
+	if err := registerPluginDir(registry, pluginDir, &warnings); err != nil {
+		t.Fatalf("registerPluginDir() error = %v, want nil", err)
+	}
+
+	greet, exists := registry.Command("greet")
+	if !exists || len(registry.Commands()) != 1 {
+		t.Fatalf("registerPluginDir() registered %v, want the symlinked greet plugin", registry.Commands())
+	}
+	if path := greet.(*PluginCommand).Path(); path != filepath.Join(pluginDir, "greeter") {
+		t.Errorf("Path() = %q, want the symlink path", path)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("registerPluginDir() warnings = %q, want none", warnings.String())
+	}
+}
+
+func TestItFailsToRegisterPluginsFromAMissingDirectory(t *testing.T) {
+	err := RegisterPluginDir(NewCommandsRegistry(), filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Errorf("RegisterPluginDir() error = nil, want an error")
+	}
+}
@@ -0,0 +1,12 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isExecutableFile reports whether the file has the .exe extension
+func isExecutableFile(info os.FileInfo) bool {
+	return strings.EqualFold(filepath.Ext(info.Name()), ".exe")
+}
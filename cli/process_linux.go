@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processStartId returns an identifier of the process start (its start time in clock
+// ticks since boot), used to detect PID reuse. Returns an empty string if unknown.
+func processStartId(pid int) string {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ""
+	}
+
+	// The command name (2nd field) may contain spaces, so split after its closing paren
+	commEnd := strings.LastIndexByte(string(stat), ')')
+	if commEnd < 0 {
+		return ""
+	}
+
+	// The start time is the 22nd field, the 20th one after the command name
+	fields := strings.Fields(string(stat[commEnd+1:]))
+	if len(fields) < 20 {
+		return ""
+	}
+	return fields[19]
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"os"
+)
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
+
+// processStartId returns an identifier of the process start, used to detect PID reuse.
+// Not supported on Windows, so it always returns an empty string.
+func processStartId(int) string {
+	return ""
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressReporter reports the progress of a long-running command. Commands implementing
+// ContextualCommand obtain it with Progress(ctx).
+type ProgressReporter interface {
+	// SetTotal sets the number of steps of the work, 0 when unknown
+	SetTotal(total int)
+
+	// Increment marks one more step as done
+	Increment()
+}
+
+const (
+	// progressBarWidth is the number of cells of the progress bar drawn on terminals
+	progressBarWidth = 30
+
+	// terminalProgressInterval is the minimum time between two progress bar redraws
+	terminalProgressInterval = 100 * time.Millisecond
+
+	// plainProgressInterval is the minimum time between two progress lines written to
+	// outputs which are not terminals, like files or pipes
+	plainProgressInterval = time.Second
+)
+
+// progressKey is the context key of the progress reporter
+type progressKey struct{}
+
+// noopProgressReporter is used when progress reporting is not enabled
+type noopProgressReporter struct{}
+
+func (noopProgressReporter) SetTotal(int) {}
+func (noopProgressReporter) Increment()   {}
+
+// writerProgressReporter renders the progress to a writer. On terminals, a progress bar
+// is redrawn in place, otherwise periodic percentage lines are written. Rendering is
+// throttled to avoid flooding the output.
+type writerProgressReporter struct {
+	mu          sync.Mutex
+	writer      io.Writer
+	interactive bool
+	interval    time.Duration
+	total       int
+	current     int
+	lastRender  time.Time
+	lastPercent int
+}
+
+// NewProgressReporter returns a ProgressReporter rendering to the writer, drawing a
+// progress bar when the writer is a terminal and writing percentage lines otherwise
+func NewProgressReporter(writer io.Writer) ProgressReporter {
+	if isTerminal(writer) {
+		return newWriterProgressReporter(writer, true, terminalProgressInterval)
+	}
+	return newWriterProgressReporter(writer, false, plainProgressInterval)
+}
+
+func newWriterProgressReporter(
+	writer io.Writer,
+	interactive bool,
+	interval time.Duration,
+) *writerProgressReporter {
+	return &writerProgressReporter{
+		writer:      writer,
+		interactive: interactive,
+		interval:    interval,
+		lastPercent: -1,
+	}
+}
+
+func (r *writerProgressReporter) SetTotal(total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total = max(total, 0)
+}
+
+func (r *writerProgressReporter) Increment() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current++
+	done := r.total > 0 && r.current >= r.total
+	if !done && time.Since(r.lastRender) < r.interval {
+		return
+	}
+	r.lastRender = time.Now()
+	r.render(done)
+}
+
+// render writes the current progress. It must be called with the mutex held.
+func (r *writerProgressReporter) render(done bool) {
+	if r.total == 0 {
+		if r.interactive {
+			_, _ = fmt.Fprintf(r.writer, "\r%d done", r.current)
+		} else {
+			_, _ = fmt.Fprintf(r.writer, "Progress: %d done\n", r.current)
+		}
+		return
+	}
+
+	percent := min(r.current*100/r.total, 100)
+	if !r.interactive {
+		// Only write a new line when the percentage changed
+		if percent != r.lastPercent {
+			_, _ = fmt.Fprintf(r.writer, "Progress: %d%% (%d/%d)\n", percent, r.current, r.total)
+		}
+		r.lastPercent = percent
+		return
+	}
+
+	filled := percent * progressBarWidth / 100
+	_, _ = fmt.Fprintf(
+		r.writer,
+		"\r[%s%s] %3d%% (%d/%d)",
+		strings.Repeat("#", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		percent,
+		r.current,
+		r.total,
+	)
+	if done {
+		_, _ = fmt.Fprintln(r.writer)
+	}
+}
+
+// isTerminal reports whether the writer is a character device, like a terminal
+func isTerminal(writer io.Writer) bool {
+	file, ok := writerFile(writer)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// withProgress returns a context holding the progress reporter
+func withProgress(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// Progress returns the progress reporter from the context passed to commands implementing
+// ContextualCommand. When progress reporting is not enabled via WithProgressReporting, a
+// no-op reporter is returned, so commands can always call it safely.
+func Progress(ctx context.Context) ProgressReporter {
+	if reporter, ok := ctx.Value(progressKey{}).(ProgressReporter); ok && reporter != nil {
+		return reporter
+	}
+	return noopProgressReporter{}
+}
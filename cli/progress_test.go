@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestItCanReportProgress(t *testing.T) {
+	tests := []struct {
+		name        string
+		interactive bool
+		total       int
+		increments  int
+		want        string
+	}{
+		{
+			name:       "percentage lines on non terminals",
+			total:      4,
+			increments: 4,
+			want: "Progress: 25% (1/4)\n" +
+				"Progress: 50% (2/4)\n" +
+				"Progress: 75% (3/4)\n" +
+				"Progress: 100% (4/4)\n",
+		},
+		{
+			name:       "unchanged percentages are not repeated",
+			total:      300,
+			increments: 2,
+			want:       "Progress: 0% (1/300)\n",
+		},
+		{
+			name:       "unknown total",
+			increments: 2,
+			want:       "Progress: 1 done\nProgress: 2 done\n",
+		},
+		{
+			name:        "progress bar on terminals",
+			interactive: true,
+			total:       2,
+			increments:  2,
+			want: "\r[###############               ]  50% (1/2)" +
+				"\r[##############################] 100% (2/2)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				reporter := newWriterProgressReporter(&buf, tt.interactive, 0)
+				reporter.SetTotal(tt.total)
+				for i := 0; i < tt.increments; i++ {
+					reporter.Increment()
+				}
+
+				if buf.String() != tt.want {
+					t.Errorf("Progress output = %q, want %q", buf.String(), tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestItThrottlesProgressRendering(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newWriterProgressReporter(&buf, false, plainProgressInterval)
+	reporter.SetTotal(100)
+	for i := 0; i < 100; i++ {
+		reporter.Increment()
+	}
+
+	// The first step and the completion are always rendered
+	want := "Progress: 1% (1/100)\nProgress: 100% (100/100)\n"
+	if buf.String() != want {
+		t.Errorf("Progress output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestItProvidesProgressReportersToCommands(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []BootstrapOption
+		wantOutput string
+	}{
+		{
+			name:       "progress reporting enabled",
+			opts:       []BootstrapOption{WithProgressReporting()},
+			wantOutput: "Progress: 100% (1/1)",
+		},
+		{
+			name:       "no-op reporter when not enabled",
+			wantOutput: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				_ = registry.Register(
+					&MockContextCommand{
+						MockCommand: MockCommand{id: "test-cmd"},
+						execContextFunc: func(ctx context.Context, writer io.Writer) error {
+							Progress(ctx).SetTotal(1)
+							Progress(ctx).Increment()
+							return nil
+						},
+					},
+				)
+
+				var buf bytes.Buffer
+				Bootstrap([]string{"test-cmd"}, registry, &buf, func(code int) {}, tt.opts...)
+
+				if !strings.Contains(buf.String(), tt.wantOutput) ||
+					(tt.wantOutput == "" && buf.Len() != 0) {
+					t.Errorf("Bootstrap() output = %q, want %q", buf.String(), tt.wantOutput)
+				}
+			},
+		)
+	}
+}
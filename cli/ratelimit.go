@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var CommandRateLimited = errors.New("command is rate limited, skipping execution")
+
+// rateLimitGuardTimeout is how long a RateLimitedCommand waits for another process to
+// finish checking and recording its own run
+const rateLimitGuardTimeout = 5 * time.Second
+
+// RateLimitedCommand is a wrapper refusing to execute the wrapped command again within a
+// minimum interval of its last run, whichever process ran it, for commands calling rate
+// limited external APIs. The time of the last run is recorded in a state file.
+type RateLimitedCommand struct {
+	// The command that is rate limited
+	Command Command
+
+	statePath   string
+	minInterval time.Duration
+}
+
+// NewRateLimitedCommand wraps the given command so that it runs at most once per
+// minInterval. The time of the last run is recorded in a state file of the stateDir, named
+// after the command id like DefaultLockFileName names lock files, with a ".last-run"
+// extension. Runs are recorded when they start, whether they succeed or not, and a run
+// refused because of the rate limit is not recorded.
+func NewRateLimitedCommand(cmd Command, stateDir string, minInterval time.Duration) Command {
+	fileName := strings.TrimSuffix(DefaultLockFileName(cmd.Id()), ".lock") + ".last-run"
+	return &RateLimitedCommand{
+		Command:     cmd,
+		statePath:   filepath.Join(stateDir, fileName),
+		minInterval: minInterval,
+	}
+}
+
+// Id returns the ID of the wrapped command.
+func (r *RateLimitedCommand) Id() string {
+	return r.Command.Id()
+}
+
+// Description returns the description of the wrapped command.
+func (r *RateLimitedCommand) Description() string {
+	return r.Command.Description()
+}
+
+// Unwrap returns the wrapped command.
+func (r *RateLimitedCommand) Unwrap() Command {
+	return r.Command
+}
+
+// DefineFlags delegates to the wrapped command.
+func (r *RateLimitedCommand) DefineFlags(flagSet *flag.FlagSet) {
+	r.Command.DefineFlags(flagSet)
+}
+
+// ValidateFlags delegates to the wrapped command.
+func (r *RateLimitedCommand) ValidateFlags() error {
+	return r.Command.ValidateFlags()
+}
+
+// Exec records the run and executes the wrapped command, unless it ran too recently.
+func (r *RateLimitedCommand) Exec(stdWriter io.Writer) error {
+	return r.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext records the run and executes the wrapped command with the given context.
+// If the command last ran less than the minimum interval ago, it returns an error wrapping
+// CommandRateLimited, with the time left before the next allowed run, without executing it.
+func (r *RateLimitedCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	if err := r.recordRun(time.Now()); err != nil {
+		return err
+	}
+	return execCommand(ctx, r.Command, stdWriter)
+}
+
+// recordRun checks that the command can run at the given time and records it as its last
+// run. A guard file lock makes the check and the record atomic across processes, and the
+// state file is replaced atomically, so that it is never read half-written.
+func (r *RateLimitedCommand) recordRun(now time.Time) (err error) {
+	guard, err := lockGuardFile(r.statePath+".guard", rateLimitGuardTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock the rate limit state of command %s: %w", r.Id(), err)
+	}
+	defer func() {
+		if unlockErr := unlockGuardFile(guard); unlockErr != nil && err == nil {
+			err = fmt.Errorf("failed to unlock the rate limit state of command %s: %w", r.Id(), unlockErr)
+		}
+	}()
+
+	lastRun, err := readLastRun(r.statePath)
+	if err != nil {
+		return err
+	}
+	// A last run in the future, after the clock was set back, does not block the command
+	if elapsed := now.Sub(lastRun); !lastRun.IsZero() && elapsed >= 0 && elapsed < r.minInterval {
+		return fmt.Errorf(
+			"%w: command %s last ran at %s, next run allowed in %s",
+			CommandRateLimited,
+			r.Id(),
+			lastRun.Format(time.RFC3339),
+			(r.minInterval - elapsed).Round(time.Millisecond),
+		)
+	}
+
+	return writeLastRun(r.statePath, now)
+}
+
+// readLastRun returns the time of the last run recorded in the state file, the zero time
+// if the command never ran
+func readLastRun(statePath string) (time.Time, error) {
+	content, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read the rate limit state %s: %w", statePath, err)
+	}
+
+	lastRun, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(content)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid rate limit state %s: %w", statePath, err)
+	}
+	return lastRun, nil
+}
+
+// writeLastRun atomically replaces the state file with the given time of the last run, by
+// renaming a temporary file written next to it
+func writeLastRun(statePath string, lastRun time.Time) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(statePath), filepath.Base(statePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write the rate limit state %s: %w", statePath, err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	_, err = tmpFile.WriteString(lastRun.Format(time.RFC3339Nano))
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), statePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the rate limit state %s: %w", statePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItCanRateLimitCommands(t *testing.T) {
+	stateDir := t.TempDir()
+	mockCmd := &MockLockableCommand{id: "sync:api"}
+
+	// Each run uses its own wrapper, like separate processes would
+	first := NewRateLimitedCommand(mockCmd, stateDir, 200*time.Millisecond)
+	if err := first.Exec(&bytes.Buffer{}); err != nil || !mockCmd.executed {
+		t.Fatalf("First Exec() error = %v, executed = %v, want nil and executed", err, mockCmd.executed)
+	}
+
+	mockCmd.executed = false
+	second := NewRateLimitedCommand(mockCmd, stateDir, 200*time.Millisecond)
+	err := second.Exec(&bytes.Buffer{})
+	if !errors.Is(err, CommandRateLimited) {
+		t.Fatalf("Second Exec() error = %v, want CommandRateLimited", err)
+	}
+	if !strings.Contains(err.Error(), "command sync:api last ran at") ||
+		!strings.Contains(err.Error(), "next run allowed in") {
+		t.Errorf("Second Exec() error = %q, want it to tell when the next run is allowed", err)
+	}
+	if mockCmd.executed {
+		t.Errorf("Rate limited command should not be executed")
+	}
+
+	stateFiles, _ := filepath.Glob(filepath.Join(stateDir, "go-cli-command-sync-api-*.last-run"))
+	if len(stateFiles) != 1 {
+		t.Errorf("State files = %v, want a single normalized state file", stateFiles)
+	}
+	if tmpFiles, _ := filepath.Glob(filepath.Join(stateDir, "*.tmp")); len(tmpFiles) != 0 {
+		t.Errorf("Temporary state files were left behind: %v", tmpFiles)
+	}
+	if guardFiles, _ := filepath.Glob(filepath.Join(stateDir, "*.guard")); len(guardFiles) != 0 {
+		t.Errorf("Guard files were left behind: %v", guardFiles)
+	}
+
+	// Once the interval elapsed, the command runs again
+	time.Sleep(200 * time.Millisecond)
+	if err = second.Exec(&bytes.Buffer{}); err != nil || !mockCmd.executed {
+		t.Errorf("Exec() after the interval error = %v, executed = %v, want nil and executed", err, mockCmd.executed)
+	}
+
+	// Other commands are not limited by it
+	other := NewRateLimitedCommand(&MockLockableCommand{id: "other"}, stateDir, time.Hour)
+	if err = other.Exec(&bytes.Buffer{}); err != nil {
+		t.Errorf("Exec() of another command error = %v, want nil", err)
+	}
+}
+
+func TestItRejectsInvalidRateLimitState(t *testing.T) {
+	stateDir := t.TempDir()
+	cmd := NewRateLimitedCommand(&MockLockableCommand{id: "sync"}, stateDir, time.Minute).(*RateLimitedCommand)
+	if err := os.WriteFile(cmd.statePath, []byte("yesterday"), 0o644); err != nil {
+		t.Fatalf("Failed to write the state file: %v", err)
+	}
+
+	err := cmd.Exec(&bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "invalid rate limit state") {
+		t.Errorf("Exec() error = %v, want an invalid rate limit state error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ReplPrompt is written before reading each line of the REPL
+const ReplPrompt = "> "
+
+// ReplCommand is an interactive mode reading command invocations, one per line, from the
+// command input and running them, so that a debugging session does not need to re-launch
+// the binary for each command. Lines are split into args on whitespace, single or double
+// quotes grouping words into one arg. The session ends at the end of the input, on an
+// "exit" line, or once the context is cancelled, which is checked between lines.
+type ReplCommand struct {
+	CommandWithoutFlags
+	registry *CommandsRegistry
+}
+
+// NewReplCommand creates a REPL command running the commands of the registry. The
+// commands are resolved like Bootstrap does, subcommands of groups included, and "help"
+// lists them.
+func NewReplCommand(registry *CommandsRegistry) Command {
+	return &ReplCommand{registry: registry}
+}
+
+func (c *ReplCommand) Id() string {
+	return "repl"
+}
+
+func (c *ReplCommand) Description() string {
+	return "Starts an interactive session running commands read line by line, until exit"
+}
+
+func (c *ReplCommand) Exec(stdWriter io.Writer) error {
+	return c.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext reads the invocations from the command input. Failures of the commands,
+// including unknown commands and flag parse errors, are written to stdWriter and do not
+// end the session.
+func (c *ReplCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	scanner := bufio.NewScanner(Input(ctx))
+	for ctx.Err() == nil {
+		_, _ = fmt.Fprint(stdWriter, ReplPrompt)
+		if !scanner.Scan() {
+			_, _ = fmt.Fprintln(stdWriter)
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "exit" {
+			return nil
+		}
+		if line == "" {
+			continue
+		}
+
+		if err := c.runLine(ctx, line, stdWriter); err != nil && !isSilentExit(err) {
+			_, _ = fmt.Fprintf(stdWriter, "Error: %s\n", strings.TrimSpace(err.Error()))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the REPL input: %w", err)
+	}
+	return ctx.Err()
+}
+
+// runLine resolves and runs the command invocation of a REPL line
+func (c *ReplCommand) runLine(ctx context.Context, line string, stdWriter io.Writer) error {
+	args, err := splitReplLine(line)
+	if err != nil {
+		return err
+	}
+
+	cmdId, cmdArgs := parseCmdInput(args)
+	if cmdId == c.Id() {
+		return errors.New("already in the REPL")
+	}
+
+	cmd, exists := c.registry.Command(cmdId)
+	if !exists && cmdId == (&HelpCommand{}).Id() {
+		cmd, exists = NewHelpCommand(slices.Collect(maps.Values(c.registry.Commands()))), true
+	}
+	if exists {
+		cmd, cmdId, cmdArgs, exists = resolveSubcommand(cmd, cmdArgs)
+	}
+	if !exists {
+		requestedId := cmdId[strings.LastIndex(cmdId, " ")+1:]
+		candidateIds := slices.Collect(maps.Keys(c.registry.Commands()))
+		return &CommandNotFoundError{
+			Id:          cmdId,
+			Suggestions: suggestCommandIds(requestedId, candidateIds),
+		}
+	}
+
+	var parseErr *FlagParseError
+	err = runCommand(ctx, cmd, cmdArgs, stdWriter, stdWriter)
+	if errors.As(err, &parseErr) && !flagErrorPolicyFrom(ctx).silent {
+		// Already reported by the flag package, along with the usage
+		return nil
+	}
+	return err
+}
+
+// splitReplLine splits a REPL line into args on whitespace, single or double quotes
+// grouping words, including spaces, into a single arg
+func splitReplLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, char := range line {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(char)
+		case char == '"' || char == '\'':
+			quote, inArg = char, true
+		case char == ' ' || char == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
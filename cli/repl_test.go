@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestItCanRunCommandsInTheRepl(t *testing.T) {
+	var executed []string
+	var executedSubcommand string
+	registry := NewCommandsRegistry()
+	_ = registry.RegisterAll(
+		&MockArgsCommand{
+			MockCommandWithFlags: MockCommandWithFlags{
+				id: "greet",
+				execFunc: func(writer io.Writer) error {
+					executed = append(executed, "greet")
+					_, _ = fmt.Fprintln(writer, "Hello")
+					return nil
+				},
+			},
+		},
+		&MockCommand{
+			id: "fail",
+			execFunc: func(writer io.Writer) error {
+				return errors.New("boom")
+			},
+		},
+		newTestDbGroup(&executedSubcommand),
+	)
+
+	tests := []struct {
+		name           string
+		input          string
+		wantExecuted   []string
+		wantSubcommand string
+		wantOutput     []string
+		absentOutput   []string
+	}{
+		{
+			name:         "runs commands until the end of the input",
+			input:        "greet\n\ngreet --test-flag 'a b'\n",
+			wantExecuted: []string{"greet", "greet"},
+			wantOutput:   []string{"> Hello\n> > Hello\n> \n"},
+		},
+		{
+			name:         "stops at the exit line",
+			input:        "greet\nexit\ngreet\n",
+			wantExecuted: []string{"greet"},
+		},
+		{
+			name:           "resolves subcommands",
+			input:          "db migrate up\n",
+			wantSubcommand: "up",
+		},
+		{
+			name:         "reports unknown commands and keeps going",
+			input:        "gret\ngreet\n",
+			wantExecuted: []string{"greet"},
+			wantOutput:   []string{"Error: The command gret does not exist", "greet"},
+		},
+		{
+			name:         "reports command failures and keeps going",
+			input:        "fail\ngreet\n",
+			wantExecuted: []string{"greet"},
+			wantOutput:   []string{"Error: boom"},
+		},
+		{
+			name:         "reports flag parse errors and keeps going",
+			input:        "greet --unknown\ngreet\n",
+			wantExecuted: []string{"greet"},
+			wantOutput:   []string{"flag provided but not defined: -unknown"},
+		},
+		{
+			name:       "reports unterminated quotes",
+			input:      "greet 'a b\n",
+			wantOutput: []string{"Error: unterminated ' quote"},
+		},
+		{
+			name:         "lists the commands with help",
+			input:        "help\n",
+			wantOutput:   []string{"greet", "fail", "db"},
+			absentOutput: []string{"Error:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				executed, executedSubcommand = nil, ""
+				repl := NewReplCommand(registry).(*ReplCommand)
+				ctx := withInput(context.Background(), strings.NewReader(tt.input))
+
+				var buf bytes.Buffer
+				err := repl.ExecContext(ctx, &buf)
+
+				if err != nil {
+					t.Fatalf("ExecContext() error = %v, want nil", err)
+				}
+				if strings.Join(executed, ",") != strings.Join(tt.wantExecuted, ",") {
+					t.Errorf("executed = %v, want %v", executed, tt.wantExecuted)
+				}
+				if executedSubcommand != tt.wantSubcommand {
+					t.Errorf("executed subcommand = %q, want %q", executedSubcommand, tt.wantSubcommand)
+				}
+				for _, want := range tt.wantOutput {
+					if !strings.Contains(buf.String(), want) {
+						t.Errorf("output = %q, want it to contain %q", buf.String(), want)
+					}
+				}
+				for _, absent := range tt.absentOutput {
+					if strings.Contains(buf.String(), absent) {
+						t.Errorf("output = %q, want it not to contain %q", buf.String(), absent)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestItCanSplitReplLines(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantArgs []string
+	}{
+		{line: "greet", wantArgs: []string{"greet"}},
+		{line: "  greet   --name  john ", wantArgs: []string{"greet", "--name", "john"}},
+		{line: `greet --name "john doe"`, wantArgs: []string{"greet", "--name", "john doe"}},
+		{line: `greet --name='it is' ""`, wantArgs: []string{"greet", "--name=it is", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.line, func(t *testing.T) {
+				args, err := splitReplLine(tt.line)
+
+				if err != nil {
+					t.Fatalf("splitReplLine() error = %v, want nil", err)
+				}
+				if fmt.Sprintf("%q", args) != fmt.Sprintf("%q", tt.wantArgs) {
+					t.Errorf("splitReplLine() = %q, want %q", args, tt.wantArgs)
+				}
+			},
+		)
+	}
+}
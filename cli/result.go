@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"errors"
+	"time"
+)
+
+// ExecResult is the result of a command execution, for richer logging and testing than
+// the command error alone
+type ExecResult struct {
+	// How long the execution took
+	Duration time.Duration
+
+	// Whether the command panicked, the panic being recovered and returned as a PanicError
+	Panicked bool
+
+	// The exit code of the execution, see ExitCoder
+	ExitCode int
+
+	// The error returned by the command, nil on success
+	Err error
+
+	// The structured result of a successful execution of a ResultCommand, nil otherwise
+	Value any
+}
+
+// ResultCommand is an optional interface for commands computing a value the calling
+// program needs, like a count or an id, besides their text output. Result is called once
+// the command executed successfully, its value being returned in ExecResult.Value by
+// RunWithResult and made available to middlewares. It is only meaningful when running
+// commands programmatically, Bootstrap exiting the process right after the execution.
+type ResultCommand interface {
+	Command
+	Result() any
+}
+
+// commandResult returns the result of the command when it implements ResultCommand and
+// its execution did not fail
+func commandResult(cmd Command, err error) any {
+	if err != nil {
+		return nil
+	}
+	if resultCmd, ok := findOptional[ResultCommand](cmd); ok {
+		return resultCmd.Result()
+	}
+	return nil
+}
+
+// newExecResult returns the result of an execution which returned err
+func newExecResult(exitCode int, err error, duration time.Duration) ExecResult {
+	var panicErr *PanicError
+	return ExecResult{
+		Duration: duration,
+		Panicked: errors.As(err, &panicErr),
+		ExitCode: exitCode,
+		Err:      err,
+	}
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestItCanReturnTheExecutionResult(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.RegisterAll(
+		&MockCommand{
+			id: "slow-cmd",
+			execFunc: func(writer io.Writer) error {
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			},
+		},
+		&MockCommand{
+			id: "exit-cmd",
+			execFunc: func(writer io.Writer) error {
+				return NewExitError(3, errors.New("bad input"))
+			},
+		},
+		&MockCommand{
+			id: "panic-cmd",
+			execFunc: func(writer io.Writer) error {
+				panic("boom")
+			},
+		},
+	)
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantPanicked bool
+		wantErr      bool
+		minDuration  time.Duration
+	}{
+		{name: "success", args: []string{"slow-cmd"}, wantExitCode: StatusOk, minDuration: 10 * time.Millisecond},
+		{name: "exit code", args: []string{"exit-cmd"}, wantExitCode: 3, wantErr: true},
+		{name: "panic", args: []string{"panic-cmd"}, wantExitCode: StatusErr, wantPanicked: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var middlewareResult *ExecResult
+				result := RunWithResult(
+					tt.args,
+					registry,
+					io.Discard,
+					WithErrorWriter(io.Discard),
+					WithoutSignalHandling(),
+					WithMiddlewares(
+						func(next CommandHandler) CommandHandler {
+							return func(ctx context.Context, invocation Invocation) error {
+								err := next(ctx, invocation)
+								middlewareResult = invocation.Result
+								return err
+							}
+						},
+					),
+				)
+
+				if result.ExitCode != tt.wantExitCode || result.Panicked != tt.wantPanicked ||
+					(result.Err != nil) != tt.wantErr || result.Duration < tt.minDuration {
+					t.Errorf("RunWithResult() = %+v, want exit code %d, panicked %v", result, tt.wantExitCode, tt.wantPanicked)
+				}
+				if middlewareResult == nil || middlewareResult.ExitCode != tt.wantExitCode ||
+					middlewareResult.Panicked != tt.wantPanicked || middlewareResult.Duration < tt.minDuration {
+					t.Errorf("Invocation.Result = %+v, want the execution result", middlewareResult)
+				}
+			},
+		)
+	}
+}
+
+// MockResultCommand is a ResultCommand implementation for testing
+type MockResultCommand struct {
+	MockCommand
+	count int
+}
+
+func (m *MockResultCommand) Result() any {
+	return m.count
+}
+
+func TestItCanReturnTheCommandResultValue(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.RegisterAll(
+		&MockResultCommand{
+			MockCommand: MockCommand{id: "count-cmd"},
+			count:       42,
+		},
+		&MockResultCommand{
+			MockCommand: MockCommand{
+				id: "failing-count-cmd",
+				execFunc: func(writer io.Writer) error {
+					return errors.New("count failed")
+				},
+			},
+			count: 7,
+		},
+	)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantValue any
+		wantErr   bool
+	}{
+		{name: "success", args: []string{"count-cmd"}, wantValue: 42},
+		{name: "failure", args: []string{"failing-count-cmd"}, wantValue: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var middlewareValue any
+				result := RunWithResult(
+					tt.args,
+					registry,
+					io.Discard,
+					WithErrorWriter(io.Discard),
+					WithoutSignalHandling(),
+					WithMiddlewares(
+						func(next CommandHandler) CommandHandler {
+							return func(ctx context.Context, invocation Invocation) error {
+								err := next(ctx, invocation)
+								middlewareValue = invocation.Result.Value
+								return err
+							}
+						},
+					),
+				)
+
+				if (result.Err != nil) != tt.wantErr {
+					t.Errorf("RunWithResult() Err = %v, wantErr %v", result.Err, tt.wantErr)
+				}
+				if result.Value != tt.wantValue {
+					t.Errorf("RunWithResult() Value = %v, want %v", result.Value, tt.wantValue)
+				}
+				if middlewareValue != tt.wantValue {
+					t.Errorf("Invocation.Result.Value = %v, want %v", middlewareValue, tt.wantValue)
+				}
+			},
+		)
+	}
+}
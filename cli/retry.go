@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"time"
+)
+
+// RetryClassifier reports whether a failed command execution should be retried
+type RetryClassifier func(err error) bool
+
+// RetryOption configures a RetryableCommand
+type RetryOption func(*RetryableCommand)
+
+// WithRetryClassifier sets the classifier deciding which errors are retried. By default,
+// every error is retried. CommandLocked and CommandRateLimited errors are never retried,
+// whatever the classifier.
+func WithRetryClassifier(classifier RetryClassifier) RetryOption {
+	return func(r *RetryableCommand) {
+		r.isRetryable = classifier
+	}
+}
+
+// RetryableCommand is a wrapper re-executing the wrapped command when it fails, for
+// commands interacting with flaky external services.
+type RetryableCommand struct {
+	// The command that is retried
+	Command Command
+
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
+	isRetryable RetryClassifier
+}
+
+// NewRetryableCommand wraps the given command so that it is executed up to maxAttempts
+// times while it fails. Before each retry, it sleeps for the duration returned by
+// backoff, called with the number of the failed attempt (starting at 1). A nil backoff
+// retries right away. Output written by failed attempts is not discarded.
+func NewRetryableCommand(
+	cmd Command,
+	maxAttempts int,
+	backoff func(attempt int) time.Duration,
+	opts ...RetryOption,
+) Command {
+	retryable := &RetryableCommand{
+		Command:     cmd,
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     backoff,
+	}
+	for _, opt := range opts {
+		opt(retryable)
+	}
+	return retryable
+}
+
+// Id returns the ID of the wrapped command.
+func (r *RetryableCommand) Id() string {
+	return r.Command.Id()
+}
+
+// Description returns the description of the wrapped command.
+func (r *RetryableCommand) Description() string {
+	return r.Command.Description()
+}
+
+// Unwrap returns the wrapped command.
+func (r *RetryableCommand) Unwrap() Command {
+	return r.Command
+}
+
+// DefineFlags delegates to the wrapped command.
+func (r *RetryableCommand) DefineFlags(flagSet *flag.FlagSet) {
+	r.Command.DefineFlags(flagSet)
+}
+
+// ValidateFlags delegates to the wrapped command.
+func (r *RetryableCommand) ValidateFlags() error {
+	return r.Command.ValidateFlags()
+}
+
+// Exec executes the wrapped command, retrying it while it fails.
+func (r *RetryableCommand) Exec(stdWriter io.Writer) error {
+	return r.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext executes the wrapped command with the given context, retrying it while it
+// fails with a retryable error. It returns the error of the last attempt. Retrying stops
+// when the context is cancelled, the context error being returned if it happens while
+// waiting between attempts.
+func (r *RetryableCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	var err error
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		err = execCommand(ctx, r.Command, stdWriter)
+		if err == nil || attempt == r.maxAttempts || !r.shouldRetry(ctx, err) {
+			return err
+		}
+
+		if r.backoff == nil {
+			continue
+		}
+
+		timer := time.NewTimer(r.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// shouldRetry reports whether the failed attempt can be retried
+func (r *RetryableCommand) shouldRetry(ctx context.Context, err error) bool {
+	if errors.Is(err, CommandLocked) || errors.Is(err, CommandRateLimited) || ctx.Err() != nil {
+		return false
+	}
+	return r.isRetryable == nil || r.isRetryable(err)
+}
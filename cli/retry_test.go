@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestItCanRetryFailingCommands(t *testing.T) {
+	errFlaky := errors.New("service unavailable")
+	errFatal := errors.New("invalid credentials")
+
+	tests := []struct {
+		name         string
+		failures     []error
+		maxAttempts  int
+		opts         []RetryOption
+		wantErr      error
+		wantAttempts int
+		wantBackoffs []int
+	}{
+		{
+			name:         "succeeds after retries",
+			failures:     []error{errFlaky, errFlaky},
+			maxAttempts:  3,
+			wantErr:      nil,
+			wantAttempts: 3,
+			wantBackoffs: []int{1, 2},
+		},
+		{
+			name:         "returns the last error when all attempts fail",
+			failures:     []error{errFlaky, errFlaky, fmt.Errorf("last: %w", errFlaky)},
+			maxAttempts:  3,
+			wantErr:      errFlaky,
+			wantAttempts: 3,
+			wantBackoffs: []int{1, 2},
+		},
+		{
+			name:         "runs once without retries",
+			failures:     []error{errFlaky},
+			maxAttempts:  0,
+			wantErr:      errFlaky,
+			wantAttempts: 1,
+		},
+		{
+			name:         "does not retry locked commands",
+			failures:     []error{fmt.Errorf("%w: command flaky", CommandLocked)},
+			maxAttempts:  3,
+			opts:         []RetryOption{WithRetryClassifier(func(err error) bool { return true })},
+			wantErr:      CommandLocked,
+			wantAttempts: 1,
+		},
+		{
+			name:         "does not retry rate limited commands",
+			failures:     []error{fmt.Errorf("%w: command flaky", CommandRateLimited)},
+			maxAttempts:  3,
+			wantErr:      CommandRateLimited,
+			wantAttempts: 1,
+		},
+		{
+			name:        "does not retry errors rejected by the classifier",
+			failures:    []error{errFlaky, errFatal, errFlaky},
+			maxAttempts: 5,
+			opts: []RetryOption{
+				WithRetryClassifier(
+					func(err error) bool {
+						return !errors.Is(err, errFatal)
+					},
+				),
+			},
+			wantErr:      errFatal,
+			wantAttempts: 2,
+			wantBackoffs: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				attempts := 0
+				var backoffs []int
+				cmd := NewRetryableCommand(
+					&MockCommand{
+						id: "flaky",
+						execFunc: func(writer io.Writer) error {
+							attempts++
+							if attempts <= len(tt.failures) {
+								return tt.failures[attempts-1]
+							}
+							return nil
+						},
+					},
+					tt.maxAttempts,
+					func(attempt int) time.Duration {
+						backoffs = append(backoffs, attempt)
+						return time.Millisecond
+					},
+					tt.opts...,
+				)
+
+				err := cmd.Exec(io.Discard)
+
+				if tt.wantErr == nil && err != nil {
+					t.Errorf("Exec() error = %v, want nil", err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Exec() error = %v, want %v", err, tt.wantErr)
+				}
+				if attempts != tt.wantAttempts {
+					t.Errorf("Exec() attempts = %d, want %d", attempts, tt.wantAttempts)
+				}
+				if fmt.Sprint(backoffs) != fmt.Sprint(tt.wantBackoffs) {
+					t.Errorf("backoff calls = %v, want %v", backoffs, tt.wantBackoffs)
+				}
+			},
+		)
+	}
+}
+
+func TestItStopsRetryingWhenTheContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	cmd := NewRetryableCommand(
+		&MockContextCommand{
+			MockCommand: MockCommand{id: "flaky"},
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				attempts++
+				cancel()
+				return errors.New("service unavailable")
+			},
+		},
+		3,
+		func(attempt int) time.Duration {
+			return time.Hour
+		},
+	)
+
+	err := cmd.(ContextualCommand).ExecContext(ctx, io.Discard)
+
+	if attempts != 1 {
+		t.Errorf("ExecContext() attempts = %d, want 1", attempts)
+	}
+	if err == nil || err.Error() != "service unavailable" {
+		t.Errorf("ExecContext() error = %v, want the command error", err)
+	}
+}
+
+func TestItAbortsTheRetryBackoffWhenTheContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	cmd := NewRetryableCommand(
+		&MockCommand{
+			id: "flaky",
+			execFunc: func(writer io.Writer) error {
+				attempts++
+				return errors.New("service unavailable")
+			},
+		},
+		3,
+		func(attempt int) time.Duration {
+			return time.Hour
+		},
+	)
+
+	start := time.Now()
+	err := cmd.(ContextualCommand).ExecContext(ctx, io.Discard)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if attempts != 1 {
+		t.Errorf("ExecContext() attempts = %d, want 1", attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ExecContext() should not wait for the backoff, took %v", elapsed)
+	}
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ScriptCommand is a command running a shell line, sh -c on Unix and cmd /C on Windows.
+// It is created by RegisterScriptCommands from a spec file, for tools generated from a
+// manifest.
+type ScriptCommand struct {
+	id          string
+	description string
+	script      string
+	args        []string
+}
+
+// NewScriptCommand returns a command running the given shell line. The args given to the
+// command are forwarded to the shell line, as "$1", "$2" and so on with sh.
+func NewScriptCommand(id string, description string, script string) *ScriptCommand {
+	return &ScriptCommand{id: id, description: description, script: script}
+}
+
+func (s *ScriptCommand) Id() string {
+	return s.id
+}
+
+func (s *ScriptCommand) Description() string {
+	return s.description
+}
+
+// Script returns the shell line run by the command
+func (s *ScriptCommand) Script() string {
+	return s.script
+}
+
+// DefineFlags defines no flags, the args being forwarded to the shell line as is
+func (s *ScriptCommand) DefineFlags(flagSet *flag.FlagSet) {
+}
+
+func (s *ScriptCommand) ValidateFlags() error {
+	return nil
+}
+
+// RawArgs makes runCommand forward all the args, flags included, to the shell line
+func (s *ScriptCommand) RawArgs() {
+}
+
+// SetArgs receives the args forwarded to the shell line
+func (s *ScriptCommand) SetArgs(args []string) {
+	s.args = args
+}
+
+func (s *ScriptCommand) Exec(stdWriter io.Writer) error {
+	return s.ExecContext(context.Background(), stdWriter)
+}
+
+// ExecContext runs the shell line, with the command input as its stdin, the output writer
+// as its stdout and the error writer as its stderr. The shell is killed when the
+// context is cancelled. When it exits with a non-zero code, the returned error makes
+// Bootstrap exit with the same code, without reporting another failure message, the
+// shell line being expected to report its own failures.
+func (s *ScriptCommand) ExecContext(ctx context.Context, stdWriter io.Writer) error {
+	name, shellArgs := scriptShell(s.script, s.args)
+	cmd := exec.CommandContext(ctx, name, shellArgs...)
+	cmd.Stdin = Input(ctx)
+	cmd.Stdout = stdWriter
+	cmd.Stderr = ErrWriter(ctx)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return NewExitError(exitErr.ExitCode(), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run script command %s: %w", s.id, err)
+	}
+	return nil
+}
+
+// scriptSpec is the spec of a script command, see RegisterScriptCommands
+type scriptSpec struct {
+	Description string `json:"description" yaml:"description"`
+	Run         string `json:"run" yaml:"run"`
+}
+
+// RegisterScriptCommands registers a ScriptCommand for each command of the spec file, a
+// JSON or YAML file (chosen by the ".yaml" or ".yml" extension, JSON otherwise) mapping
+// the command ids to their description and the shell line they run:
+//
+//	greet:
+//	  description: Says hello
+//	  run: echo "hello $1"
+//
+// The ids must be valid command ids, see CommandsRegistry.Register, and the run lines
+// must not be empty. Nothing is registered if the spec cannot be read or
+// is invalid, while registration failures, like ids already registered, are returned
+// after registering the other commands.
+func RegisterScriptCommands(registry *CommandsRegistry, specPath string) error {
+	content, err := os.ReadFile(specPath)
+	if err != nil {
+		return fmt.Errorf("failed to read script commands spec %s: %w", specPath, err)
+	}
+
+	var specs map[string]scriptSpec
+	switch strings.ToLower(filepath.Ext(specPath)) {
+	case ".yaml", ".yml":
+		specs, err = parseYamlScriptSpecs(content)
+	default:
+		specs, err = parseJsonScriptSpecs(content)
+	}
+	if err != nil {
+		return fmt.Errorf("malformed script commands spec %s: %w", specPath, err)
+	}
+
+	ids := slices.Sorted(maps.Keys(specs))
+	commands := make([]Command, 0, len(ids))
+	for _, id := range ids {
+		if err = validateCommandId(id); err != nil {
+			return fmt.Errorf("invalid script commands spec %s: %w", specPath, err)
+		}
+		if strings.TrimSpace(specs[id].Run) == "" {
+			return fmt.Errorf("invalid script commands spec %s: command %s has no run line", specPath, id)
+		}
+		commands = append(commands, NewScriptCommand(id, specs[id].Description, specs[id].Run))
+	}
+
+	if err = registry.RegisterAll(commands...); err != nil {
+		return fmt.Errorf("failed to register the script commands of %s: %w", specPath, err)
+	}
+	return nil
+}
+
+// parseJsonScriptSpecs parses a JSON object mapping command ids to their spec, rejecting
+// unknown spec keys
+func parseJsonScriptSpecs(content []byte) (map[string]scriptSpec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	var specs map[string]scriptSpec
+	if err := decoder.Decode(&specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// parseYamlScriptSpecs parses a YAML mapping of command ids to their spec, rejecting
+// unknown spec keys and ids defined more than once
+func parseYamlScriptSpecs(content []byte) (map[string]scriptSpec, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var specs map[string]scriptSpec
+	if err := decoder.Decode(&specs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return specs, nil
+}
@@ -0,0 +1,7 @@
+package cli
+
+// scriptShell returns the shell binary and args running the shell line with sh, the
+// given args being its positional parameters
+func scriptShell(script string, args []string) (string, []string) {
+	return "sh", append([]string{"-c", script, "sh"}, args...)
+}
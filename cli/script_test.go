@@ -0,0 +1,206 @@
+//go:build unix
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeScriptSpec writes the script commands spec into a temporary directory
+func writeScriptSpec(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec %s: %v", name, err)
+	}
+	return path
+}
+
+func TestItCanRegisterScriptCommandsFromASpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		spec     string
+	}{
+		{
+			name:     "json spec",
+			fileName: "commands.json",
+			spec: `{
+  "greet": {"description": "Says hello", "run": "echo \"hello $1\""},
+  "fail": {"description": "Fails with code 3", "run": "echo failed >&2; exit 3"}
+}`,
+		},
+		{
+			name:     "yaml spec",
+			fileName: "commands.yaml",
+			spec: `# Generated from the manifest
+greet:
+  description: Says hello
+  run: 'echo "hello $1"'
+
+fail:
+  description: "Fails with code 3" # trailing comment
+  run: echo failed >&2; exit 3
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				if err := RegisterScriptCommands(registry, writeScriptSpec(t, tt.fileName, tt.spec)); err != nil {
+					t.Fatalf("RegisterScriptCommands() error = %v, want nil", err)
+				}
+
+				greet, exists := registry.Command("greet")
+				if !exists || greet.Description() != "Says hello" {
+					t.Fatalf("Command greet = %v, want it registered with its description", greet)
+				}
+
+				var out bytes.Buffer
+				exitCode, err := Run([]string{"greet", "world"}, registry, &out, WithoutSignalHandling())
+				if exitCode != StatusOk || err != nil {
+					t.Errorf("Run(greet) = %d, %v, want %d, nil", exitCode, err, StatusOk)
+				}
+				if out.String() != "hello world\n" {
+					t.Errorf("Run(greet) output = %q, want %q", out.String(), "hello world\n")
+				}
+
+				var errOut bytes.Buffer
+				exitCode, _ = Run([]string{"fail"}, registry, io.Discard, WithErrorWriter(&errOut), WithoutSignalHandling())
+				if exitCode != 3 {
+					t.Errorf("Run(fail) exitCode = %d, want 3", exitCode)
+				}
+				if errOut.String() != "failed\n" {
+					t.Errorf("Run(fail) error output = %q, want only the script stderr", errOut.String())
+				}
+			},
+		)
+	}
+}
+
+func TestItRejectsInvalidScriptCommandSpecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		spec     string
+		wantErr  string
+	}{
+		{
+			name:     "yaml key without value",
+			fileName: "commands.yaml",
+			spec:     "greet\n",
+			wantErr:  "malformed script commands spec",
+		},
+		{
+			name:     "malformed json",
+			fileName: "commands.json",
+			spec:     `{"greet": {"run": "echo hello"`,
+			wantErr:  "malformed script commands spec",
+		},
+		{
+			name:     "unknown json key",
+			fileName: "commands.json",
+			spec:     `{"greet": {"command": "echo hello"}}`,
+			wantErr:  `unknown field "command"`,
+		},
+		{
+			name:     "unknown yaml key",
+			fileName: "commands.yml",
+			spec:     "greet:\n  command: echo hello\n",
+			wantErr:  "line 2: field command not found",
+		},
+		{
+			name:     "yaml command without spec keys",
+			fileName: "commands.yaml",
+			spec:     "greet: echo hello\n",
+			wantErr:  "line 1: cannot unmarshal !!str `echo hello`",
+		},
+		{
+			name:     "unterminated yaml string",
+			fileName: "commands.yaml",
+			spec:     "greet:\n  run: \"echo hello\n",
+			wantErr:  "malformed script commands spec",
+		},
+		{
+			name:     "duplicate yaml id",
+			fileName: "commands.yaml",
+			spec:     "greet:\n  run: echo hello\ngreet:\n  run: echo hi\n",
+			wantErr:  `mapping key "greet" already defined`,
+		},
+		{
+			name:     "invalid id",
+			fileName: "commands.yaml",
+			spec:     "say hello:\n  run: echo hello\n",
+			wantErr:  "invalid command id 'say hello'",
+		},
+		{
+			name:     "missing run line",
+			fileName: "commands.json",
+			spec:     `{"greet": {"description": "Says hello"}}`,
+			wantErr:  "command greet has no run line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				registry := NewCommandsRegistry()
+				err := RegisterScriptCommands(registry, writeScriptSpec(t, tt.fileName, tt.spec))
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RegisterScriptCommands() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if len(registry.Commands()) != 0 {
+					t.Errorf("RegisterScriptCommands() registered %d commands, want none", len(registry.Commands()))
+				}
+			},
+		)
+	}
+}
+
+func TestItCanRegisterNamespacedScriptCommands(t *testing.T) {
+	spec := `db:backup:
+  description: Backs up the database
+  run: 'echo "backup: done"'
+"db:restore":
+  run: echo restored
+'cache:clear' :
+  run: echo cleared
+cache:warm:
+  run: 'echo ''warm'' "up"'
+`
+	registry := NewCommandsRegistry()
+	if err := RegisterScriptCommands(registry, writeScriptSpec(t, "commands.yaml", spec)); err != nil {
+		t.Fatalf("RegisterScriptCommands() error = %v, want nil", err)
+	}
+
+	wantScripts := map[string]string{
+		"db:backup":   `echo "backup: done"`,
+		"db:restore":  "echo restored",
+		"cache:clear": "echo cleared",
+		"cache:warm":  `echo 'warm' "up"`,
+	}
+	for id, wantScript := range wantScripts {
+		cmd, exists := registry.Command(id)
+		if !exists {
+			t.Errorf("Command %s is not registered", id)
+			continue
+		}
+		if script := cmd.(*ScriptCommand).Script(); script != wantScript {
+			t.Errorf("Command %s script = %q, want %q", id, script, wantScript)
+		}
+	}
+}
+
+func TestItFailsToRegisterScriptCommandsFromAMissingSpec(t *testing.T) {
+	err := RegisterScriptCommands(NewCommandsRegistry(), filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "failed to read script commands spec") {
+		t.Errorf("RegisterScriptCommands() error = %v, want a read error", err)
+	}
+}
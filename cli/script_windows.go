@@ -0,0 +1,7 @@
+package cli
+
+// scriptShell returns the shell binary and args running the shell line with cmd, the
+// given args being appended to the line
+func scriptShell(script string, args []string) (string, []string) {
+	return "cmd", append([]string{"/C", script}, args...)
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// The sources of the values listed by the config command
+const (
+	ConfigSourceArg     = "arg"
+	ConfigSourceEnv     = "env"
+	ConfigSourceConfig  = "config"
+	ConfigSourceDefault = "default"
+	ConfigSourceCommand = "command"
+)
+
+// EffectiveConfigProvider is an optional interface for commands resolving settings beyond
+// their flags, like environment overrides or derived values. EffectiveConfig is called by
+// the config command once the flags are parsed and the config file defaults applied, and
+// its entries are listed after the flags.
+type EffectiveConfigProvider interface {
+	Command
+	EffectiveConfig() map[string]string
+}
+
+// ConfigCommand shows the effective configuration of another command, as in
+// "app config db:backup --verbose": the value of each flag after parsing the given args and
+// applying the environment (see WithEnvPrefix) and the config file defaults (see
+// ConfigurableCommand), along with where the value comes from, followed by the entries
+// of EffectiveConfigProvider. The target command is not validated nor executed.
+type ConfigCommand struct {
+	CommandWithoutFlags
+	registry *CommandsRegistry
+	args     []string
+}
+
+// NewConfigCommand creates a command showing the effective configuration of the commands
+// of the registry
+func NewConfigCommand(registry *CommandsRegistry) *ConfigCommand {
+	return &ConfigCommand{registry: registry}
+}
+
+func (c *ConfigCommand) Id() string {
+	return "config"
+}
+
+func (c *ConfigCommand) Description() string {
+	return "Shows the effective configuration of a command, as in: config <command> [flags]"
+}
+
+// RawArgs makes runCommand forward all the args, the target command flags included
+func (c *ConfigCommand) RawArgs() {
+}
+
+// SetArgs receives the target command id, followed by its args
+func (c *ConfigCommand) SetArgs(args []string) {
+	c.args = args
+}
+
+func (c *ConfigCommand) Exec(baseWriter io.Writer) error {
+	return c.ExecContext(context.Background(), baseWriter)
+}
+
+func (c *ConfigCommand) ExecContext(ctx context.Context, baseWriter io.Writer) error {
+	if len(c.args) == 0 {
+		return errors.New("missing command id, usage: config <command> [flags]")
+	}
+
+	cmd, exists := c.registry.Command(c.args[0])
+	cmdId := c.args[0]
+	var args []string
+	if exists {
+		cmd, cmdId, args, exists = resolveSubcommand(cmd, c.args[1:])
+	}
+	if !exists {
+		return &CommandNotFoundError{Id: cmdId}
+	}
+	if takesRawArgs(cmd) {
+		return fmt.Errorf("command %s takes raw args and has no flags to show", cmdId)
+	}
+
+	flagSet := setupFlagSet(cmd, io.Discard)
+	defineCommandFlags(cmd, flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return &FlagParseError{Err: err}
+	}
+
+	sources := make(map[string]string)
+	flagSet.Visit(
+		func(setFlag *flag.Flag) {
+			sources[aliasTarget(setFlag)] = ConfigSourceArg
+		},
+	)
+	var envFlags []string
+	if prefix, enabled := envPrefixFrom(ctx); enabled {
+		var err error
+		if envFlags, err = applyFlagEnv(flagSet, prefix); err != nil {
+			return err
+		}
+		for _, name := range envFlags {
+			sources[name] = ConfigSourceEnv
+		}
+	}
+	if _, isConfigurable := findOptional[ConfigurableCommand](cmd); isConfigurable {
+		configPath := flagSet.Lookup(ConfigFlagName).Value.String()
+		applied, err := applyFlagDefaults(flagSet, configPath, envFlags)
+		if err != nil {
+			return err
+		}
+		for _, name := range applied {
+			sources[name] = ConfigSourceConfig
+		}
+	}
+
+	writer := tabwriter.NewWriter(baseWriter, 0, 0, 4, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "SETTING\tVALUE\tSOURCE")
+	flagSet.VisitAll(
+		func(definedFlag *flag.Flag) {
+			if isFlagAlias(definedFlag) {
+				return
+			}
+			source, isSet := sources[definedFlag.Name]
+			if !isSet {
+				source = ConfigSourceDefault
+			}
+			_, _ = fmt.Fprintf(writer, "--%s\t%s\t%s\n", definedFlag.Name, definedFlag.Value, source)
+		},
+	)
+
+	if provider, ok := findOptional[EffectiveConfigProvider](cmd); ok {
+		config := provider.EffectiveConfig()
+		for _, key := range slices.Sorted(maps.Keys(config)) {
+			value := strings.ReplaceAll(config[key], "\n", " ")
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", key, value, ConfigSourceCommand)
+		}
+	}
+
+	return writer.Flush()
+}
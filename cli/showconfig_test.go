@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// MockEffectiveConfigCommand is an EffectiveConfigProvider implementation for testing
+type MockEffectiveConfigCommand struct {
+	MockConfigurableCommand
+}
+
+func (m *MockEffectiveConfigCommand) EffectiveConfig() map[string]string {
+	return map[string]string{"endpoint": "https://" + m.name + ".example.com", "api-key": "from env"}
+}
+
+func TestItCanShowTheEffectiveConfigOfACommand(t *testing.T) {
+	configPath := writeConfigFile(t, "config.json", `{"name": "john", "count": 3}`)
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockEffectiveConfigCommand{
+			MockConfigurableCommand{MockCommand: MockCommand{id: "deploy"}, configPath: configPath},
+		},
+	)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantLines [][]string
+		wantErr   error
+	}{
+		{
+			name: "flags from args, config file and defaults",
+			args: []string{"deploy", "--count", "5"},
+			wantLines: [][]string{
+				{"SETTING", "VALUE", "SOURCE"},
+				{"--config", configPath, "default"},
+				{"--count", "5", "arg"},
+				{"--delay", "1s", "default"},
+				{"--name", "john", "config"},
+				{"--verbose", "false", "default"},
+				{"api-key", "from", "env", "command"},
+				{"endpoint", "https://john.example.com", "command"},
+			},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"unknown"},
+			wantErr: ErrCommandNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				configCmd := NewConfigCommand(registry)
+				configCmd.SetArgs(tt.args)
+
+				var buf bytes.Buffer
+				err := configCmd.Exec(&buf)
+
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("Exec() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Exec() error = %v, want nil", err)
+				}
+
+				lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+				if len(lines) != len(tt.wantLines) {
+					t.Fatalf("Exec() output has %d lines, want %d:\n%s", len(lines), len(tt.wantLines), buf.String())
+				}
+				for i, line := range lines {
+					if fields := strings.Fields(line); strings.Join(fields, " ") != strings.Join(tt.wantLines[i], " ") {
+						t.Errorf("Exec() line %d = %q, want the fields %q", i, line, tt.wantLines[i])
+					}
+				}
+			},
+		)
+	}
+}
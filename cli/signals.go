@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// shutdownHandler cancels the command context when the process receives SIGINT or
+// SIGTERM, giving the running command (and deferred cleanups like lock releases) a
+// chance to finish before the process is force-exited. Since deferred cleanups do not
+// run on a forced exit, the resources registered with releaseOnForceExit, like the locks
+// of FsLockableCommand, are released right before it.
+type shutdownHandler struct {
+	signals     chan os.Signal
+	done        chan struct{}
+	cancel      context.CancelFunc
+	interrupted atomic.Bool
+	stopOnce    sync.Once
+
+	releasesMu  sync.Mutex
+	releases    map[int]func()
+	nextRelease int
+}
+
+// shutdownHandlerKey is the context key of the shutdown handler
+type shutdownHandlerKey struct{}
+
+// handleShutdownSignals installs the signal handler and returns a context which will be
+// cancelled when a shutdown signal is received. If the command does not return within
+// gracePeriod after the signal (or a second signal arrives), the registered resources are
+// released and forceExit is called. A gracePeriod <= 0 waits for the command
+// indefinitely after the first signal, as does a nil forceExit.
+func handleShutdownSignals(
+	ctx context.Context,
+	gracePeriod time.Duration,
+	forceExit func(),
+) (context.Context, *shutdownHandler) {
+	ctx, cancel := context.WithCancel(ctx)
+	handler := &shutdownHandler{
+		signals:  make(chan os.Signal, 2),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+		releases: make(map[int]func()),
+	}
+	signal.Notify(handler.signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-handler.signals:
+		case <-handler.done:
+			return
+		}
+
+		handler.interrupted.Store(true)
+		cancel()
+		if forceExit == nil {
+			return
+		}
+
+		var graceExpired <-chan time.Time
+		if gracePeriod > 0 {
+			timer := time.NewTimer(gracePeriod)
+			defer timer.Stop()
+			graceExpired = timer.C
+		}
+
+		select {
+		case <-handler.signals:
+		case <-graceExpired:
+		case <-handler.done:
+			return
+		}
+		handler.releaseAll()
+		forceExit()
+	}()
+
+	return context.WithValue(ctx, shutdownHandlerKey{}, handler), handler
+}
+
+// releaseOnForceExit registers a release of a resource, like a lock, to run if the process
+// is force-exited while the command is running, and returns the function unregistering it,
+// to call once the resource is released normally. It does nothing without a shutdown
+// handler in the context.
+func releaseOnForceExit(ctx context.Context, release func()) (unregister func()) {
+	handler, ok := ctx.Value(shutdownHandlerKey{}).(*shutdownHandler)
+	if !ok {
+		return func() {}
+	}
+
+	handler.releasesMu.Lock()
+	defer handler.releasesMu.Unlock()
+	id := handler.nextRelease
+	handler.nextRelease++
+	handler.releases[id] = release
+
+	return func() {
+		handler.releasesMu.Lock()
+		defer handler.releasesMu.Unlock()
+		delete(handler.releases, id)
+	}
+}
+
+// releaseAll runs the registered releases, before the process is force-exited
+func (h *shutdownHandler) releaseAll() {
+	h.releasesMu.Lock()
+	defer h.releasesMu.Unlock()
+	for id, release := range h.releases {
+		release()
+		delete(h.releases, id)
+	}
+}
+
+// Interrupted reports whether a shutdown signal has been received
+func (h *shutdownHandler) Interrupted() bool {
+	return h.interrupted.Load()
+}
+
+// Stop uninstalls the signal handler and releases its resources
+func (h *shutdownHandler) Stop() {
+	h.stopOnce.Do(
+		func() {
+			signal.Stop(h.signals)
+			close(h.done)
+			h.cancel()
+		},
+	)
+}
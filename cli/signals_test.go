@@ -0,0 +1,178 @@
+//go:build unix
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestItCancelsCommandContextAndReleasesLockOnSignal(t *testing.T) {
+	tempDir := t.TempDir()
+
+	started := make(chan struct{})
+	cmd := &MockContextCommand{
+		MockCommand: MockCommand{id: "blocking-cmd"},
+		execContextFunc: func(ctx context.Context, writer io.Writer) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	lockableCmd := NewLockableCommand(cmd, tempDir)
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(lockableCmd)
+
+	go func() {
+		<-started
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}()
+
+	var buf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"blocking-cmd"},
+		registry,
+		&buf,
+		func(code int) { exitCode = code },
+		WithErrorWriter(&buf),
+	)
+
+	if exitCode != StatusInterrupted {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusInterrupted)
+	}
+
+	locked, err := NewLockableCommand(cmd, tempDir).Lock()
+	if err != nil || !locked {
+		t.Errorf("Expected lock to be released after signal, got locked=%v err=%v", locked, err)
+	}
+}
+
+func TestItForceExitsWhenGracePeriodElapses(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cmd := &MockCommand{
+		id: "stubborn-cmd",
+		execFunc: func(writer io.Writer) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(cmd)
+
+	go func() {
+		<-started
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+
+	var mu sync.Mutex
+	var exitCodes []int
+	Bootstrap(
+		[]string{"stubborn-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) {
+			mu.Lock()
+			exitCodes = append(exitCodes, code)
+			mu.Unlock()
+			close(release)
+		},
+		WithShutdownGracePeriod(20*time.Millisecond),
+		WithSignalExitCode(42),
+	)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(exitCodes) != 1 || exitCodes[0] != 42 {
+		t.Errorf("Bootstrap() exit codes = %v, want exactly [42]", exitCodes)
+	}
+}
+
+func TestItNeverExitsTheProcessFromRun(t *testing.T) {
+	started := make(chan struct{})
+	cmd := &MockCommand{
+		id: "stubborn-cmd",
+		execFunc: func(writer io.Writer) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		},
+	}
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(cmd)
+
+	go func() {
+		<-started
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+		time.Sleep(10 * time.Millisecond)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+
+	// The test process would be killed if Run exited it
+	exitCode, _ := Run(
+		[]string{"stubborn-cmd"},
+		registry,
+		io.Discard,
+		WithErrorWriter(io.Discard),
+		WithShutdownGracePeriod(20*time.Millisecond),
+		WithSignalExitCode(42),
+	)
+
+	if exitCode != 42 {
+		t.Errorf("Run() exitCode = %v, want 42", exitCode)
+	}
+}
+
+func TestItReleasesLocksBeforeForceExiting(t *testing.T) {
+	tempDir := t.TempDir()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cmd := &MockCommand{
+		id: "stubborn-locked-cmd",
+		execFunc: func(writer io.Writer) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+
+	registry := NewCommandsRegistry()
+	_ = registry.Register(NewLockableCommand(cmd, tempDir))
+
+	go func() {
+		<-started
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+
+	var lockedOnExit bool
+	var lockErr error
+	Bootstrap(
+		[]string{"stubborn-locked-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) {
+			// The command is still running, like it would be when the process exits
+			if code == StatusInterrupted {
+				other := NewLockableCommand(cmd, tempDir)
+				lockedOnExit, lockErr = other.Lock()
+				_ = other.Unlock()
+				close(release)
+			}
+		},
+		WithShutdownGracePeriod(20*time.Millisecond),
+	)
+
+	if lockErr != nil || !lockedOnExit {
+		t.Errorf("Expected the lock to be released on force exit, got locked=%v err=%v", lockedOnExit, lockErr)
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of "did you mean" suggestions shown
+const maxSuggestions = 3
+
+// levenshtein computes the edit distance between two strings, counted in runes
+func levenshtein(a string, b string) int {
+	aRunes, bRunes := []rune(a), []rune(b)
+	previous := make([]int, len(bRunes)+1)
+	current := make([]int, len(bRunes)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(aRunes); i++ {
+		current[0] = i
+		for j := 1; j <= len(bRunes); j++ {
+			cost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(bRunes)]
+}
+
+// suggestCommandIds returns up to maxSuggestions candidate ids which are reasonably
+// close to the requested id, closest first. A candidate is considered close when its
+// edit distance is at most a third of the requested id length (but at least 2), or
+// when one contains the other.
+func suggestCommandIds(requestedId string, candidateIds []string) []string {
+	requestedId = strings.ToLower(requestedId)
+	if requestedId == "" {
+		return nil
+	}
+
+	threshold := max(2, len([]rune(requestedId))/3)
+	type suggestion struct {
+		id       string
+		distance int
+	}
+
+	var suggestions []suggestion
+	for _, candidateId := range candidateIds {
+		lowerId := strings.ToLower(candidateId)
+		distance := levenshtein(requestedId, lowerId)
+		if distance <= threshold ||
+			strings.Contains(lowerId, requestedId) ||
+			strings.Contains(requestedId, lowerId) {
+			suggestions = append(suggestions, suggestion{candidateId, distance})
+		}
+	}
+
+	slices.SortFunc(
+		suggestions, func(a, b suggestion) int {
+			if a.distance != b.distance {
+				return a.distance - b.distance
+			}
+			return strings.Compare(a.id, b.id)
+		},
+	)
+
+	ids := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		ids = append(ids, suggestions[i].id)
+	}
+	return ids
+}
+
+// formatSuggestions formats the suggested ids as a "did you mean" hint, or returns an
+// empty string when there is nothing to suggest
+func formatSuggestions(ids []string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = fmt.Sprintf("'%s'", id)
+	}
+
+	if len(quoted) == 1 {
+		return fmt.Sprintf("did you mean %s?", quoted[0])
+	}
+	return fmt.Sprintf("did you mean one of %s?", strings.Join(quoted, ", "))
+}
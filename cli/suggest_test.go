@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestItCanComputeLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"say-hello", "say-hello", 0},
+		{"say-helo", "say-hello", 1},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestItCanSuggestCloseCommandIds(t *testing.T) {
+	candidates := []string{"say-hello", "say-hello-dynamic", "help", "migrate", "say-bye"}
+
+	tests := []struct {
+		name      string
+		requested string
+		want      []string
+	}{
+		{"typo", "say-helo", []string{"say-hello"}},
+		{"prefix", "say-hell", []string{"say-hello", "say-hello-dynamic"}},
+		{"case difference", "HELP", []string{"help"}},
+		{"no close match", "zzzzzz", []string{}},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := suggestCommandIds(tt.requested, candidates)
+				if !slices.Equal(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+					t.Errorf("suggestCommandIds(%q) = %v, want %v", tt.requested, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestItSuggestsCommandsWhenTheRequestedOneDoesNotExist(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(&MockCommand{id: "say-hello"})
+
+	var errBuf bytes.Buffer
+	Bootstrap(
+		[]string{"say-helo"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) {},
+		WithErrorWriter(&errBuf),
+	)
+
+	if !strings.Contains(errBuf.String(), "did you mean 'say-hello'?") {
+		t.Errorf("Bootstrap() should suggest say-hello, got %q", errBuf.String())
+	}
+
+	errBuf.Reset()
+	Bootstrap(
+		[]string{"hepl"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) {},
+		WithErrorWriter(&errBuf),
+	)
+
+	if !strings.Contains(errBuf.String(), "did you mean 'help'?") {
+		t.Errorf("Bootstrap() should suggest the help command, got %q", errBuf.String())
+	}
+}
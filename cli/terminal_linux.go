@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize is the terminal size structure filled by the TIOCGWINSZ ioctl
+type winsize struct {
+	Rows    uint16
+	Cols    uint16
+	XPixels uint16
+	YPixels uint16
+}
+
+// terminalWidth returns the width, in columns, of the terminal the file refers to, or 0
+// if it is unknown
+func terminalWidth(file *os.File) int {
+	var size winsize
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		file.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if errno != 0 {
+		return 0
+	}
+	return int(size.Cols)
+}
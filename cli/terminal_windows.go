@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// consoleScreenBufferInfo is the CONSOLE_SCREEN_BUFFER_INFO structure filled by
+// GetConsoleScreenBufferInfo
+type consoleScreenBufferInfo struct {
+	Size              struct{ X, Y int16 }
+	CursorPosition    struct{ X, Y int16 }
+	Attributes        uint16
+	Window            struct{ Left, Top, Right, Bottom int16 }
+	MaximumWindowSize struct{ X, Y int16 }
+}
+
+var procGetConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32.dll").
+	NewProc("GetConsoleScreenBufferInfo")
+
+// terminalWidth returns the width, in columns, of the console the file refers to, or 0
+// if it is unknown
+func terminalWidth(file *os.File) int {
+	var info consoleScreenBufferInfo
+	ok, _, _ := procGetConsoleScreenBufferInfo.Call(file.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0
+	}
+	return int(info.Window.Right-info.Window.Left) + 1
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// TimeoutCommand is an optional interface for commands with a maximum execution
+// duration. The --timeout global flag, when given, takes precedence over it.
+type TimeoutCommand interface {
+	Command
+	Timeout() time.Duration
+}
+
+// commandTimeout returns the timeout of the command execution, 0 meaning no timeout
+func commandTimeout(ctx context.Context, cmd Command) time.Duration {
+	if timeout, _ := ctx.Value(timeoutKey{}).(time.Duration); timeout > 0 {
+		return timeout
+	}
+
+	if timeoutCmd, ok := findOptional[TimeoutCommand](cmd); ok {
+		return timeoutCmd.Timeout()
+	}
+
+	return 0
+}
+
+// timeoutGracePeriod is how long a command is given to return once its timeout elapsed
+// and its context was cancelled, so that its deferred cleanup runs before the timeout is
+// reported
+var timeoutGracePeriod = 5 * time.Second
+
+// execCommandWithTimeout executes the command with a context cancelled after the
+// timeout. Commands observing the context (ContextualCommand) are expected to stop when it
+// is done, and are waited for during the timeoutGracePeriod before the TimeoutError is
+// returned. Commands ignoring it cannot be killed: once the grace period elapses too, the
+// command keeps running in the background until the process exits, which Bootstrap does
+// right after reporting the failure.
+func execCommandWithTimeout(
+	ctx context.Context,
+	cmd Command,
+	outputWriter io.Writer,
+	timeout time.Duration,
+) error {
+	_, err := execCommandWithinTimeout(ctx, cmd, outputWriter, timeout)
+	return err
+}
+
+// execCommandWithinTimeout executes the command like execCommandWithTimeout does, also
+// reporting whether the command returned, which is false when it is still running after
+// the grace period
+func execCommandWithinTimeout(
+	ctx context.Context,
+	cmd Command,
+	outputWriter io.Writer,
+	timeout time.Duration,
+) (returned bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- panicToError(recovered)
+			}
+		}()
+		done <- execCommand(ctx, cmd, outputWriter)
+	}()
+
+	select {
+	case err = <-done:
+		if errors.Is(err, context.DeadlineExceeded) &&
+			errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return true, &TimeoutError{Timeout: timeout, Err: err}
+		}
+		return true, err
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Cancelled by the caller (e.g. on shutdown signal), let the command finish
+			return true, <-done
+		}
+	}
+
+	// Give the command, whose context is now done, time to return and clean up
+	grace := time.NewTimer(timeoutGracePeriod)
+	defer grace.Stop()
+	select {
+	case err = <-done:
+		if err == nil {
+			err = ctx.Err()
+		}
+		return true, &TimeoutError{Timeout: timeout, Err: err}
+	case <-grace.C:
+		return false, &TimeoutError{Timeout: timeout, Err: ctx.Err()}
+	}
+}
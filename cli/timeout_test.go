@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockTimeoutCommand is a TimeoutCommand implementation for testing
+type MockTimeoutCommand struct {
+	MockContextCommand
+	timeout time.Duration
+}
+
+func (m *MockTimeoutCommand) Timeout() time.Duration {
+	return m.timeout
+}
+
+// setTimeoutGracePeriod shortens the grace period given to timed out commands during
+// the test
+func setTimeoutGracePeriod(t *testing.T, gracePeriod time.Duration) {
+	previous := timeoutGracePeriod
+	timeoutGracePeriod = gracePeriod
+	t.Cleanup(func() { timeoutGracePeriod = previous })
+}
+
+func TestItCanRunCommandsWithinTheirTimeout(t *testing.T) {
+	setTimeoutGracePeriod(t, 20*time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+
+	scenarios := map[string]struct {
+		execContextFunc func(ctx context.Context, writer io.Writer) error
+		wantTimeout     bool
+	}{
+		"fast command": {
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				return nil
+			},
+			wantTimeout: false,
+		},
+		"slow command observing the context": {
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-release:
+					return nil
+				}
+			},
+			wantTimeout: true,
+		},
+		"slow command ignoring the context": {
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				<-release
+				return nil
+			},
+			wantTimeout: true,
+		},
+	}
+
+	for name, scenario := range scenarios {
+		t.Run(
+			name, func(t *testing.T) {
+				cmd := &MockTimeoutCommand{
+					MockContextCommand: MockContextCommand{
+						MockCommand:     MockCommand{id: "test-cmd"},
+						execContextFunc: scenario.execContextFunc,
+					},
+					timeout: 20 * time.Millisecond,
+				}
+
+				err := runCommand(context.Background(), cmd, nil, io.Discard, io.Discard)
+
+				var timeoutErr *TimeoutError
+				if errors.As(err, &timeoutErr) != scenario.wantTimeout {
+					t.Fatalf("runCommand() error = %v, want timeout %v", err, scenario.wantTimeout)
+				}
+				if scenario.wantTimeout && exitCodeFor(err) != StatusTimeout {
+					t.Errorf("exitCodeFor() = %v, want %v", exitCodeFor(err), StatusTimeout)
+				}
+			},
+		)
+	}
+}
+
+func TestItWaitsForTimedOutCommandsToCleanUp(t *testing.T) {
+	cleanedUp := false
+	cmd := &MockTimeoutCommand{
+		MockContextCommand: MockContextCommand{
+			MockCommand: MockCommand{id: "test-cmd"},
+			execContextFunc: func(ctx context.Context, writer io.Writer) error {
+				<-ctx.Done()
+				time.Sleep(50 * time.Millisecond)
+				cleanedUp = true
+				return ctx.Err()
+			},
+		},
+		timeout: 20 * time.Millisecond,
+	}
+
+	err := runCommand(context.Background(), cmd, nil, io.Discard, io.Discard)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("runCommand() error = %v, want a TimeoutError", err)
+	}
+	if !cleanedUp {
+		t.Errorf("runCommand() returned before the timed out command cleaned up")
+	}
+}
+
+func TestItCanSetTheTimeoutViaGlobalFlag(t *testing.T) {
+	registry := NewCommandsRegistry()
+	_ = registry.Register(
+		&MockTimeoutCommand{
+			MockContextCommand: MockContextCommand{
+				MockCommand: MockCommand{id: "test-cmd"},
+				execContextFunc: func(ctx context.Context, writer io.Writer) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			timeout: time.Hour,
+		},
+	)
+
+	var errBuf bytes.Buffer
+	exitCode := -1
+	Bootstrap(
+		[]string{"--timeout", "20ms", "test-cmd"},
+		registry,
+		&bytes.Buffer{},
+		func(code int) { exitCode = code },
+		WithErrorWriter(&errBuf),
+	)
+
+	if exitCode != StatusTimeout {
+		t.Errorf("Bootstrap() exitCode = %v, want %v", exitCode, StatusTimeout)
+	}
+	if !strings.Contains(errBuf.String(), "command timed out after 20ms") {
+		t.Errorf("Error output should report the timeout, got %v", errBuf.String())
+	}
+}
@@ -2,7 +2,10 @@ module github.com/rsgcata/go-cli-command
 
 go 1.24.1
 
-require github.com/rsgcata/go-fs v0.0.0-20250608175813-b10fd3f2e1de
+require (
+	github.com/rsgcata/go-fs v0.0.0-20250608175813-b10fd3f2e1de
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -10,5 +13,4 @@ require (
 	github.com/rsgcata/go-params v1.0.0 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )